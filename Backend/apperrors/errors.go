@@ -0,0 +1,141 @@
+// Package apperrors describes the errors the WillowSuite Vault API can return and the HTTP status each one maps to.
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind is the category of an application error, it decides the HTTP status sent to the client.
+type Kind string
+
+const (
+	KindBadRequest           Kind = "bad_request"
+	KindValidation           Kind = "validation"
+	KindNotFound             Kind = "not_found"
+	KindConflict             Kind = "conflict"
+	KindPreconditionFailed   Kind = "precondition_failed"
+	KindPreconditionRequired Kind = "precondition_required"
+	KindUnsupportedMedia     Kind = "unsupported_media_type"
+	KindUnauthorized         Kind = "unauthorized"
+	KindTooManyRequests      Kind = "too_many_requests"
+	KindUpstream             Kind = "upstream"
+	KindInternal             Kind = "internal"
+)
+
+var statuses = map[Kind]int{
+	KindBadRequest:           http.StatusBadRequest,
+	KindValidation:           http.StatusUnprocessableEntity,
+	KindNotFound:             http.StatusNotFound,
+	KindConflict:             http.StatusConflict,
+	KindPreconditionFailed:   http.StatusPreconditionFailed,
+	KindPreconditionRequired: http.StatusPreconditionRequired,
+	KindUnsupportedMedia:     http.StatusUnsupportedMediaType,
+	KindUnauthorized:         http.StatusUnauthorized,
+	KindTooManyRequests:      http.StatusTooManyRequests,
+	KindUpstream:             http.StatusBadGateway,
+	KindInternal:             http.StatusInternalServerError,
+}
+
+// Error is the error type returned by the repository and controllers.
+type Error struct {
+	Kind    Kind
+	Message string
+	Service string
+	Details interface{}
+	Err     error
+}
+
+// Error returns the client facing message followed by the underlying error, if there is one.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status code for the error.
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Kind]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// BadRequest is returned when a request can't be understood at all, for example malformed JSON.
+func BadRequest(message string, err error) *Error {
+	return &Error{Kind: KindBadRequest, Message: message, Err: err}
+}
+
+// Validation is returned when a request was understood but some of its fields are invalid.
+func Validation(details interface{}) *Error {
+	return &Error{Kind: KindValidation, Message: "data validation failed", Details: details}
+}
+
+// NotFound is returned when a record doesn't exist or doesn't belong to the user.
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// Conflict is returned when a request can't be applied to the current state of a record.
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+// PreconditionFailed is returned when a write names a version of a record that is no longer current.
+func PreconditionFailed(message string) *Error {
+	return &Error{Kind: KindPreconditionFailed, Message: message}
+}
+
+// PreconditionRequired is returned when a write that has to name the version of a record it replaces doesn't.
+func PreconditionRequired(message string) *Error {
+	return &Error{Kind: KindPreconditionRequired, Message: message}
+}
+
+// UnsupportedMedia is returned when a request body is sent in a format the route doesn't accept.
+func UnsupportedMedia(message string) *Error {
+	return &Error{Kind: KindUnsupportedMedia, Message: message}
+}
+
+// Unauthorized is returned when the user's credentials are missing, invalid or revoked.
+func Unauthorized(message string, err error) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message, Err: err}
+}
+
+// TooManyRequests is returned when a client has used up its rate limit for a group of routes.
+func TooManyRequests(message string) *Error {
+	return &Error{Kind: KindTooManyRequests, Message: message}
+}
+
+// Upstream is returned when a service we depend on (Redis, S3, Cognito) fails.
+func Upstream(service string, message string, err error) *Error {
+	return &Error{Kind: KindUpstream, Service: service, Message: message, Err: err}
+}
+
+// Internal is returned for failures that are our fault, such as a failed database query.
+func Internal(message string, err error) *Error {
+	return &Error{Kind: KindInternal, Message: message, Err: err}
+}
+
+// As converts any error into an application error, treating unknown errors as internal.
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	return Internal("internal server error", err)
+}
+
+// Is reports whether err is an application error of the given kind.
+func Is(err error, kind Kind) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Kind == kind
+}
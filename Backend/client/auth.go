@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Tokens are the Cognito tokens for a signed in user.
+type Tokens struct {
+	AccessToken  string
+	IDToken      string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// Challenge is a step the user must complete before they are signed in, such as entering an MFA code.
+type Challenge struct {
+	Name       string
+	Session    string
+	Parameters map[string]string
+}
+
+// SignInResult holds the tokens for a signed in user, or the challenge they must answer first.
+type SignInResult struct {
+	Tokens    *Tokens
+	Challenge *Challenge
+}
+
+// SoftwareToken is the secret to load into an authenticator app when enrolling in MFA.
+type SoftwareToken struct {
+	SecretCode string
+	Session    string
+}
+
+// authResponse is the data sent back by the sign in, challenge and refresh endpoints.
+type authResponse struct {
+	AccessToken         string
+	IdToken             string
+	RefreshToken        string
+	ExpiresIn           string
+	ChallengeName       string
+	Session             string
+	ChallengeParameters map[string]string
+}
+
+// Tokens returns the client's current tokens.
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.tokens
+}
+
+// SignUp registers a new user, who must then confirm their email with ConfirmSignUp.
+func (c *Client) SignUp(ctx context.Context, body SignUpRequest) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/v1/user", body: body}, nil)
+}
+
+// ConfirmSignUp confirms a user's email with the code they were sent.
+func (c *Client) ConfirmSignUp(ctx context.Context, body ConfirmSignUpRequest) error {
+	return c.do(ctx, request{method: http.MethodPut, path: "/v1/user", body: body}, nil)
+}
+
+// SignIn signs the user in. When the result has no challenge the client keeps the tokens and uses them for
+// every later call.
+func (c *Client) SignIn(ctx context.Context, userEmail string, password string) (*SignInResult, error) {
+	body := signInRequest{UserEmail: userEmail, Password: password}
+	return c.authenticate(ctx, request{method: http.MethodPost, path: "/v1/token", body: body})
+}
+
+// RespondToChallenge answers a challenge returned by SignIn.
+func (c *Client) RespondToChallenge(ctx context.Context, body ChallengeRequest) (*SignInResult, error) {
+	return c.authenticate(ctx, request{method: http.MethodPost, path: "/v1/token/challenge", body: body})
+}
+
+// Refresh exchanges the refresh token for a new access token.
+func (c *Client) Refresh(ctx context.Context) error {
+	current := c.Tokens()
+	if current.RefreshToken == "" {
+		return ErrNotSignedIn
+	}
+
+	response := authResponse{}
+	body := refreshRequest{RefreshToken: current.RefreshToken, IDToken: current.IDToken}
+	if err := c.do(ctx, request{method: http.MethodPut, path: "/v1/token", body: body}, &response); err != nil {
+		return err
+	}
+
+	c.setTokens(response)
+	return nil
+}
+
+// LogOut revokes the refresh token and the current session, then forgets the client's tokens.
+func (c *Client) LogOut(ctx context.Context) error {
+	body := logOutRequest{RefreshToken: c.Tokens().RefreshToken}
+	if err := c.do(ctx, request{method: http.MethodDelete, path: "/v1/token", body: body, auth: true}, nil); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.tokens = Tokens{}
+	c.mu.Unlock()
+
+	return nil
+}
+
+// AssociateSoftwareToken starts MFA enrolment. Pass the session from an MFA_SETUP challenge, or an empty session
+// to enrol the signed in user.
+func (c *Client) AssociateSoftwareToken(ctx context.Context, session string) (*SoftwareToken, error) {
+	token := &SoftwareToken{}
+	body := associateSoftwareTokenRequest{Session: session}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/v1/user/mfa", body: body, auth: session == ""}, token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// VerifySoftwareToken finishes MFA enrolment with a code from the authenticator app. It returns the session to
+// answer the next challenge with when enrolling during sign in.
+func (c *Client) VerifySoftwareToken(ctx context.Context, body VerifySoftwareTokenRequest) (string, error) {
+	response := map[string]string{}
+	if err := c.do(ctx, request{method: http.MethodPut, path: "/v1/user/mfa", body: body, auth: body.Session == ""}, &response); err != nil {
+		return "", err
+	}
+
+	return response["Session"], nil
+}
+
+func (c *Client) authenticate(ctx context.Context, req request) (*SignInResult, error) {
+	response := authResponse{}
+	if err := c.do(ctx, req, &response); err != nil {
+		return nil, err
+	}
+
+	if response.ChallengeName != "" {
+		return &SignInResult{Challenge: &Challenge{
+			Name:       response.ChallengeName,
+			Session:    response.Session,
+			Parameters: response.ChallengeParameters,
+		}}, nil
+	}
+
+	tokens := c.setTokens(response)
+	return &SignInResult{Tokens: &tokens}, nil
+}
+
+// setTokens stores the tokens from response, keeping the current refresh token when none was sent.
+func (c *Client) setTokens(response authResponse) Tokens {
+	c.mu.Lock()
+	tokens := Tokens{
+		AccessToken:  response.AccessToken,
+		IDToken:      response.IdToken,
+		RefreshToken: response.RefreshToken,
+	}
+
+	if tokens.RefreshToken == "" {
+		tokens.RefreshToken = c.tokens.RefreshToken
+	}
+
+	if expiresIn, err := strconv.Atoi(response.ExpiresIn); err == nil {
+		tokens.ExpiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	c.tokens = tokens
+	c.mu.Unlock()
+
+	if c.onRefresh != nil {
+		c.onRefresh(tokens)
+	}
+
+	return tokens
+}
+
+func (c *Client) accessToken() string {
+	return c.Tokens().AccessToken
+}
+
+func (c *Client) canRefresh() bool {
+	return c.Tokens().RefreshToken != ""
+}
+
+// refreshIfExpiring refreshes the access token when it is about to expire.
+func (c *Client) refreshIfExpiring(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	tokens := c.Tokens()
+	if tokens.ExpiresAt.IsZero() || tokens.RefreshToken == "" || time.Until(tokens.ExpiresAt) > refreshLeeway {
+		return nil
+	}
+
+	return c.Refresh(ctx)
+}
+
+// refreshRejected refreshes after the API rejected staleAccessToken, unless another call already replaced it.
+func (c *Client) refreshRejected(ctx context.Context, staleAccessToken string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if c.accessToken() != staleAccessToken {
+		return nil
+	}
+
+	return c.Refresh(ctx)
+}
@@ -0,0 +1,304 @@
+// Package client is a Go SDK for the WillowSuite Vault API. It handles the {message,data} envelope, refreshes
+// tokens before they expire and retries calls that fail for transient reasons. Writes are sent with an
+// Idempotency-Key so a retry of one that already ran is answered with its first response instead of running again.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultRetryWait  = 200 * time.Millisecond
+	// maxRetryAfter is the longest Retry-After the client waits out itself. When the API asks for longer, as a
+	// rate limit over a long window does, the call fails with an *Error carrying RetryAfter instead.
+	maxRetryAfter = 5 * time.Second
+
+	// refreshLeeway is how long before the access token expires that we refresh it.
+	refreshLeeway = 30 * time.Second
+)
+
+// Client calls the WillowSuite Vault API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	retryWait  time.Duration
+	onRefresh  func(Tokens)
+
+	mu     sync.Mutex
+	tokens Tokens
+
+	// refreshMu stops concurrent calls from refreshing the same expired token more than once.
+	refreshMu sync.Mutex
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used to send requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTokens sets the tokens from an earlier sign in so the client can be used without signing in again.
+func WithTokens(tokens Tokens) Option {
+	return func(c *Client) {
+		c.tokens = tokens
+	}
+}
+
+// WithRetries sets how many times a call is retried and the wait before the first retry. The wait
+// doubles after every attempt.
+func WithRetries(maxRetries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryWait = wait
+	}
+}
+
+// WithTokenRefreshHandler registers a function called with the new tokens every time the client gets them, on
+// sign in and on refresh, so they can be persisted.
+func WithTokenRefreshHandler(onRefresh func(Tokens)) Option {
+	return func(c *Client) {
+		c.onRefresh = onRefresh
+	}
+}
+
+// New returns a client for the API at baseURL, for example https://vault.example.com/api.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		retryWait:  defaultRetryWait,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// envelope is the body of every successful response.
+type envelope struct {
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// request describes a single API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	auth   bool
+	header http.Header
+}
+
+// do sends req and decodes the data of the response into out, which may be nil.
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	if req.auth {
+		if c.accessToken() == "" {
+			return ErrNotSignedIn
+		}
+
+		if err := c.refreshIfExpiring(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Every attempt at a write, including the one after a refresh, is the same call to the API.
+	if !isSafe(req.method) {
+		req.header = req.header.Clone()
+		if req.header == nil {
+			req.header = http.Header{}
+		}
+		req.header.Set("Idempotency-Key", newIdempotencyKey())
+	}
+
+	sentAccessToken := c.accessToken()
+	res, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	// The access token may have been revoked or expired early, refresh it once and try again.
+	if res.StatusCode == http.StatusUnauthorized && req.auth && c.canRefresh() {
+		res.Body.Close()
+
+		if err = c.refreshRejected(ctx, sentAccessToken); err != nil {
+			return err
+		}
+
+		if res, err = c.send(ctx, req); err != nil {
+			return err
+		}
+	}
+	defer res.Body.Close()
+
+	return decodeResponse(res, out)
+}
+
+// send sends req, retrying reads and writes that carry an Idempotency-Key on network errors and transient
+// statuses. Writes without one could run twice, so they are sent once.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("client: encoding request body: %w", err)
+		}
+	}
+
+	endpoint := c.baseURL + req.path
+	if len(req.query) > 0 {
+		endpoint += "?" + req.query.Encode()
+	}
+
+	attempts := 1
+	if isSafe(req.method) || req.header.Get("Idempotency-Key") != "" {
+		attempts += c.maxRetries
+	}
+
+	wait := c.retryWait
+	for attempt := 1; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("client: building request: %w", err)
+		}
+
+		httpReq.Header.Set("Accept", "application/json")
+		if payload != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+
+		for name, values := range req.header {
+			httpReq.Header[name] = values
+		}
+
+		if accessToken := c.accessToken(); req.auth && accessToken != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+
+		res, err := c.httpClient.Do(httpReq)
+		if attempt >= attempts || (err == nil && !isRetryable(res.StatusCode)) {
+			if err != nil {
+				return nil, fmt.Errorf("client: %s %s: %w", req.method, req.path, err)
+			}
+
+			return res, nil
+		}
+
+		delay := wait
+		if err == nil {
+			retryAfter := parseRetryAfter(res.Header.Get("Retry-After"))
+			if retryAfter > maxRetryAfter {
+				return res, nil
+			}
+
+			if retryAfter > 0 {
+				delay = retryAfter
+			}
+
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		wait *= 2
+	}
+}
+
+// decodeResponse turns an error status into an *Error and otherwise decodes the envelope data into out.
+func decodeResponse(res *http.Response, out interface{}) error {
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("client: reading response: %w", err)
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return newError(res, data)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	body := envelope{}
+	if err = json.Unmarshal(data, &body); err != nil {
+		return fmt.Errorf("client: decoding response: %w", err)
+	}
+
+	if err = json.Unmarshal(body.Data, out); err != nil {
+		return fmt.Errorf("client: decoding response data: %w", err)
+	}
+
+	return nil
+}
+
+func isSafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// newIdempotencyKey returns a random key for one call.
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+func isRetryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// entityPath builds the path for a single entity.
+func entityPath(prefix string, category string, id uint64) string {
+	return fmt.Sprintf("%s/%s/%d", prefix, url.PathEscape(category), id)
+}
+
+// ErrNotSignedIn is returned by calls that need tokens when the client has none.
+var ErrNotSignedIn = errors.New("client: not signed in")
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultPageSize is the page size used by Entities when ListOptions.Limit isn't set.
+const defaultPageSize = 20
+
+// EntityRecord is a stored entity. Buildings have an Address and no Parent, every other category has a Parent.
+type EntityRecord struct {
+	Entity  Entity
+	Parent  Parent
+	Address *string
+}
+
+// ListOptions filters and pages the results of GetEntities and Entities.
+type ListOptions struct {
+	Offset  int
+	Limit   int
+	Search  string
+	Filters []string
+}
+
+func (opts ListOptions) values() url.Values {
+	values := url.Values{}
+	values.Set("offset", strconv.Itoa(opts.Offset))
+
+	if opts.Limit > 0 {
+		values.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	if opts.Search != "" {
+		values.Set("search", opts.Search)
+	}
+
+	if len(opts.Filters) > 0 {
+		values.Set("filter", strings.Join(opts.Filters, ","))
+	}
+
+	return values
+}
+
+// CreateEntity creates a new entity.
+func (c *Client) CreateEntity(ctx context.Context, body CreateEntityRequest) (*EntityRecord, error) {
+	record := &EntityRecord{}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/v1/entity", body: body, auth: true}, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// EditEntity replaces an existing entity. version is the Entity.Version it was read at, the call fails with a 412
+// error if someone has changed the entity since. A version of 0 overwrites whatever is there.
+func (c *Client) EditEntity(ctx context.Context, body EditEntityRequest, version uint64) (*EntityRecord, error) {
+	record := &EntityRecord{}
+	if err := c.do(ctx, request{method: http.MethodPut, path: "/v1/entity", body: body, auth: true, header: ifMatch(version)}, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// GetEntity returns a single entity.
+func (c *Client) GetEntity(ctx context.Context, category string, id uint64) (*EntityRecord, error) {
+	record := &EntityRecord{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: entityPath("/v1/entity", category, id), auth: true}, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// PatchEntity changes only the fields in patch, as a JSON Merge Patch. Fields set to nil are cleared. version works
+// as it does for EditEntity.
+func (c *Client) PatchEntity(ctx context.Context, category string, id uint64, patch map[string]interface{}, version uint64) (*EntityRecord, error) {
+	header := ifMatch(version)
+	header.Set("Content-Type", "application/merge-patch+json")
+
+	record := &EntityRecord{}
+	if err := c.do(ctx, request{method: http.MethodPatch, path: entityPath("/v1/entity", category, id), body: patch, auth: true, header: header}, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// DeleteEntity deletes an entity. Entities that still have children can't be deleted. version works as it does
+// for EditEntity.
+func (c *Client) DeleteEntity(ctx context.Context, category string, id uint64, version uint64) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: entityPath("/v1/entity", category, id), auth: true, header: ifMatch(version)}, nil)
+}
+
+// Batch runs several entity operations in one transaction. In atomic mode a failing operation fails the whole call,
+// otherwise the result of each operation says whether it was saved.
+func (c *Client) Batch(ctx context.Context, body BatchRequest) (*BatchResponse, error) {
+	response := &BatchResponse{}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/v1/entities/batch", body: body, auth: true}, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetEntities returns a single page of entities and the total number matching opts.
+func (c *Client) GetEntities(ctx context.Context, opts ListOptions) (*EntitiesPage, error) {
+	response := &EntitiesPage{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/v1/entities", query: opts.values(), auth: true}, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Entities iterates over every entity matching opts, fetching one page at a time starting at opts.Offset.
+// Iteration stops after the first error, which is yielded with a zero entity.
+func (c *Client) Entities(ctx context.Context, opts ListOptions) iter.Seq2[EntitySummary, error] {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+
+	return func(yield func(EntitySummary, error) bool) {
+		for {
+			page, err := c.GetEntities(ctx, opts)
+			if err != nil {
+				yield(EntitySummary{}, err)
+				return
+			}
+
+			for _, entity := range page.Entities {
+				if !yield(entity, nil) {
+					return
+				}
+			}
+
+			opts.Offset += len(page.Entities)
+			if len(page.Entities) < opts.Limit || opts.Offset >= page.TotalCount {
+				return
+			}
+		}
+	}
+}
+
+// GetParents returns the entities that can be the parent of an entity in category.
+func (c *Client) GetParents(ctx context.Context, category string) ([]RelatedEntity, error) {
+	parents := []RelatedEntity{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/v1/parents/" + url.PathEscape(category), auth: true}, &parents); err != nil {
+		return nil, err
+	}
+
+	return parents, nil
+}
+
+// GetChildren returns the direct children of an entity.
+func (c *Client) GetChildren(ctx context.Context, category string, id uint64) ([]RelatedEntity, error) {
+	children := []RelatedEntity{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: entityPath("/v1/children", category, id), auth: true}, &children); err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+// GenerateQR returns a presigned URL for the QR code image of an entity.
+func (c *Client) GenerateQR(ctx context.Context, category string, id uint64) (string, error) {
+	var presignedURL string
+	body := generateQRRequest{Category: category, ID: id}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/v1/qr", body: body, auth: true}, &presignedURL); err != nil {
+		return "", err
+	}
+
+	return presignedURL, nil
+}
+
+// GetSessions returns the devices the user is signed in on, most recently used first.
+func (c *Client) GetSessions(ctx context.Context) ([]Session, error) {
+	sessions := []Session{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/v1/sessions", auth: true}, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// LogOutAllDevices revokes every session the user has, including the client's own.
+func (c *Client) LogOutAllDevices(ctx context.Context) error {
+	if err := c.do(ctx, request{method: http.MethodDelete, path: "/v1/sessions", auth: true}, nil); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.tokens = Tokens{}
+	c.mu.Unlock()
+
+	return nil
+}
+
+// ifMatch returns the If-Match header for a write based on version of an entity.
+func ifMatch(version uint64) http.Header {
+	if version == 0 {
+		return http.Header{"If-Match": {"*"}}
+	}
+
+	return http.Header{"If-Match": {`"` + strconv.FormatUint(version, 10) + `"`}}
+}
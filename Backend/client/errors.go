@@ -0,0 +1,81 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Error is returned when the API responds with an error status. It carries the problem details sent by the API.
+type Error struct {
+	StatusCode  int
+	Type        string
+	Title       string
+	Detail      string
+	RequestID   string
+	Message     string
+	FieldErrors FieldErrors
+	// RetryAfter is how long the API asked to wait before trying again, 0 when it didn't say.
+	RetryAfter time.Duration
+}
+
+// problem is the application/problem+json body sent by the API.
+type problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Detail    string      `json:"detail"`
+	RequestID string      `json:"requestId"`
+	Errors    FieldErrors `json:"errors"`
+	Message   string      `json:"message"`
+}
+
+func newError(res *http.Response, data []byte) *Error {
+	apiErr := &Error{
+		StatusCode: res.StatusCode,
+		Title:      http.StatusText(res.StatusCode),
+		RequestID:  res.Header.Get("X-Request-Id"),
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+	}
+
+	body := problem{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		apiErr.Detail = string(data)
+		return apiErr
+	}
+
+	apiErr.Type, apiErr.Detail, apiErr.Message, apiErr.FieldErrors = body.Type, body.Detail, body.Message, body.Errors
+	if body.Title != "" {
+		apiErr.Title = body.Title
+	}
+
+	if body.RequestID != "" {
+		apiErr.RequestID = body.RequestID
+	}
+
+	return apiErr
+}
+
+// Error returns the status and detail of the error.
+func (e *Error) Error() string {
+	if len(e.FieldErrors) > 0 {
+		return fmt.Sprintf("vault api: %d %s: %s", e.StatusCode, e.Title, e.FieldErrors.Error())
+	}
+
+	return fmt.Sprintf("vault api: %d %s: %s", e.StatusCode, e.Title, e.Detail)
+}
+
+// IsNotFound reports whether the API said the entity doesn't exist.
+func (e *Error) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether the API rejected the call because of the current state of the entity.
+func (e *Error) IsConflict() bool {
+	return e.StatusCode == http.StatusConflict
+}
+
+// IsPreconditionFailed reports whether the API rejected a write because the entity changed since it was read.
+func (e *Error) IsPreconditionFailed() bool {
+	return e.StatusCode == http.StatusPreconditionFailed
+}
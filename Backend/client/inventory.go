@@ -0,0 +1,278 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Node is an entity in an Inventory, linked to its parent and children.
+type Node struct {
+	ID       uint64
+	Name     string
+	Category string
+	Notes    *string
+	Address  *string
+	Version  uint64
+	Parent   *Node
+	Children []*Node
+}
+
+// Ref returns the category:id reference for the node, which can be passed to Inventory.Resolve.
+func (node *Node) Ref() string {
+	return node.Category + ":" + strconv.FormatUint(node.ID, 10)
+}
+
+// Path returns the names from the building down to the node, for example /Home/Kitchen/Pantry.
+func (node *Node) Path() string {
+	if node.Parent == nil {
+		return "/" + node.Name
+	}
+
+	return node.Parent.Path() + "/" + node.Name
+}
+
+// Inventory is a snapshot of every entity the user has, arranged as a tree under their buildings.
+type Inventory struct {
+	Roots []*Node
+	nodes map[string]*Node
+}
+
+// ExportRecord is a single entity in an export. Parent is the category:id reference of its parent, which is either
+// another record in the export or an entity that already exists.
+type ExportRecord struct {
+	Ref      string  `json:"ref" yaml:"ref"`
+	Category string  `json:"category" yaml:"category"`
+	Name     string  `json:"name" yaml:"name"`
+	Notes    *string `json:"notes,omitempty" yaml:"notes,omitempty"`
+	Address  *string `json:"address,omitempty" yaml:"address,omitempty"`
+	Parent   string  `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Path     string  `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// ListAll returns every entity matching the search and filters in opts, ignoring its offset and limit.
+func (c *Client) ListAll(ctx context.Context, opts ListOptions) ([]EntitySummary, error) {
+	opts.Offset, opts.Limit = 0, 1
+	count, err := c.GetEntities(ctx, opts)
+	if err != nil || count.TotalCount == 0 {
+		return []EntitySummary{}, err
+	}
+
+	// The API limits each category separately before paging, so a single page as large as the whole result is
+	// the only way to be sure every entity is returned.
+	opts.Limit = count.TotalCount
+	page, err := c.GetEntities(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Entities, nil
+}
+
+// LoadInventory fetches every entity and arranges them into an Inventory.
+func (c *Client) LoadInventory(ctx context.Context) (*Inventory, error) {
+	entities, err := c.ListAll(ctx, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewInventory(entities), nil
+}
+
+// NewInventory arranges entities into an Inventory. Entities whose parent is missing are treated as roots.
+func NewInventory(entities []EntitySummary) *Inventory {
+	inventory := &Inventory{nodes: map[string]*Node{}}
+
+	for _, entity := range entities {
+		node := &Node{
+			ID:       entity.ID,
+			Name:     entity.Name,
+			Category: entity.Category,
+			Notes:    entity.Notes,
+			Address:  entity.Address,
+			Version:  entity.Version,
+		}
+		inventory.nodes[node.Ref()] = node
+	}
+
+	for _, entity := range entities {
+		node := inventory.nodes[entity.Category+":"+strconv.FormatUint(entity.ID, 10)]
+
+		if len(entity.Parent) > 0 && entity.Parent[0].Category != "" {
+			parentRef := entity.Parent[0].Category + ":" + strconv.FormatUint(entity.Parent[0].ID, 10)
+			if parent, ok := inventory.nodes[parentRef]; ok {
+				node.Parent = parent
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+
+		inventory.Roots = append(inventory.Roots, node)
+	}
+
+	sortNodes(inventory.Roots)
+	for _, node := range inventory.nodes {
+		sortNodes(node.Children)
+	}
+
+	return inventory
+}
+
+func sortNodes(nodes []*Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+	})
+}
+
+// Resolve finds a node by category:id reference or by path. An empty path or / returns nil, meaning the top of
+// the inventory.
+func (inventory *Inventory) Resolve(path string) (*Node, error) {
+	if node, ok := inventory.nodes[path]; ok {
+		return node, nil
+	}
+
+	if strings.Trim(path, "/") == "" {
+		return nil, nil
+	}
+
+	var current *Node
+	children := inventory.Roots
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		var matches []*Node
+		for _, child := range children {
+			if child.Name == name {
+				matches = append(matches, child)
+			}
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no entity at %s", path)
+		}
+
+		if len(matches) > 1 {
+			refs := make([]string, len(matches))
+			for i, match := range matches {
+				refs[i] = match.Ref()
+			}
+
+			return nil, fmt.Errorf("%s is ambiguous, use one of %s", path, strings.Join(refs, ", "))
+		}
+
+		current = matches[0]
+		children = current.Children
+	}
+
+	return current, nil
+}
+
+// Children returns the children of node, or the buildings when node is nil.
+func (inventory *Inventory) Children(node *Node) []*Node {
+	if node == nil {
+		return inventory.Roots
+	}
+
+	return node.Children
+}
+
+// Walk calls fn for node and every node below it, parents before children. A nil node walks the whole inventory.
+func (inventory *Inventory) Walk(node *Node, fn func(node *Node, depth int)) {
+	var walk func(nodes []*Node, depth int)
+	walk = func(nodes []*Node, depth int) {
+		for _, child := range nodes {
+			fn(child, depth)
+			walk(child.Children, depth+1)
+		}
+	}
+
+	if node == nil {
+		walk(inventory.Roots, 0)
+		return
+	}
+
+	fn(node, 0)
+	walk(node.Children, 1)
+}
+
+// Export returns node and everything below it as records, parents before children. A nil node exports the whole
+// inventory.
+func (inventory *Inventory) Export(node *Node) []ExportRecord {
+	records := []ExportRecord{}
+	inventory.Walk(node, func(node *Node, _ int) {
+		record := ExportRecord{
+			Ref:      node.Ref(),
+			Category: node.Category,
+			Name:     node.Name,
+			Notes:    node.Notes,
+			Address:  node.Address,
+			Path:     node.Path(),
+		}
+
+		if node.Parent != nil {
+			record.Parent = node.Parent.Ref()
+		}
+
+		records = append(records, record)
+	})
+
+	return records
+}
+
+// Import creates an entity for every record, in order, so parents must come before their children. It returns the
+// reference each record was created as, keyed by the record's own reference.
+func (c *Client) Import(ctx context.Context, records []ExportRecord) (map[string]string, error) {
+	created := map[string]string{}
+
+	for _, record := range records {
+		body := CreateEntityRequest{
+			Name:     record.Name,
+			Notes:    record.Notes,
+			Category: record.Category,
+			Address:  record.Address,
+		}
+
+		if record.Parent != "" {
+			parentRef := record.Parent
+			if newRef, ok := created[parentRef]; ok {
+				parentRef = newRef
+			}
+
+			category, id, err := ParseRef(parentRef)
+			if err != nil {
+				return created, fmt.Errorf("%s: %w", record.Ref, err)
+			}
+
+			body.ParentCategory = category
+			body.ParentID = id
+		}
+
+		entity, err := c.CreateEntity(ctx, body)
+		if err != nil {
+			return created, fmt.Errorf("%s: %w", record.Ref, err)
+		}
+
+		created[record.Ref] = record.Category + ":" + strconv.FormatUint(entity.Entity.ID, 10)
+	}
+
+	return created, nil
+}
+
+// ParseRef splits a category:id reference.
+func ParseRef(ref string) (string, uint64, error) {
+	category, idString, found := strings.Cut(ref, ":")
+	if !found {
+		return "", 0, fmt.Errorf("%q is not a category:id reference", ref)
+	}
+
+	if _, ok := ParentCategories[category]; !ok {
+		return "", 0, fmt.Errorf("invalid category %v", category)
+	}
+
+	id, err := strconv.ParseUint(idString, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("id must be type integer: %v", idString)
+	}
+
+	return category, id, nil
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"strings"
+	"time"
+)
+
+// The types below mirror the JSON the API sends and accepts. They are declared here rather than shared with the
+// server so the SDK doesn't pull in its database and validation code.
+
+// ParentCategories lists the categories each category of entity can be placed under.
+var ParentCategories = map[string][]string{
+	"building":      {},
+	"room":          {"building"},
+	"shelving_unit": {"room"},
+	"shelf":         {"shelving_unit"},
+	"container":     {"shelf", "room"},
+	"item":          {"container", "shelf", "room"},
+}
+
+// Modes a batch can run in.
+const (
+	// BatchAtomic saves nothing unless every operation succeeds.
+	BatchAtomic = "atomic"
+	// BatchIndependent saves every operation that succeeds and reports the rest.
+	BatchIndependent = "independent"
+)
+
+// SignUpRequest is the body of SignUp.
+type SignUpRequest struct {
+	UserEmail string `json:"userEmail"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Birthday  string `json:"birthday"`
+}
+
+// ConfirmSignUpRequest is the body of ConfirmSignUp.
+type ConfirmSignUpRequest struct {
+	UserEmail        string `json:"userEmail"`
+	ConfirmationCode string `json:"confirmationCode"`
+}
+
+// ChallengeRequest is the body of RespondToChallenge.
+type ChallengeRequest struct {
+	UserEmail     string `json:"userEmail"`
+	ChallengeName string `json:"challengeName"`
+	Session       string `json:"session"`
+	Code          string `json:"code"`
+	NewPassword   string `json:"newPassword"`
+}
+
+// VerifySoftwareTokenRequest is the body of VerifySoftwareToken. Session is empty when enrolling the signed in user.
+type VerifySoftwareTokenRequest struct {
+	Session    string `json:"session"`
+	Code       string `json:"code"`
+	DeviceName string `json:"deviceName"`
+}
+
+type signInRequest struct {
+	UserEmail string `json:"userEmail"`
+	Password  string `json:"password"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	IDToken      string `json:"idToken"`
+}
+
+type logOutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type associateSoftwareTokenRequest struct {
+	Session string `json:"session"`
+}
+
+type generateQRRequest struct {
+	Category string `json:"category"`
+	ID       uint64 `json:"id"`
+}
+
+// CreateEntityRequest is the body of CreateEntity. Buildings have an Address and no parent, every other category
+// needs a ParentID and ParentCategory.
+type CreateEntityRequest struct {
+	Name           string  `json:"name"`
+	Notes          *string `json:"notes"`
+	Category       string  `json:"category"`
+	Address        *string `json:"address"`
+	ParentID       uint64  `json:"parentID,omitempty"`
+	ParentCategory string  `json:"parentCategory"`
+}
+
+// EditEntityRequest is the body of EditEntity.
+type EditEntityRequest struct {
+	ID uint64 `json:"id,omitempty"`
+	CreateEntityRequest
+}
+
+// BatchOperation is one create, update, move or delete in a batch. Creates can set a Ref that later operations
+// name in ParentRef instead of a ParentID. Version works like the version of EditEntity, 0 matches any version.
+type BatchOperation struct {
+	Op        string `json:"op"`
+	Ref       string `json:"ref"`
+	ParentRef string `json:"parentRef"`
+	Version   uint64 `json:"version"`
+	EditEntityRequest
+}
+
+// BatchRequest is the body of Batch.
+type BatchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult is the outcome of one operation of a batch. Data is the entity it touched, Error is the problem it
+// ran into.
+type BatchResult struct {
+	Index  int         `json:"index"`
+	Op     string      `json:"op"`
+	Ref    string      `json:"ref,omitempty"`
+	Status int         `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  interface{} `json:"error,omitempty"`
+}
+
+// BatchResponse lists the result of every operation of a batch, in the order they were sent.
+type BatchResponse struct {
+	Mode    string        `json:"mode"`
+	Results []BatchResult `json:"results"`
+}
+
+// Entity is what every category of entity has in common.
+type Entity struct {
+	ID        uint64
+	Name      string
+	Notes     *string
+	UserID    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Version goes up by one on every edit, pass it to EditEntity, PatchEntity and DeleteEntity.
+	Version uint64
+}
+
+// Parent is the entity another is placed under.
+type Parent struct {
+	ParentID       uint64
+	ParentCategory string
+}
+
+// RelatedEntity names an entity related to another, one of its parents or children.
+type RelatedEntity struct {
+	ID       uint64
+	Name     string
+	Category string
+}
+
+// EntitySummary is an entity in a list, with its parents from the closest up to the building.
+type EntitySummary struct {
+	ID       uint64
+	Name     string
+	Category string
+	Parent   []RelatedEntity
+	Notes    *string
+	Address  *string
+	Version  uint64
+}
+
+// EntitiesPage is a page of GetEntities and the total number of entities matching it.
+type EntitiesPage struct {
+	TotalCount int
+	Entities   []EntitySummary
+}
+
+// Session describes a device the user is signed in on.
+type Session struct {
+	ID         string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	Current    bool
+}
+
+// FieldError describes a single problem with one field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FieldErrors lists every problem the API found with a request body.
+type FieldErrors []FieldError
+
+// Error joins all of the messages.
+func (errs FieldErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		messages[i] = fieldErr.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
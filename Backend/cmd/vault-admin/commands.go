@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+	"willowsuite-vault/migrations"
+	"willowsuite-vault/repository"
+)
+
+func runMigrate(_ context.Context, a *app, args []string) error {
+	db := a.repository.Database
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch {
+	case action == "up" && len(args) <= 1:
+		ran, err := migrations.Up(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range ran {
+			fmt.Fprintf(a.out, "Applied %d_%s\n", migration.Version, migration.Name)
+		}
+
+		fmt.Fprintf(a.out, "%d migrations applied\n", len(ran))
+		return nil
+	case action == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number, got %q", args[1])
+			}
+		}
+
+		reverted, err := migrations.Down(db, steps)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range reverted {
+			fmt.Fprintf(a.out, "Reverted %d_%s\n", migration.Version, migration.Name)
+		}
+
+		fmt.Fprintf(a.out, "%d migrations reverted\n", len(reverted))
+		return nil
+	case action == "status" && len(args) == 1:
+		statuses, err := migrations.Status(db)
+		if err != nil {
+			return err
+		}
+
+		table := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(table, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(table, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+
+		return table.Flush()
+	default:
+		return errors.New("usage: vault-admin " + commands["migrate"].usage)
+	}
+}
+
+func runFlushCache(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("flush-cache", flag.ContinueOnError)
+	all := flags.Bool("all", false, "flush every user")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if *all == (len(positional) == 1) || len(positional) > 1 {
+		return errors.New("usage: vault-admin " + commands["flush-cache"].usage)
+	}
+
+	if *all {
+		users, err := a.repository.FlushAllEntities(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(a.out, "Flushed the cached entities of %d users\n", users)
+		return nil
+	}
+
+	a.repository.FlushEntities(ctx, positional[0])
+	fmt.Fprintf(a.out, "Flushed the cached entities of %s\n", positional[0])
+	return nil
+}
+
+func runOrphans(_ context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("orphans", flag.ContinueOnError)
+	userID := flags.String("user", "", "only check this user")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	orphans, err := a.repository.FindOrphans(*userID)
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "USER\tREF\tNAME\tMISSING PARENT")
+	for _, orphan := range orphans {
+		fmt.Fprintf(table, "%s\t%s:%d\t%s\t%s:%d\n", orphan.UserID, orphan.Category, orphan.ID, orphan.Name, orphan.ParentCategory, orphan.ParentID)
+	}
+
+	if err = table.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "%d orphaned entities\n", len(orphans))
+	return nil
+}
+
+func runReindex(ctx context.Context, a *app, _ []string) error {
+	if err := a.repository.ReindexSearch(); err != nil {
+		return err
+	}
+
+	users, err := a.repository.FlushAllEntities(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Reindexed entity tables and flushed the cached searches of %d users\n", users)
+	return nil
+}
+
+func runPurge(_ context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	olderThan := flags.Duration("older-than", 30*24*time.Hour, "only purge entities deleted longer ago than this")
+	dryRun := flags.Bool("dry-run", false, "count what would be purged without removing it")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *olderThan < 0 {
+		return errors.New("-older-than can't be negative")
+	}
+
+	before := time.Now().Add(-*olderThan)
+
+	var counts []repository.CategoryCount
+	var err error
+	if *dryRun {
+		counts, err = a.repository.CountDeleted(before)
+	} else {
+		counts, err = a.repository.PurgeDeleted(before)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	verb := "purged"
+	if *dryRun {
+		verb = "would purge"
+	}
+
+	var total int64
+	for _, count := range counts {
+		fmt.Fprintf(a.out, "%s: %s %d\n", count.Category, verb, count.Rows)
+		total += count.Rows
+	}
+
+	fmt.Fprintf(a.out, "%d entities deleted before %s %s\n", total, before.Format(time.RFC3339), verb)
+	return nil
+}
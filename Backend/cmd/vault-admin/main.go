@@ -0,0 +1,138 @@
+// Command vault-admin runs maintenance tasks against the WillowSuite Vault database and cache. It reads the same
+// environment as the API server.
+//
+// Usage:
+//
+//	vault-admin <command> [arguments]
+//
+// Run vault-admin help for the list of commands.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"willowsuite-vault/config"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/database"
+	"willowsuite-vault/repository"
+)
+
+// command is a vault-admin subcommand.
+type command struct {
+	usage       string
+	description string
+	needsCache  bool
+	run         func(ctx context.Context, app *app, args []string) error
+}
+
+// commands is filled in by init because the commands print their own usage from it.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"migrate":     {"migrate [up | down [steps] | status]", "Apply pending migrations, revert the latest ones or list them", false, runMigrate},
+		"flush-cache": {"flush-cache [-all] [user id]", "Clear the cached entities of a user, or of every user", true, runFlushCache},
+		"orphans":     {"orphans [-user id]", "List entities whose parent is deleted or missing", false, runOrphans},
+		"reindex":     {"reindex", "Rebuild the search indexes and clear every cached search", true, runReindex},
+		"purge":       {"purge [-older-than duration] [-dry-run]", "Permanently remove soft deleted entities", false, runPurge},
+	}
+}
+
+// app holds what every command needs.
+type app struct {
+	repository *repository.Repository
+	out        io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "vault-admin:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		printUsage(out)
+		return nil
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, run vault-admin help", args[0])
+	}
+
+	if err := config.SetupConfig(); err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	masterDSN, replicaDSN := config.DbConfiguration()
+	if err := database.DbConnection(masterDSN, replicaDSN); err != nil {
+		return fmt.Errorf("connecting to the database: %w", err)
+	}
+
+	a := &app{
+		repository: &repository.Repository{Database: database.GetDB()},
+		out:        out,
+	}
+
+	if cmd.needsCache {
+		if err := cache.ClientConnection(config.RedisConfiguration()); err != nil {
+			return fmt.Errorf("connecting to redis: %w", err)
+		}
+
+		a.repository.Redis = cache.GetClient()
+		if err := a.repository.Redis.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("connecting to redis: %w", err)
+		}
+
+		// Flushing has to reach the cache the API instances share, so it goes straight to Redis.
+		a.repository.Cache = cache.NewRedisCache(a.repository.Redis)
+	}
+
+	return cmd.run(ctx, a, args[1:])
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: vault-admin <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Connects with the same MASTER_DB_*, REPLICA_DB_* and REDIS_* environment as the API. Commands:")
+	fmt.Fprintln(w)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-42s %s\n", commands[name].usage, commands[name].description)
+	}
+}
+
+// parseFlags parses flags that may appear before, between or after the positional arguments.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	flags.SetOutput(io.Discard)
+
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"willowsuite-vault/client"
+
+	"gopkg.in/yaml.v2"
+)
+
+func runLogin(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	url := flags.String("url", a.credentials.URL, "API base URL")
+	email := flags.String("email", os.Getenv("VAULT_EMAIL"), "user email")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var err error
+	if *email == "" {
+		if *email, err = a.prompt("Email: "); err != nil {
+			return err
+		}
+	}
+
+	password := os.Getenv("VAULT_PASSWORD")
+	if password == "" {
+		if password, err = a.promptSecret("Password: "); err != nil {
+			return err
+		}
+	}
+
+	a.credentials = credentials{URL: strings.TrimSuffix(*url, "/")}
+	a.client = a.newClient(a.credentials.URL)
+
+	result, err := a.client.SignIn(ctx, *email, password)
+	for err == nil && result.Challenge != nil {
+		result, err = a.answerChallenge(ctx, *email, result.Challenge)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return a.printMessage("message", "Logged in as "+*email)
+}
+
+// answerChallenge prompts for whatever the challenge needs and responds to it.
+func (a *app) answerChallenge(ctx context.Context, email string, challenge *client.Challenge) (*client.SignInResult, error) {
+	body := client.ChallengeRequest{UserEmail: email, ChallengeName: challenge.Name, Session: challenge.Session}
+
+	var err error
+	switch challenge.Name {
+	case "SOFTWARE_TOKEN_MFA", "SMS_MFA":
+		body.Code, err = a.promptSecret("MFA code: ")
+	case "NEW_PASSWORD_REQUIRED":
+		body.NewPassword, err = a.promptSecret("New password: ")
+	default:
+		return nil, fmt.Errorf("the %s challenge can't be answered from the terminal, sign in on the web first", challenge.Name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a.client.RespondToChallenge(ctx, body)
+}
+
+func runLogout(ctx context.Context, a *app, _ []string) error {
+	// Forget the credentials even when the API can't revoke them, they are no use to anyone once logged out.
+	logOutErr := a.client.LogOut(ctx)
+	if err := removeCredentials(a.credentialsPath); err != nil {
+		return err
+	}
+
+	if logOutErr != nil {
+		return logOutErr
+	}
+
+	return a.printMessage("message", "Logged out")
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	inventory, node, err := a.resolve(ctx, optionalArg(args))
+	if err != nil {
+		return err
+	}
+
+	rows := []row{}
+	for _, child := range inventory.Children(node) {
+		rows = append(rows, nodeRow(child))
+	}
+
+	return a.printRows(rows)
+}
+
+func runTree(ctx context.Context, a *app, args []string) error {
+	inventory, node, err := a.resolve(ctx, optionalArg(args))
+	if err != nil {
+		return err
+	}
+
+	if a.format != formatTable {
+		trees := []treeNode{}
+		if node != nil {
+			trees = append(trees, newTreeNode(node))
+		} else {
+			for _, root := range inventory.Roots {
+				trees = append(trees, newTreeNode(root))
+			}
+		}
+
+		return encode(a.out, a.format, trees)
+	}
+
+	inventory.Walk(node, func(node *client.Node, depth int) {
+		fmt.Fprintf(a.out, "%s%s (%s)\n", strings.Repeat("  ", depth), node.Name, node.Ref())
+	})
+
+	return nil
+}
+
+func runAdd(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("add", flag.ContinueOnError)
+	parentPath := flags.String("parent", "", "path of the parent")
+	notes := flags.String("notes", "", "notes")
+	address := flags.String("address", "", "address, for buildings")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 2 {
+		return errors.New("usage: vault " + commands["add"].usage)
+	}
+
+	body := client.CreateEntityRequest{Category: positional[0], Name: positional[1]}
+	if *notes != "" {
+		body.Notes = notes
+	}
+
+	if *address != "" {
+		body.Address = address
+	}
+
+	if *parentPath != "" {
+		_, parent, err := a.resolve(ctx, *parentPath)
+		if err != nil {
+			return err
+		}
+
+		if parent != nil {
+			body.ParentCategory = parent.Category
+			body.ParentID = parent.ID
+		}
+	}
+
+	record, err := a.client.CreateEntity(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	return a.printMessage("ref", fmt.Sprintf("%s:%d", body.Category, record.Entity.ID))
+}
+
+func runMove(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: vault " + commands["mv"].usage)
+	}
+
+	inventory, node, err := a.resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return errors.New("nothing to move at /")
+	}
+
+	parent, err := inventory.Resolve(args[1])
+	if err != nil {
+		return err
+	}
+
+	if parent == nil {
+		return fmt.Errorf("a %s needs a parent", node.Category)
+	}
+
+	if !slices.Contains(client.ParentCategories[node.Category], parent.Category) {
+		return fmt.Errorf("a %s can't be put in a %s", node.Category, parent.Category)
+	}
+
+	body := client.EditEntityRequest{
+		ID: node.ID,
+		CreateEntityRequest: client.CreateEntityRequest{
+			Name:           node.Name,
+			Notes:          node.Notes,
+			Category:       node.Category,
+			Address:        node.Address,
+			ParentID:       parent.ID,
+			ParentCategory: parent.Category,
+		},
+	}
+
+	if _, err = a.client.EditEntity(ctx, body, node.Version); err != nil {
+		return err
+	}
+
+	return a.printMessage("message", fmt.Sprintf("Moved %s to %s", node.Path(), parent.Path()))
+}
+
+func runRemove(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "also delete everything inside the entity")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: vault " + commands["rm"].usage)
+	}
+
+	inventory, node, err := a.resolve(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return errors.New("refusing to remove /")
+	}
+
+	if len(node.Children) > 0 && !*recursive {
+		return fmt.Errorf("%s has %d children, use rm -r to delete them too", node.Path(), len(node.Children))
+	}
+
+	// Children have to go before their parents, so delete in the reverse of walk order.
+	var doomed []*client.Node
+	inventory.Walk(node, func(node *client.Node, _ int) {
+		doomed = append(doomed, node)
+	})
+
+	for i := len(doomed) - 1; i >= 0; i-- {
+		if err = a.client.DeleteEntity(ctx, doomed[i].Category, doomed[i].ID, doomed[i].Version); err != nil {
+			return fmt.Errorf("deleting %s: %w", doomed[i].Path(), err)
+		}
+	}
+
+	return a.printMessage("message", fmt.Sprintf("Deleted %d entities", len(doomed)))
+}
+
+func runFind(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("find", flag.ContinueOnError)
+	categories := flags.String("category", "", "comma separated categories to search")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: vault " + commands["find"].usage)
+	}
+
+	opts := client.ListOptions{Search: positional[0]}
+	if *categories != "" {
+		opts.Filters = strings.Split(*categories, ",")
+	}
+
+	entities, err := a.client.ListAll(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	rows := []row{}
+	for _, entity := range entities {
+		notes := ""
+		if entity.Notes != nil {
+			notes = *entity.Notes
+		}
+
+		rows = append(rows, row{
+			Ref:      fmt.Sprintf("%s:%d", entity.Category, entity.ID),
+			Category: entity.Category,
+			Name:     entity.Name,
+			Path:     entityPath(entity),
+			Notes:    notes,
+		})
+	}
+
+	return a.printRows(rows)
+}
+
+func runQR(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: vault " + commands["qr"].usage)
+	}
+
+	_, node, err := a.resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return errors.New("/ has no QR code")
+	}
+
+	url, err := a.client.GenerateQR(ctx, node.Category, node.ID)
+	if err != nil {
+		return err
+	}
+
+	return a.printMessage("url", url)
+}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	file := flags.String("file", "", "write to file instead of standard output")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	inventory, node, err := a.resolve(ctx, optionalArg(positional))
+	if err != nil {
+		return err
+	}
+
+	format := a.format
+	if format == formatTable {
+		format = formatFromExtension(*file)
+	}
+
+	if *file == "" {
+		return encode(a.out, format, inventory.Export(node))
+	}
+
+	out, err := os.Create(*file)
+	if err != nil {
+		return fmt.Errorf("creating export: %w", err)
+	}
+	defer out.Close()
+
+	if err = encode(out, format, inventory.Export(node)); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: vault " + commands["import"].usage)
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("reading import: %w", err)
+	}
+
+	records := []client.ExportRecord{}
+	if formatFromExtension(args[0]) == formatYAML {
+		err = yaml.Unmarshal(data, &records)
+	} else {
+		err = json.Unmarshal(data, &records)
+	}
+
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", args[0], err)
+	}
+
+	created, err := a.client.Import(ctx, records)
+	if err != nil {
+		return fmt.Errorf("imported %d of %d entities: %w", len(created), len(records), err)
+	}
+
+	if a.format != formatTable {
+		return encode(a.out, a.format, created)
+	}
+
+	for _, record := range records {
+		fmt.Fprintf(a.out, "%s -> %s\n", record.Ref, created[record.Ref])
+	}
+
+	return nil
+}
+
+// resolve loads the inventory and finds path in it.
+func (a *app) resolve(ctx context.Context, path string) (*client.Inventory, *client.Node, error) {
+	inventory, err := a.client.LoadInventory(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	node, err := inventory.Resolve(path)
+	return inventory, node, err
+}
+
+// entityPath builds the path of a search result from its chain of parents.
+func entityPath(entity client.EntitySummary) string {
+	names := []string{entity.Name}
+	for _, parent := range entity.Parent {
+		if parent.Category != "" {
+			names = append([]string{parent.Name}, names...)
+		}
+	}
+
+	return "/" + strings.Join(names, "/")
+}
+
+func formatFromExtension(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	default:
+		return formatJSON
+	}
+}
+
+func optionalArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	return args[0]
+}
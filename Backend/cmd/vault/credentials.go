@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"willowsuite-vault/client"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// credentials are what login stores between runs.
+type credentials struct {
+	URL    string        `json:"url"`
+	Tokens client.Tokens `json:"tokens"`
+}
+
+func defaultCredentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "willowsuite-vault", "credentials.json"), nil
+}
+
+func loadCredentials(path string) (credentials, error) {
+	stored := credentials{URL: envOrDefault("VAULT_URL", defaultURL)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stored, nil
+	}
+
+	if err != nil {
+		return stored, fmt.Errorf("reading credentials: %w", err)
+	}
+
+	if err = json.Unmarshal(data, &stored); err != nil {
+		return stored, fmt.Errorf("decoding credentials in %s: %w", path, err)
+	}
+
+	return stored, nil
+}
+
+// saveCredentials writes the credentials so only the current user can read them.
+func saveCredentials(path string, stored credentials) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding credentials: %w", err)
+	}
+
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing credentials: %w", err)
+	}
+
+	return nil
+}
+
+func removeCredentials(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing credentials: %w", err)
+	}
+
+	return nil
+}
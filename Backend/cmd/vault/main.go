@@ -0,0 +1,217 @@
+// Command vault manages a WillowSuite Vault inventory from the terminal.
+//
+// Usage:
+//
+//	vault [-o table|json|yaml] [-config file] <command> [arguments]
+//
+// Run vault help for the list of commands.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"willowsuite-vault/client"
+
+	"golang.org/x/term"
+)
+
+// command is a vault subcommand.
+type command struct {
+	usage       string
+	description string
+	needsLogin  bool
+	run         func(ctx context.Context, app *app, args []string) error
+}
+
+// commands is filled in by init because the commands print their own usage from it.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"login":  {"login [-url url] [-email email]", "Sign in and store credentials", false, runLogin},
+		"logout": {"logout", "Sign out and forget stored credentials", true, runLogout},
+		"ls":     {"ls [path]", "List the entities directly inside path", true, runList},
+		"tree":   {"tree [path]", "Show everything inside path as a tree", true, runTree},
+		"add":    {"add [-parent path] [-notes text] [-address text] <category> <name>", "Create an entity", true, runAdd},
+		"mv":     {"mv <path> <new parent path>", "Move an entity to a new parent", true, runMove},
+		"rm":     {"rm [-r] <path>", "Delete an entity, and with -r everything inside it", true, runRemove},
+		"find":   {"find [-category list] <text>", "Search names, notes and addresses", true, runFind},
+		"qr":     {"qr <path>", "Print a link to the QR code for an entity", true, runQR},
+		"export": {"export [-file file] [path]", "Export path, or the whole inventory, as JSON or YAML", true, runExport},
+		"import": {"import <file>", "Create the entities in a JSON or YAML export", true, runImport},
+	}
+}
+
+// app holds what every command needs.
+type app struct {
+	client          *client.Client
+	credentials     credentials
+	credentialsPath string
+	format          string
+	in              *bufio.Reader
+	// terminal is the file descriptor of in when it is a terminal, so secrets can be read without echoing them,
+	// and -1 otherwise.
+	terminal int
+	out      io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "vault:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("vault", flag.ContinueOnError)
+	format := flags.String("o", envOrDefault("VAULT_OUTPUT", formatTable), "output format: table, json or yaml")
+	credentialsPath := flags.String("config", os.Getenv("VAULT_CONFIG"), "credentials file")
+	flags.Usage = func() { printUsage(flags.Output()) }
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format != formatTable && *format != formatJSON && *format != formatYAML {
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+
+	if flags.NArg() == 0 || flags.Arg(0) == "help" {
+		printUsage(out)
+		return nil
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown command %q, run vault help", flags.Arg(0))
+	}
+
+	if *credentialsPath == "" {
+		path, err := defaultCredentialsPath()
+		if err != nil {
+			return err
+		}
+		*credentialsPath = path
+	}
+
+	stored, err := loadCredentials(*credentialsPath)
+	if err != nil {
+		return err
+	}
+
+	if cmd.needsLogin && stored.Tokens.AccessToken == "" {
+		return errors.New("not logged in, run vault login")
+	}
+
+	a := &app{
+		credentials:     stored,
+		credentialsPath: *credentialsPath,
+		format:          *format,
+		in:              bufio.NewReader(in),
+		terminal:        -1,
+		out:             out,
+	}
+	if file, ok := in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		a.terminal = int(file.Fd())
+	}
+	a.client = a.newClient(stored.URL)
+
+	return cmd.run(ctx, a, flags.Args()[1:])
+}
+
+// newClient returns a client for url that saves refreshed tokens back to the credentials file.
+func (a *app) newClient(url string) *client.Client {
+	return client.New(url,
+		client.WithTokens(a.credentials.Tokens),
+		client.WithTokenRefreshHandler(func(tokens client.Tokens) {
+			a.credentials.Tokens = tokens
+			if err := saveCredentials(a.credentialsPath, a.credentials); err != nil {
+				fmt.Fprintln(os.Stderr, "vault: saving refreshed credentials:", err)
+			}
+		}))
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: vault [-o table|json|yaml] [-config file] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Paths are entity names from the building down, such as /Home/Kitchen/Pantry, or a category:id")
+	fmt.Fprintln(w, "reference such as shelf:12. Commands:")
+	fmt.Fprintln(w)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-70s %s\n", commands[name].usage, commands[name].description)
+	}
+}
+
+// parseFlags parses flags that may appear before, between or after the positional arguments.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	flags.SetOutput(io.Discard)
+
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// prompt asks the user for a line of input.
+func (a *app) prompt(label string) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+
+	line, err := a.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("reading %s: %w", strings.TrimSuffix(strings.ToLower(label), ": "), err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptSecret is prompt without echoing what is typed when reading from a terminal. Piped input is read a line at
+// a time like any other.
+func (a *app) promptSecret(label string) (string, error) {
+	if a.terminal < 0 {
+		return a.prompt(label)
+	}
+
+	fmt.Fprint(os.Stderr, label)
+	secret, err := term.ReadPassword(a.terminal)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", strings.TrimSuffix(strings.ToLower(label), ": "), err)
+	}
+
+	return string(secret), nil
+}
+
+func envOrDefault(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"willowsuite-vault/client"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// row is an entity as printed by ls and find.
+type row struct {
+	Ref      string `json:"ref" yaml:"ref"`
+	Category string `json:"category" yaml:"category"`
+	Name     string `json:"name" yaml:"name"`
+	Path     string `json:"path" yaml:"path"`
+	Notes    string `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// treeNode is an entity as printed by tree in JSON and YAML.
+type treeNode struct {
+	Ref      string     `json:"ref" yaml:"ref"`
+	Category string     `json:"category" yaml:"category"`
+	Name     string     `json:"name" yaml:"name"`
+	Children []treeNode `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+func nodeRow(node *client.Node) row {
+	notes := ""
+	if node.Notes != nil {
+		notes = *node.Notes
+	}
+
+	return row{Ref: node.Ref(), Category: node.Category, Name: node.Name, Path: node.Path(), Notes: notes}
+}
+
+func newTreeNode(node *client.Node) treeNode {
+	tree := treeNode{Ref: node.Ref(), Category: node.Category, Name: node.Name}
+	for _, child := range node.Children {
+		tree.Children = append(tree.Children, newTreeNode(child))
+	}
+
+	return tree
+}
+
+// encode writes value as JSON or YAML.
+func encode(w io.Writer, format string, value interface{}) error {
+	if format == formatYAML {
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("encoding yaml: %w", err)
+		}
+
+		_, err = w.Write(data)
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// printRows writes rows as a table, JSON or YAML.
+func (a *app) printRows(rows []row) error {
+	if a.format != formatTable {
+		return encode(a.out, a.format, rows)
+	}
+
+	table := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "REF\tCATEGORY\tNAME\tPATH\tNOTES")
+	for _, r := range rows {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", r.Ref, r.Category, r.Name, r.Path, firstLine(r.Notes))
+	}
+
+	return table.Flush()
+}
+
+// printMessage writes a short result, wrapped in an object for JSON and YAML.
+func (a *app) printMessage(key string, value string) error {
+	if a.format != formatTable {
+		return encode(a.out, a.format, map[string]string{key: value})
+	}
+
+	_, err := fmt.Fprintln(a.out, value)
+	return err
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -16,6 +18,18 @@ func CognitoUserPoolID() string {
 	return viper.GetString("AWS_USER_POOL_ID")
 }
 
+// CognitoIssuer is the issuer of the tokens the user pool signs.
+func CognitoIssuer() string {
+	return fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", AWSRegion(), CognitoUserPoolID())
+}
+
+// CognitoJWKSURL is where the keys that sign the user pool's tokens are published. AWS_JWKS_URL overrides it, for
+// a local Cognito emulator.
+func CognitoJWKSURL() string {
+	viper.SetDefault("AWS_JWKS_URL", CognitoIssuer()+"/.well-known/jwks.json")
+	return viper.GetString("AWS_JWKS_URL")
+}
+
 func CognitoClientSecret() string {
 	return viper.GetString("AWS_CLIENT_SECRET")
 }
@@ -26,3 +40,15 @@ func CognitoSecretHash(userName string) string {
 
 	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
 }
+
+// AccessTokenMaxAge is the longest an access token issued by the user pool can stay valid.
+func AccessTokenMaxAge() time.Duration {
+	viper.SetDefault("ACCESS_TOKEN_MAX_AGE", "24h")
+	return viper.GetDuration("ACCESS_TOKEN_MAX_AGE")
+}
+
+// SessionMaxAge is the longest a sign in session can be kept alive through refresh tokens.
+func SessionMaxAge() time.Duration {
+	viper.SetDefault("SESSION_MAX_AGE", "720h")
+	return viper.GetDuration("SESSION_MAX_AGE")
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -42,3 +43,21 @@ func DbConfiguration() (string, string) {
 	)
 	return masterDBDSN, replicaDBDSN
 }
+
+// ReplicaMaxLag is how far behind the primary a replica can fall before reads stop going to it.
+func ReplicaMaxLag() time.Duration {
+	viper.SetDefault("REPLICA_MAX_LAG", "2s")
+	return viper.GetDuration("REPLICA_MAX_LAG")
+}
+
+// ReplicaCheckInterval is how often the lag of the replicas is checked.
+func ReplicaCheckInterval() time.Duration {
+	viper.SetDefault("REPLICA_CHECK_INTERVAL", "2s")
+	return viper.GetDuration("REPLICA_CHECK_INTERVAL")
+}
+
+// ReadPrimaryWindow is how long a user's reads go to the primary after they write. By the end of it every replica
+// still being read from has the write.
+func ReadPrimaryWindow() time.Duration {
+	return ReplicaMaxLag() + ReplicaCheckInterval()
+}
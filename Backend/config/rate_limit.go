@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// rateLimitDefaults are the limits of each group of routes unless RATE_LIMIT_<GROUP>_REQUESTS and
+// RATE_LIMIT_<GROUP>_WINDOW say otherwise: signing in and up per IP, signing in per account, QR codes and
+// everything else per user.
+var rateLimitDefaults = map[string]struct {
+	requests int
+	window   string
+}{
+	"auth":   {requests: 20, window: "1m"},
+	"signin": {requests: 10, window: "15m"},
+	"qr":     {requests: 30, window: "1m"},
+	"api":    {requests: 600, window: "1m"},
+}
+
+// RateLimitRequests is how many requests a client may make to a group of routes within RateLimitWindow. 0 turns
+// the limit off.
+func RateLimitRequests(group string) int {
+	key := "RATE_LIMIT_" + strings.ToUpper(group) + "_REQUESTS"
+	viper.SetDefault(key, rateLimitDefaults[group].requests)
+	return viper.GetInt(key)
+}
+
+// RateLimitWindow is the sliding window RateLimitRequests are counted over.
+func RateLimitWindow(group string) time.Duration {
+	key := "RATE_LIMIT_" + strings.ToUpper(group) + "_WINDOW"
+	viper.SetDefault(key, rateLimitDefaults[group].window)
+	return viper.GetDuration(key)
+}
@@ -23,3 +23,16 @@ func RedisConfiguration() string {
 
 	return redisConnectionString
 }
+
+// CacheDriver is where cached reads are kept: "redis", "memory" for an in-process cache that only suits a single
+// instance, or "none" to always read from the database.
+func CacheDriver() string {
+	viper.SetDefault("CACHE_DRIVER", "redis")
+	return viper.GetString("CACHE_DRIVER")
+}
+
+// CacheSize is how many entries the in-process cache keeps before dropping the least recently used.
+func CacheSize() int {
+	viper.SetDefault("CACHE_SIZE", 10000)
+	return viper.GetInt("CACHE_SIZE")
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -25,6 +28,86 @@ func FrontEndURL() string {
 	return viper.GetString("FRONT_END_URL")
 }
 
+// TrustedProxies are the proxies in front of the API, as a comma separated list of CIDRs or addresses. Only
+// requests from them have their X-Forwarded-For and X-Real-IP headers believed. It is read once at startup, and an
+// entry that doesn't parse is an error rather than a proxy silently left out.
+func TrustedProxies() ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, entry := range strings.Split(viper.GetString("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		} else {
+			return nil, fmt.Errorf("TRUSTED_PROXIES entry %q is neither a CIDR nor an address", entry)
+		}
+	}
+
+	return prefixes, nil
+}
+
 func EncryptionSecert() string {
 	return viper.GetString("ENCRYPTION_SECERT")
 }
+
+// MetricsToken is the bearer token scrapers present to read /metrics. It is separate from user sign in, and
+// /metrics is off while it is empty.
+func MetricsToken() string {
+	return viper.GetString("METRICS_TOKEN")
+}
+
+// LogFormat is the format log lines are written in, "json" or "logfmt".
+func LogFormat() string {
+	viper.SetDefault("LOG_FORMAT", "json")
+	return viper.GetString("LOG_FORMAT")
+}
+
+// LogLevel is the lowest level logged: "debug", "info", "warn", "error" or "fatal".
+func LogLevel() string {
+	viper.SetDefault("LOG_LEVEL", "info")
+	return viper.GetString("LOG_LEVEL")
+}
+
+// ServerReadTimeout is how long a client has to send a whole request, body included.
+func ServerReadTimeout() time.Duration {
+	viper.SetDefault("SERVER_READ_TIMEOUT", "30s")
+	return viper.GetDuration("SERVER_READ_TIMEOUT")
+}
+
+// ServerWriteTimeout is how long a response has to be written once its request has been read. Event streams
+// extend it for every event they send.
+func ServerWriteTimeout() time.Duration {
+	viper.SetDefault("SERVER_WRITE_TIMEOUT", "60s")
+	return viper.GetDuration("SERVER_WRITE_TIMEOUT")
+}
+
+// ServerIdleTimeout is how long a keep-alive connection is held open waiting for the next request.
+func ServerIdleTimeout() time.Duration {
+	viper.SetDefault("SERVER_IDLE_TIMEOUT", "120s")
+	return viper.GetDuration("SERVER_IDLE_TIMEOUT")
+}
+
+// ShutdownDelay is how long the server keeps serving after SIGTERM while /readyz fails, so load balancers stop
+// sending it requests before it stops accepting them.
+func ShutdownDelay() time.Duration {
+	viper.SetDefault("SHUTDOWN_DELAY", "0s")
+	return viper.GetDuration("SHUTDOWN_DELAY")
+}
+
+// ShutdownTimeout is how long requests in flight get to finish once the server stops accepting new ones.
+func ShutdownTimeout() time.Duration {
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	return viper.GetDuration("SHUTDOWN_TIMEOUT")
+}
+
+// ReadinessCacheTTL is how long /readyz reuses its last check of the dependencies, so asking it often doesn't
+// multiply the calls to them.
+func ReadinessCacheTTL() time.Duration {
+	viper.SetDefault("READINESS_CACHE_TTL", "5s")
+	return viper.GetDuration("READINESS_CACHE_TTL")
+}
@@ -0,0 +1,23 @@
+package config
+
+import "github.com/spf13/viper"
+
+// TracingExporter is where trace spans are sent: "otlp" to the collector at OTEL_EXPORTER_OTLP_ENDPOINT, "stdout",
+// "file" to append them to TracingFile, or "none" to turn tracing off.
+func TracingExporter() string {
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	return viper.GetString("TRACING_EXPORTER")
+}
+
+// TracingFile is the file the "file" exporter appends spans to, one JSON object per span.
+func TracingFile() string {
+	viper.SetDefault("TRACING_FILE", "traces.json")
+	return viper.GetString("TRACING_FILE")
+}
+
+// TracingSampleRatio is the share of new traces that are recorded, from 0 to 1. Requests that arrive with a trace
+// follow the caller's decision.
+func TracingSampleRatio() float64 {
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	return viper.GetFloat64("TRACING_SAMPLE_RATIO")
+}
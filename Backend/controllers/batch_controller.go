@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// BatchEntities returns void, but runs a list of entity operations in one transaction and sends back the result of
+// each. In atomic mode the first failure rolls everything back and is sent as the error of the request.
+func (handler Handler) BatchEntities(w http.ResponseWriter, request *http.Request) {
+	var body models.BatchRequest
+	if !decodeRequest(w, request, &body) {
+		return
+	}
+
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	response := models.BatchResponse{Mode: body.Mode, Results: make([]models.BatchResult, len(body.Operations))}
+	if response.Mode == "" {
+		response.Mode = models.BatchAtomic
+	}
+
+	var events []models.EntityEvent
+	err := handler.Repository.Transaction(request.Context(), func(repo repository.Repository) error {
+		created := map[string]*models.EntityRecord{}
+		events = nil
+
+		for i, op := range body.Operations {
+			var model *models.EntityRecord
+			var event models.EntityEvent
+			var err error
+
+			if response.Mode == models.BatchAtomic {
+				model, event, err = runBatchOperation(request.Context(), repo, userID, op, created)
+			} else {
+				// Every operation gets its own savepoint so one that fails can be undone on its own.
+				err = repo.Transaction(request.Context(), func(repo repository.Repository) error {
+					var opErr error
+					model, event, opErr = runBatchOperation(request.Context(), repo, userID, op, created)
+					return opErr
+				})
+			}
+
+			result := models.BatchResult{Index: i, Op: op.Op, Ref: op.Ref, Status: http.StatusOK}
+			if err != nil {
+				if response.Mode == models.BatchAtomic {
+					return batchError(i, err)
+				}
+
+				problem := helpers.NewProblem(request, err)
+				result.Status, result.Error = problem.Status, problem
+				response.Results[i] = result
+				continue
+			}
+
+			if op.Ref != "" {
+				created[op.Ref] = model
+			}
+
+			result.Data = event.Entity
+			response.Results[i] = result
+			events = append(events, event)
+		}
+
+		return nil
+	})
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	// The cache is cleared once for the whole batch rather than after every operation.
+	if len(events) > 0 {
+		handler.entitiesChanged(request.Context(), userID, events...)
+	}
+
+	helpers.SuccessResponse(w, response)
+}
+
+// runBatchOperation applies one operation of a batch with repo, which belongs to the batch's transaction. created
+// holds the entities made by the earlier creates of the batch, by ref. It returns the entity and the event that
+// describes the change.
+func runBatchOperation(ctx context.Context, repo repository.Repository, userID string, op models.BatchOperation, created map[string]*models.EntityRecord) (*models.EntityRecord, models.EntityEvent, error) {
+	category, id := op.Category, op.ID.Value
+
+	parent := models.Parent{ParentID: op.ParentID.Value, ParentCategory: op.ParentCategory}
+	if op.ParentRef != "" {
+		record, ok := created[op.ParentRef]
+		if !ok {
+			return nil, models.EntityEvent{}, apperrors.Conflict(fmt.Sprintf("The create with ref %v failed.", op.ParentRef))
+		}
+
+		parent.ParentID = record.Entity.ID
+	}
+
+	if category == "building" {
+		parent = models.Parent{}
+	}
+
+	var model *models.EntityRecord
+	var previous models.Parent
+	var err error
+
+	switch op.Op {
+	case "create":
+		entity := models.Entity{Name: op.Name, Notes: op.Notes, UserID: userID}
+		_, model = buildEntity(entity, parent, category, op.Address)
+		if err = repo.Save(ctx, model); err != nil {
+			return nil, models.EntityEvent{}, err
+		}
+
+		return model, models.NewEntityEvent(models.EventCreate, model), nil
+	case "update":
+		entity := models.Entity{ID: id, Name: op.Name, Notes: op.Notes, UserID: userID}
+		_, model = buildEntity(entity, parent, category, op.Address)
+	case "move":
+		_, current := buildEntity(models.Entity{ID: id}, models.Parent{}, category, nil)
+		if err = repo.GetOne(ctx, current, userID); err != nil {
+			return nil, models.EntityEvent{}, entityError(err, category, id)
+		}
+
+		_, model = buildEntity(current.Entity, parent, category, current.Attributes.Address)
+	case "delete":
+		if model, err = deleteEntity(ctx, repo, userID, category, id, op.Version.Value); err != nil {
+			return nil, models.EntityEvent{}, err
+		}
+
+		return model, models.NewEntityEvent(models.EventDelete, model), nil
+	default:
+		return nil, models.EntityEvent{}, apperrors.BadRequest(fmt.Sprintf("Unknown op %v.", op.Op), nil)
+	}
+
+	if previous, err = repo.Update(ctx, model, op.Version.Value); err != nil {
+		return nil, models.EntityEvent{}, entityError(err, category, id)
+	}
+
+	return model, updateEvent(previous, model), nil
+}
+
+// batchError names the operation an atomic batch failed at in the error sent for the whole batch.
+func batchError(index int, err error) error {
+	appErr := apperrors.As(err)
+
+	return &apperrors.Error{
+		Kind:    appErr.Kind,
+		Message: fmt.Sprintf("Operation %d: %s", index, appErr.Message),
+		Service: appErr.Service,
+		Details: appErr.Details,
+		Err:     appErr.Err,
+	}
+}
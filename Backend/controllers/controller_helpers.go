@@ -1,17 +1,131 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"willowsuite-vault/apperrors"
 	"willowsuite-vault/helpers"
 	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
 )
 
-// logAndRespond logs an error message and sends a bad request response.
-func logAndRespond(w http.ResponseWriter, message string, err error) {
-	if err != nil {
-		logger.Errorf("%s: %s", message, err)
+// requestBody is implemented by every typed request body so it can report all of its field errors at once.
+type requestBody interface {
+	Validate() models.ValidationErrors
+}
+
+// respondError logs an error and sends it to the client with the status matching its kind.
+func respondError(w http.ResponseWriter, request *http.Request, err error) {
+	appErr := apperrors.As(err)
+	if appErr.Status() >= http.StatusInternalServerError {
+		logger.Ctx(request.Context()).Errorf("%s %s: %s", request.Method, request.URL.Path, err)
 	} else {
-		logger.Errorf(message)
+		logger.Ctx(request.Context()).Warnf("%s %s: %s", request.Method, request.URL.Path, err)
+	}
+
+	helpers.ErrorResponse(w, request, appErr)
+}
+
+// entitiesChanged clears the user's cached entities and passes the changes on to their event streams and webhooks.
+func (handler Handler) entitiesChanged(ctx context.Context, userID string, events ...models.EntityEvent) {
+	handler.Repository.FlushEntities(ctx, userID)
+	handler.Repository.PublishEvents(ctx, userID, events...)
+	handler.Repository.QueueWebhooks(ctx, userID, events...)
+}
+
+// decodeRequest reads the request body into body and validates it. It responds to the client and returns false
+// when the body can't be parsed or has field errors.
+func decodeRequest(w http.ResponseWriter, request *http.Request, body requestBody) bool {
+	byteData, err := io.ReadAll(request.Body)
+	if err != nil {
+		respondError(w, request, apperrors.BadRequest("Error parsing request", err))
+		return false
+	}
+
+	if err = parseBody(byteData, body); err != nil {
+		respondError(w, request, err)
+		return false
+	}
+
+	return true
+}
+
+// parseBody unmarshals JSON into body and validates it, returning the problems as an application error. Fields of
+// the wrong JSON type are listed along with whatever else Validate finds wrong.
+func parseBody(byteData []byte, body requestBody) error {
+	err := json.Unmarshal(byteData, body)
+
+	var typeErr *json.UnmarshalTypeError
+	if err != nil && !errors.As(err, &typeErr) {
+		return apperrors.BadRequest("Error parsing json", err)
+	}
+
+	errs := models.ValidationErrors{}
+	if typeErr != nil {
+		errs = typeErrors(byteData, body, typeErr)
 	}
-	helpers.BadRequest(w, message)
+
+	// A field of the wrong type is left empty, which Validate would also report as missing.
+	wrongType := map[string]bool{}
+	for _, fieldErr := range errs {
+		wrongType[fieldErr.Field] = true
+	}
+
+	for _, fieldErr := range body.Validate() {
+		if !wrongType[fieldErr.Field] {
+			errs = append(errs, fieldErr)
+		}
+	}
+
+	if len(errs) > 0 {
+		return apperrors.Validation(errs)
+	}
+
+	return nil
+}
+
+// typeErrors lists every field of a JSON object that has the wrong type for body. json.Unmarshal only reports the
+// first, so each field is decoded again on its own. first is reported when the body isn't an object.
+func typeErrors(byteData []byte, body requestBody, first *json.UnmarshalTypeError) models.ValidationErrors {
+	errs := models.ValidationErrors{}
+	add := func(typeErr *json.UnmarshalTypeError) {
+		errs.Add(typeErr.Field, models.ValidationInvalidType, fmt.Sprintf("%v must be type %v", typeErr.Field, typeErr.Type))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(byteData))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		add(first)
+		return errs
+	}
+
+	bodyType := reflect.TypeOf(body).Elem()
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			break
+		}
+
+		field, _ := json.Marshal(map[string]json.RawMessage{token.(string): value})
+		var typeErr *json.UnmarshalTypeError
+		if err = json.Unmarshal(field, reflect.New(bodyType).Interface()); errors.As(err, &typeErr) {
+			add(typeErr)
+		}
+	}
+
+	if len(errs) == 0 {
+		add(first)
+	}
+
+	return errs
 }
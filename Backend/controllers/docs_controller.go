@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/docs"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// OpenAPISpec returns void, but sends the OpenAPI document for the API back to the client.
+func (handler Handler) OpenAPISpec(w http.ResponseWriter, request *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docs.Spec)
+}
+
+// Docs returns void, but sends the page rendering the OpenAPI document back to the client.
+func (handler Handler) Docs(w http.ResponseWriter, request *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docs.UI)
+}
+
+// DocsAsset returns void, but sends one of the vendored Swagger UI files the docs page loads back to the client.
+func (handler Handler) DocsAsset(w http.ResponseWriter, request *http.Request) {
+	name := path.Join("swagger-ui", chi.URLParam(request, "file"))
+	if _, err := fs.Stat(docs.Assets, name); err != nil {
+		respondError(w, request, apperrors.NotFound("Docs asset not found."))
+		return
+	}
+
+	http.ServeFileFS(w, request, docs.Assets, name)
+}
@@ -2,15 +2,18 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"willowsuite-vault/apperrors"
 	"willowsuite-vault/helpers"
 	"willowsuite-vault/infra/logger"
 	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
@@ -18,59 +21,44 @@ import (
 
 // CreateEntity returns void but sends a success message or error message back to the client.
 func (handler Handler) CreateEntity(w http.ResponseWriter, request *http.Request) {
-	byteData, err := io.ReadAll(request.Body)
-	if err != nil {
-		logAndRespond(w, "Error parsing request", err)
-		return
-	}
-
-	var parsedData map[string]string
-	err = json.Unmarshal(byteData, &parsedData)
-	if err != nil {
-		logAndRespond(w, "Error parsing json", err)
-		return
-	}
-
-	_, name, category, parentID, parentCategory, err := validateParams(parsedData, false)
-	if err != nil {
-		logAndRespond(w, "Error validating parameters", err)
+	var body models.CreateEntityRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
 	claims := request.Context().Value("user_claims").(jwt.MapClaims)
 	userID := claims["username"].(string)
-	tmpNotes := parsedData["notes"]
+	category := body.Category
 
 	var parent models.Parent
 	if category != "building" {
 		validParent := false
-		validParent, parent = buildParent(category, parentID, parentCategory)
+		validParent, parent = buildParent(category, body.ParentID.Value, body.ParentCategory)
 		if !validParent {
-			logAndRespond(w, "Invalid parent.", nil)
+			respondError(w, request, apperrors.BadRequest("Invalid parent.", nil))
 			return
 		}
 	}
 
 	entity := models.Entity{
-		Name:   name,
-		Notes:  &tmpNotes,
+		Name:   body.Name,
+		Notes:  body.Notes,
 		UserID: userID,
 	}
 
-	validEntity, model := buildEntity(entity, parent, category, parsedData["address"])
+	validEntity, model := buildEntity(entity, parent, category, body.Address)
 	if !validEntity {
-		logAndRespond(w, fmt.Sprintf("Invalid category %v.", category), nil)
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil))
 		return
 	}
 
-	dberr := handler.Repository.Save(model)
-	if dberr != nil {
-		logAndRespond(w, "Error adding etity.", nil)
+	if err := handler.Repository.Save(request.Context(), model); err != nil {
+		respondError(w, request, err)
 		return
 	}
 
-	handler.Repository.FlushEntities(request.Context(), userID)
-	helpers.SuccessResponse(w, &model)
+	handler.entitiesChanged(request.Context(), userID, models.NewEntityEvent(models.EventCreate, model))
+	helpers.SuccessResponse(w, model.View())
 }
 
 // GetEntities return void, but sends a paginated list of all entities back to the client.
@@ -80,7 +68,7 @@ func (handler Handler) GetEntities(w http.ResponseWriter, request *http.Request)
 	values := request.URL.Query()
 	offset, limit, search, filters, err := getEntitiesParseQueryParams(values)
 	if err != nil {
-		logAndRespond(w, "Error reading query parameters", err)
+		respondError(w, request, apperrors.BadRequest("Error reading query parameters", err))
 		return
 	}
 
@@ -88,12 +76,12 @@ func (handler Handler) GetEntities(w http.ResponseWriter, request *http.Request)
 	userID := claims["username"].(string)
 	response.Entities, err = handler.Repository.GetAllEntities(request.Context(), userID, offset, limit, search, filters)
 	if err != nil {
-		logAndRespond(w, fmt.Sprintf("Error getting entries: %v", err), err)
+		respondError(w, request, err)
 		return
 	}
 
 	response.TotalCount = handler.Repository.CountEntities(request.Context(), userID, search, filters)
-	helpers.SuccessResponse(w, &response)
+	helpers.ConditionalResponse(w, request, &response)
 }
 
 // GetEntity return void, but sends a single entity back to the client if it finds a match.
@@ -103,7 +91,7 @@ func (handler Handler) GetEntity(w http.ResponseWriter, request *http.Request) {
 
 	id, err := strconv.ParseUint(idParam, 10, 64)
 	if err != nil {
-		logAndRespond(w, fmt.Sprintf("ID must be type integer: %v", idParam), nil)
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("ID must be type integer: %v", idParam), err))
 		return
 	}
 
@@ -114,132 +102,183 @@ func (handler Handler) GetEntity(w http.ResponseWriter, request *http.Request) {
 		ID: id,
 	}
 
-	validEntity, model := buildEntity(entity, models.Parent{}, category, "")
+	validEntity, model := buildEntity(entity, models.Parent{}, category, nil)
 	if !validEntity {
-		logAndRespond(w, fmt.Sprintf("Invalid category %v.", category), nil)
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil))
 		return
 	}
 
-	dberr := handler.Repository.GetOne(model, userID)
-	if dberr != nil {
-		logAndRespond(w, fmt.Sprintf("Entity category of %v with id %v not found.", category, id), nil)
+	if err = handler.Repository.GetOne(request.Context(), model, userID); err != nil {
+		respondError(w, request, entityError(err, category, id))
 		return
 	}
 
-	helpers.SuccessResponse(w, model)
+	w.Header().Set("ETag", helpers.ETag(model.Entity.Version))
+	helpers.SuccessResponse(w, model.View())
 }
 
 // EditEntity returns void, but sends a success message or error message back to the client.
 func (handler Handler) EditEntity(w http.ResponseWriter, request *http.Request) {
-	byteData, err := io.ReadAll(request.Body)
-	if err != nil {
-		logAndRespond(w, "Error parsing request", err)
-		return
-	}
-
-	var parsedData map[string]string
-	if err = json.Unmarshal(byteData, &parsedData); err != nil {
-		logAndRespond(w, "Error parsing json", err)
+	var body models.EditEntityRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
-	id, name, category, parentID, parentCategory, err := validateParams(parsedData, true)
+	version, err := helpers.IfMatchVersion(request)
 	if err != nil {
-		logAndRespond(w, "Error validating parameters: ", err)
+		respondError(w, request, err)
 		return
 	}
 
 	claims := request.Context().Value("user_claims").(jwt.MapClaims)
 	userID := claims["username"].(string)
-	tmpNotes := parsedData["notes"]
+	id, category := body.ID.Value, body.Category
 
 	var parent models.Parent
 	if category != "building" {
 		validParent := false
-		validParent, parent = buildParent(category, parentID, parentCategory)
+		validParent, parent = buildParent(category, body.ParentID.Value, body.ParentCategory)
 		if !validParent {
-			logAndRespond(w, "Invalid parent.", nil)
+			respondError(w, request, apperrors.BadRequest("Invalid parent.", nil))
 			return
 		}
 	}
 
 	entity := models.Entity{
 		ID:     id,
-		Name:   name,
-		Notes:  &tmpNotes,
+		Name:   body.Name,
+		Notes:  body.Notes,
 		UserID: userID,
 	}
 
-	validEntity, model := buildEntity(entity, parent, category, parsedData["address"])
+	validEntity, model := buildEntity(entity, parent, category, body.Address)
 	if !validEntity {
-		logAndRespond(w, fmt.Sprintf("Invalid category %v.", category), nil)
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil))
 		return
 	}
 
-	dberr := handler.Repository.Save(model)
-	if dberr != nil {
-		logAndRespond(w, fmt.Sprintf("Entity category of %v with id %v not found.", category, id), nil)
+	previous, err := handler.Repository.Update(request.Context(), model, version)
+	if err != nil {
+		respondError(w, request, entityError(err, category, id))
 		return
 	}
 
-	handler.Repository.FlushEntities(request.Context(), userID)
-	helpers.SuccessResponse(w, model)
+	handler.entitiesChanged(request.Context(), userID, updateEvent(previous, model))
+	w.Header().Set("ETag", helpers.ETag(model.Entity.Version))
+	helpers.SuccessResponse(w, model.View())
 }
 
-// DeleteEntity return void, but sends a confirmation message to the client.
-func (handler Handler) DeleteEntity(w http.ResponseWriter, request *http.Request) {
+// PatchEntity returns void, but applies a JSON Merge Patch or JSON Patch to an entity and sends the result back.
+func (handler Handler) PatchEntity(w http.ResponseWriter, request *http.Request) {
 	category := chi.URLParam(request, "category")
 	idParam := chi.URLParam(request, "id")
 
 	id, err := strconv.ParseUint(idParam, 10, 64)
 	if err != nil {
-		logAndRespond(w, fmt.Sprintf("ID must be type integer: %v", idParam), nil)
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("ID must be type integer: %v", idParam), err))
+		return
+	}
+
+	version, err := helpers.IfMatchVersion(request)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	patch, err := io.ReadAll(request.Body)
+	if err != nil {
+		respondError(w, request, apperrors.BadRequest("Error parsing request", err))
 		return
 	}
 
 	claims := request.Context().Value("user_claims").(jwt.MapClaims)
 	userID := claims["username"].(string)
 
-	entity := models.Entity{
-		ID: id,
+	validEntity, current := buildEntity(models.Entity{ID: id}, models.Parent{}, category, nil)
+	if !validEntity {
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil))
+		return
 	}
 
-	validEntity, model := buildEntity(entity, models.Parent{}, category, "")
-	if !validEntity {
-		logAndRespond(w, fmt.Sprintf("Invalid category %v.", category), nil)
+	if err = handler.Repository.GetOne(request.Context(), current, userID); err != nil {
+		respondError(w, request, entityError(err, category, id))
 		return
 	}
 
-	dberr := handler.Repository.GetOne(model, userID)
-	if dberr != nil {
-		logAndRespond(w, fmt.Sprintf("Entity category of %v with id %v not found.", category, id), nil)
+	if version != 0 && version != current.Entity.Version {
+		respondError(w, request, apperrors.PreconditionFailed("The entity has been changed since it was read."))
 		return
 	}
 
-	if category != "item" {
-		hasChildren, count, err := handler.Repository.HasChildren(entity.ID, category, userID)
-		if err != nil {
-			logAndRespond(w, "Issue getting children", err)
-			return
-		}
+	body, err := patchRequest(current.Request(), request.Header.Get("Content-Type"), patch)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
 
-		if hasChildren {
-			logAndRespond(w,
-				fmt.Sprintf("Cannot delete entity with children. Number of children: %d", count),
-				fmt.Errorf("Cannot delete entity with children. Number of children: %v", count))
+	var parent models.Parent
+	if category != "building" {
+		validParent := false
+		validParent, parent = buildParent(category, body.ParentID.Value, body.ParentCategory)
+		if !validParent {
+			respondError(w, request, apperrors.BadRequest("Invalid parent.", nil))
 			return
 		}
 	}
 
-	dberr = handler.Repository.Delete(model, userID)
-	if dberr != nil {
-		logAndRespond(w,
-			fmt.Sprintf("Error deleting entity: %s - %d", category, id),
-			fmt.Errorf("Error deleting entity: %s - %d", category, id))
+	entity := models.Entity{
+		ID:     id,
+		Name:   body.Name,
+		Notes:  body.Notes,
+		UserID: userID,
+	}
+
+	_, model := buildEntity(entity, parent, category, body.Address)
+
+	// The patch was applied to the version that was just read, so it is the one that has to be replaced.
+	previous, err := handler.Repository.Update(request.Context(), model, current.Entity.Version)
+	if err != nil {
+		respondError(w, request, entityError(err, category, id))
 		return
 	}
 
-	handler.Repository.FlushEntities(request.Context(), userID)
+	handler.entitiesChanged(request.Context(), userID, updateEvent(previous, model))
+	w.Header().Set("ETag", helpers.ETag(model.Entity.Version))
+	helpers.SuccessResponse(w, model.View())
+}
+
+// DeleteEntity return void, but sends a confirmation message to the client.
+func (handler Handler) DeleteEntity(w http.ResponseWriter, request *http.Request) {
+	category := chi.URLParam(request, "category")
+	idParam := chi.URLParam(request, "id")
+
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("ID must be type integer: %v", idParam), err))
+		return
+	}
+
+	version, err := helpers.IfMatchVersion(request)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	if _, ok := models.ParentCategories[category]; !ok {
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil))
+		return
+	}
+
+	model, err := deleteEntity(request.Context(), *handler.Repository, userID, category, id, version)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	handler.entitiesChanged(request.Context(), userID, models.NewEntityEvent(models.EventDelete, model))
 	helpers.SuccessResponse(w, "Successfully Deleted!")
 }
 
@@ -252,7 +291,7 @@ func (handler Handler) GetParents(w http.ResponseWriter, request *http.Request)
 		category != "shelf" &&
 		category != "shelving_unit" &&
 		category != "room" {
-		logAndRespond(w, "Invalid category", nil)
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil))
 		return
 	}
 
@@ -261,7 +300,7 @@ func (handler Handler) GetParents(w http.ResponseWriter, request *http.Request)
 
 	parents, err := handler.Repository.GetParents(request.Context(), category, userID)
 	if err != nil {
-		logAndRespond(w, "Issue getting parents.", err)
+		respondError(w, request, err)
 		return
 	}
 
@@ -276,97 +315,132 @@ func (handler Handler) GetChildren(w http.ResponseWriter, request *http.Request)
 	idParam := chi.URLParam(request, "id")
 
 	if category == "" {
-		logAndRespond(w, "Missing category", nil)
+		respondError(w, request, apperrors.BadRequest("Missing category", nil))
+		return
 	}
 
 	if idParam == "" {
-		logAndRespond(w, "Missing id", nil)
+		respondError(w, request, apperrors.BadRequest("Missing id", nil))
+		return
 	}
 
 	id, err := strconv.ParseUint(idParam, 10, 64)
 	if err != nil {
-		err = errors.New("ID must be type integer")
+		respondError(w, request, apperrors.BadRequest(fmt.Sprintf("ID must be type integer: %v", idParam), err))
+		return
 	}
 
 	claims := request.Context().Value("user_claims").(jwt.MapClaims)
 	userID := claims["username"].(string)
-	response, _ = handler.Repository.GetChildren(id, category, userID)
+	response, err = handler.Repository.GetChildren(request.Context(), id, category, userID)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
 
 	helpers.SuccessResponse(w, &response)
 }
 
-func validateParams(parsedData map[string]string, edit bool) (uint64, string, string, uint64, string, error) {
-	var err error
-	var id uint64
-	if parsedData["id"] == "" && edit {
-		err = errors.New("Missing id")
+// updateEvent describes an update as a move when it took the entity away from its previous parent.
+func updateEvent(previous models.Parent, model *models.EntityRecord) models.EntityEvent {
+	if previous != model.Parent() {
+		return models.NewEntityEvent(models.EventMove, model)
 	}
 
-	if parsedData["name"] == "" {
-		err = errors.New("Missing name")
+	return models.NewEntityEvent(models.EventUpdate, model)
+}
+
+// deleteEntity soft deletes an entity that is still at version and has no children, and returns it.
+func deleteEntity(ctx context.Context, repo repository.Repository, userID string, category string, id uint64, version uint64) (*models.EntityRecord, error) {
+	_, model := buildEntity(models.Entity{ID: id}, models.Parent{}, category, nil)
+	if err := repo.GetOne(ctx, model, userID); err != nil {
+		return nil, entityError(err, category, id)
 	}
 
-	if parsedData["category"] == "" {
-		err = errors.New("Missing category")
+	if version != 0 && version != model.Entity.Version {
+		return nil, apperrors.PreconditionFailed("The entity has been changed since it was read.")
 	}
 
-	if parsedData["parentID"] == "" && parsedData["category"] != "building" {
-		err = errors.New("Missing parent id")
+	if category != "item" {
+		hasChildren, count, err := repo.HasChildren(ctx, id, category, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if hasChildren {
+			return nil, apperrors.Conflict(fmt.Sprintf("Cannot delete entity with children. Number of children: %d", count))
+		}
 	}
 
-	if parsedData["parentCategory"] == "" && parsedData["category"] != "building" {
-		err = errors.New("Missing parent category")
+	return model, entityError(repo.Delete(ctx, model, userID, version), category, id)
+}
+
+// patchRequest applies a patch to the body of the request that would create an entity as it is now, and checks
+// the result like any other request body. The category of an entity can't be patched.
+func patchRequest(document models.CreateEntityRequest, contentType string, patch []byte) (models.CreateEntityRequest, error) {
+	var body models.CreateEntityRequest
+
+	current, err := json.Marshal(document)
+	if err != nil {
+		return body, apperrors.Internal("Error encoding entity.", err)
 	}
 
-	if edit {
-		id, err = strconv.ParseUint(parsedData["id"], 10, 64)
-		if err != nil {
-			err = errors.New("ID must be type integer")
+	members := map[string]json.RawMessage{}
+	if err = json.Unmarshal(current, &members); err != nil {
+		return body, apperrors.Internal("Error encoding entity.", err)
+	}
+
+	known := make(map[string]bool, len(members))
+	for name := range members {
+		known[name] = true
+	}
+
+	if err = helpers.ApplyPatch(contentType, members, patch); err != nil {
+		return body, err
+	}
+
+	errs := models.ValidationErrors{}
+	for name := range members {
+		if !known[name] {
+			errs.Add(name, models.ValidationUnknownField, fmt.Sprintf("Unknown field %v.", name))
 		}
 	}
 
-	parentID, err2 := strconv.ParseUint(parsedData["parentID"], 10, 64)
-	if err2 != nil && parsedData["category"] != "building" {
-		err = errors.New("Parent ID must be type integer")
+	var category string
+	if err = json.Unmarshal(members["category"], &category); err != nil || category != document.Category {
+		errs.Add("category", models.ValidationReadOnly, "Category can't be changed.")
 	}
 
-	return id, parsedData["name"], parsedData["category"], parentID, parsedData["parentCategory"], err
+	if len(errs) > 0 {
+		return body, apperrors.Validation(errs)
+	}
+
+	patched, err := json.Marshal(members)
+	if err != nil {
+		return body, apperrors.Internal("Error encoding entity.", err)
+	}
+
+	err = parseBody(patched, &body)
+	return body, err
 }
 
-func validateParent(category string, parentCategory string) bool {
-	isValid := false
+// entityError names the entity in a not found error so the client knows which lookup failed.
+func entityError(err error, category string, id uint64) error {
+	if apperrors.Is(err, apperrors.KindNotFound) {
+		return apperrors.NotFound(fmt.Sprintf("Entity category of %v with id %v not found.", category, id))
+	}
 
-	switch category {
-	case "item":
-		if (parentCategory == "container") || (parentCategory == "shelf") || (parentCategory == "room") {
-			isValid = true
-		}
-		break
-	case "container":
-		if (parentCategory == "shelf") || (parentCategory == "room") {
-			isValid = true
-		}
-		break
-	case "shelf":
-		if parentCategory == "shelving_unit" {
-			isValid = true
-		}
-		break
-	case "shelving_unit":
-		if parentCategory == "room" {
-			isValid = true
-		}
-		break
-	case "room":
-		if parentCategory == "building" {
-			isValid = true
-		}
-		break
-	default:
+	return err
+}
+
+func validateParent(category string, parentCategory string) bool {
+	validParents, ok := models.ParentCategories[category]
+	if !ok {
 		logger.Errorf("Invalid category for entity.")
+		return false
 	}
 
-	return isValid
+	return slices.Contains(validParents, parentCategory)
 }
 
 func buildParent(category string, parentID uint64, parentCategory string) (bool, models.Parent) {
@@ -384,52 +458,10 @@ func buildParent(category string, parentID uint64, parentCategory string) (bool,
 	return isParentValid, parent
 }
 
-func buildEntity(entity models.Entity, parent models.Parent, category string, address string) (bool, interface{}) {
-	valid := true
-	var model interface{}
-
-	switch category {
-	case "item":
-		model = &models.Item{
-			Entity: entity,
-			Parent: parent,
-		}
-		break
-	case "container":
-		model = &models.Container{
-			Entity: entity,
-			Parent: parent,
-		}
-		break
-	case "shelf":
-		model = &models.Shelf{
-			Entity: entity,
-			Parent: parent,
-		}
-		break
-	case "shelving_unit":
-		model = &models.ShelvingUnit{
-			Entity: entity,
-			Parent: parent,
-		}
-		break
-	case "room":
-		model = &models.Room{
-			Entity: entity,
-			Parent: parent,
-		}
-		break
-	case "building":
-		tmpAddress := address
-
-		model = &models.Building{
-			Entity:  entity,
-			Address: &tmpAddress,
-		}
-		break
-	default:
+func buildEntity(entity models.Entity, parent models.Parent, category string, address *string) (bool, *models.EntityRecord) {
+	model, valid := models.NewEntityRecord(category, entity, parent, address)
+	if !valid {
 		logger.Errorf("Invalid Category: %v", category)
-		valid = false
 	}
 
 	return valid, model
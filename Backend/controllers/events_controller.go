@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// eventsHeartbeat is how often a comment is sent on an idle event stream so proxies don't close it.
+	eventsHeartbeat = 25 * time.Second
+	// eventsWriteTimeout is how long each write to an event stream gets. It stands in for the server's
+	// WriteTimeout, which would otherwise end every stream that long after it opened.
+	eventsWriteTimeout = 10 * time.Second
+)
+
+// Events returns void, but streams changes to the user's entities to the client as Server-Sent Events until the
+// client goes away. A client that reconnects with Last-Event-ID first gets the events it missed. When some of them
+// are no longer stored it gets a reset event instead and should reload its entities.
+func (handler Handler) Events(w http.ResponseWriter, request *http.Request) {
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+	ctx := request.Context()
+
+	// Subscribe before reading the stored events so nothing published in between is lost.
+	subscription, err := handler.Repository.SubscribeEvents(ctx, userID)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+	defer subscription.Close()
+
+	lastID := request.Header.Get("Last-Event-ID")
+	var missed []models.EntityEvent
+	complete := true
+	if lastID != "" {
+		if missed, complete, err = handler.Repository.EventsSince(ctx, userID, lastID); err != nil {
+			respondError(w, request, err)
+			return
+		}
+	}
+
+	controller := http.NewResponseController(w)
+	extendWrite(controller)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	for _, event := range missed {
+		writeEvent(w, event)
+		lastID = event.ID
+	}
+
+	if err := controller.Flush(); err != nil {
+		logger.Ctx(request.Context()).Warnf("%s %s: %s", request.Method, request.URL.Path, err)
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	messages := subscription.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ShuttingDown():
+			// The client reconnects with Last-Event-ID, to an instance that isn't going away.
+			return
+		case <-heartbeat.C:
+			extendWrite(controller)
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var event models.EntityEvent
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				logger.Ctx(request.Context()).Errorf("error decoding event: %v", err)
+				continue
+			}
+
+			// Events already sent from the stored ones come through the subscription as well.
+			if models.ValidEventID(lastID) && !models.EventIDBefore(lastID, event.ID) {
+				continue
+			}
+
+			extendWrite(controller)
+			writeEvent(w, event)
+			lastID = event.ID
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// extendWrite gives the next write to an event stream eventsWriteTimeout from now. Writers without deadlines, such
+// as test recorders, are left as they are.
+func extendWrite(controller *http.ResponseController) {
+	controller.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+}
+
+// writeEvent writes one event in the Server-Sent Events format, named after its type.
+func writeEvent(w http.ResponseWriter, event models.EntityEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Errorf("error encoding event: %v", err)
+		return
+	}
+
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
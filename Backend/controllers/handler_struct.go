@@ -2,6 +2,7 @@
 package controllers
 
 import (
+	"net/netip"
 	"willowsuite-vault/helpers"
 	"willowsuite-vault/infra/cognito"
 	"willowsuite-vault/infra/s3"
@@ -15,4 +16,5 @@ type Handler struct {
 	S3Client        s3.S3Client
 	S3PresignClient s3.S3PresignClient
 	TokenHelper     helpers.TokenHelper
+	TrustedProxies  []netip.Prefix
 }
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"willowsuite-vault/config"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/database"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// readinessTimeout is how long each dependency gets to answer a readiness check.
+const readinessTimeout = 2 * time.Second
+
+var (
+	shutdown     = make(chan struct{})
+	shutdownOnce sync.Once
+)
+
+// Shutdown marks the server as shutting down: /readyz fails from then on so load balancers stop sending requests,
+// and event streams end so their clients reconnect to another instance.
+func Shutdown() {
+	shutdownOnce.Do(func() { close(shutdown) })
+}
+
+// ShuttingDown is closed once Shutdown has been called.
+func ShuttingDown() <-chan struct{} {
+	return shutdown
+}
+
+// dependencyCheck checks one dependency, returning nil when it is healthy.
+type dependencyCheck struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+}
+
+// Healthz returns void, but tells whoever asks that the process is up. It checks no dependencies, so an outage of
+// one doesn't get every instance restarted.
+func (handler Handler) Healthz(w http.ResponseWriter, _ *http.Request) {
+	helpers.SuccessResponse(w, "alive ok")
+}
+
+// Readyz returns void, but reports whether the API is ready from the last check of every dependency. It answers 503
+// while Postgres, which every request needs, is down or once the server is shutting down. Redis, S3, Cognito and
+// the read replicas being down only makes the API degraded: reads skip the cache and tokens are checked against
+// this instance's revocations, so taking every instance out of rotation together wouldn't help.
+//
+// Anyone can ask, so only callers with the metrics token are told how each dependency answered. Everyone else gets
+// the overall status, and the errors are logged.
+func (handler Handler) Readyz(w http.ResponseWriter, request *http.Request) {
+	readiness := models.Readiness{Status: models.HealthUp, Dependencies: handler.checkDependencies(request.Context())}
+
+	select {
+	case <-ShuttingDown():
+		readiness.Status, readiness.ShuttingDown = models.HealthDown, true
+	default:
+	}
+
+	// Replicas are checked in the background already, so their last check is reported rather than a new one.
+	for _, replica := range database.Replicas() {
+		health := models.DependencyHealth{Status: models.HealthUp}
+		if !replica.Healthy {
+			health.Status, health.Error = models.HealthDown, replica.Error
+			if health.Error == "" {
+				health.Error = fmt.Sprintf("%s behind the primary", replica.Lag)
+			}
+		}
+		readiness.Dependencies["postgres_"+replica.Name] = health
+	}
+
+	for _, health := range readiness.Dependencies {
+		switch {
+		case health.Status == models.HealthUp:
+		case health.Required:
+			readiness.Status = models.HealthDown
+		case readiness.Status == models.HealthUp:
+			readiness.Status = models.HealthDegraded
+		}
+	}
+
+	status := http.StatusOK
+	if readiness.Status == models.HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	if !presentsMetricsToken(request) {
+		readiness.Dependencies = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": readiness.Status,
+		"data":    readiness,
+	})
+}
+
+// lastChecks is the last time the dependencies were checked and how each answered.
+var lastChecks struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	results   map[string]models.DependencyHealth
+}
+
+// checkDependencies checks every dependency at once, or returns a copy of the last check while it is younger than
+// config.ReadinessCacheTTL. Requests that arrive during a check wait for it rather than starting their own.
+func (handler Handler) checkDependencies(ctx context.Context) map[string]models.DependencyHealth {
+	lastChecks.mu.Lock()
+	defer lastChecks.mu.Unlock()
+
+	if lastChecks.results == nil || time.Since(lastChecks.checkedAt) >= config.ReadinessCacheTTL() {
+		// The results are shared, so the caller hanging up mustn't cut the checks short.
+		ctx = context.WithoutCancel(ctx)
+		results := map[string]models.DependencyHealth{}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, dependency := range handler.dependencyChecks() {
+			wg.Add(1)
+			go func(dependency dependencyCheck) {
+				defer wg.Done()
+
+				ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+				defer cancel()
+
+				start := time.Now()
+				err := dependency.check(ctx)
+				health := models.DependencyHealth{
+					Status:    models.HealthUp,
+					Required:  dependency.required,
+					LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+				}
+				if err != nil {
+					health.Status, health.Error = models.HealthDown, err.Error()
+					logger.Ctx(ctx).Warnf("readiness check of %s failed: %v", dependency.name, err)
+				}
+
+				mu.Lock()
+				results[dependency.name] = health
+				mu.Unlock()
+			}(dependency)
+		}
+		wg.Wait()
+
+		lastChecks.results, lastChecks.checkedAt = results, time.Now()
+	}
+
+	results := make(map[string]models.DependencyHealth, len(lastChecks.results))
+	for name, health := range lastChecks.results {
+		results[name] = health
+	}
+
+	return results
+}
+
+// dependencyChecks lists the dependencies Readyz checks.
+func (handler Handler) dependencyChecks() []dependencyCheck {
+	return []dependencyCheck{
+		{name: "postgres", required: true, check: func(ctx context.Context) error {
+			return database.PingPrimary(ctx, handler.Repository.Database)
+		}},
+		{name: "redis", check: func(ctx context.Context) error {
+			return handler.Repository.Redis.Ping(ctx).Err()
+		}},
+		{name: "s3", check: func(ctx context.Context) error {
+			_, err := handler.S3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(config.S3BucketName())})
+			return err
+		}},
+		{name: "cognito", check: func(ctx context.Context) error {
+			// Tokens are verified with these keys, so Cognito is usable when they can be fetched.
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.CognitoJWKSURL(), nil)
+			if err != nil {
+				return err
+			}
+
+			response, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusOK {
+				return fmt.Errorf("signing keys answered %d", response.StatusCode)
+			}
+
+			return nil
+		}},
+	}
+}
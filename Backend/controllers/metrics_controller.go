@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/config"
+	"willowsuite-vault/infra/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var metricsHandler = promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+
+// Metrics returns void, but sends the Prometheus metrics of this instance back to a scraper that presents the
+// metrics token. The endpoint is off when no token is configured.
+func (handler Handler) Metrics(w http.ResponseWriter, request *http.Request) {
+	if config.MetricsToken() == "" {
+		respondError(w, request, apperrors.NotFound("Metrics are not enabled."))
+		return
+	}
+
+	if !presentsMetricsToken(request) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		respondError(w, request, apperrors.Unauthorized("Invalid metrics token.", nil))
+		return
+	}
+
+	metricsHandler.ServeHTTP(w, request)
+}
+
+// presentsMetricsToken reports whether the request carries the metrics token as a bearer token. It never does
+// while no token is configured.
+func presentsMetricsToken(request *http.Request) bool {
+	token := config.MetricsToken()
+	presented, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	return token != "" && ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
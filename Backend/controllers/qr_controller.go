@@ -7,22 +7,23 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+	"willowsuite-vault/apperrors"
 	"willowsuite-vault/config"
 	"willowsuite-vault/helpers"
 	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/infra/metrics"
 	"willowsuite-vault/models"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
 	"github.com/yeqown/go-qrcode/v2"
 	"github.com/yeqown/go-qrcode/writer/standard"
 )
@@ -35,36 +36,14 @@ type PresignedURLCacheKey struct {
 
 // Generate creates a new QR code, uploads it to S3, and then returns the url to the frontend.
 func (handler Handler) Generate(w http.ResponseWriter, request *http.Request) {
-	//Get parameters
-	byteData, err := io.ReadAll(request.Body)
-	if err != nil {
-		logAndRespond(w, "Error parsing request", err)
-		return
-	}
-
-	var parsedData map[string]string
-	if err = json.Unmarshal(byteData, &parsedData); err != nil {
-		logAndRespond(w, "Error parsing json", err)
+	//Get and validate parameters
+	var body models.GenerateQRRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
-	//Validate parameters
-	category, stringID := parsedData["category"], parsedData["id"]
-	if category == "" {
-		logAndRespond(w, "Missing category", nil)
-		return
-	}
-
-	if stringID == "" {
-		logAndRespond(w, "Missing id", nil)
-		return
-	}
-
-	id, err := strconv.ParseUint(stringID, 10, 64)
-	if err != nil {
-		logAndRespond(w, fmt.Sprintf("ID must be type integer: %v", stringID), nil)
-		return
-	}
+	category, id := body.Category, body.ID.Value
+	stringID := strconv.FormatUint(id, 10)
 
 	claims := request.Context().Value("user_claims").(jwt.MapClaims)
 	userID := claims["username"].(string)
@@ -82,15 +61,17 @@ func (handler Handler) Generate(w http.ResponseWriter, request *http.Request) {
 
 	key, err := json.Marshal(keyStructured)
 	if err != nil {
-		logAndRespond(w, fmt.Sprintf("Error encoding Redis key: %v", err), err)
+		respondError(w, request, apperrors.Internal("Error encoding cache key.", err))
 		return
 	}
 
-	value, redisErr := handler.Repository.Cache.Get(request.Context(), string(key)).Result()
-	if redisErr != nil && !errors.Is(redisErr, redis.Nil) {
-		logAndRespond(w, fmt.Sprintf("Error retriving entites from Redis: %v", redisErr), err)
-		return
+	// A QR code that can't be read from the cache is made again, and one that can't be cached is only logged.
+	value, cacheErr := handler.Repository.Cache.Get(request.Context(), string(key))
+	if cacheErr != nil && !errors.Is(cacheErr, cache.ErrMiss) {
+		logger.Ctx(request.Context()).Warnf("error reading QR code from the cache: %v", cacheErr)
 	}
+	cache.CountLookup(keyStructured.CacheKey.Function, value != "")
+	cached := value != ""
 
 	if value == "" {
 		// Verify entity exists
@@ -98,15 +79,14 @@ func (handler Handler) Generate(w http.ResponseWriter, request *http.Request) {
 			ID: id,
 		}
 
-		validEntity, model := buildEntity(entity, models.Parent{}, category, "")
+		validEntity, model := buildEntity(entity, models.Parent{}, category, nil)
 		if !validEntity {
-			logAndRespond(w, fmt.Sprintf("Invalid category %v.", category), nil)
+			respondError(w, request, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil))
 			return
 		}
 
-		dberr := handler.Repository.GetOne(model, userID)
-		if dberr != nil {
-			logAndRespond(w, fmt.Sprintf("Entity category of %v with id %v not found.", category, stringID), nil)
+		if err = handler.Repository.GetOne(request.Context(), model, userID); err != nil {
+			respondError(w, request, entityError(err, category, id))
 			return
 		}
 
@@ -114,7 +94,7 @@ func (handler Handler) Generate(w http.ResponseWriter, request *http.Request) {
 		bucketName := config.S3BucketName()
 		folderName, err := Encrypt(userID, config.EncryptionSecert())
 		if err != nil {
-			logAndRespond(w, fmt.Sprintf("error encrypting your classified text: %v", err), err)
+			respondError(w, request, apperrors.Internal("Error encrypting folder name.", err))
 			return
 		}
 
@@ -130,7 +110,7 @@ func (handler Handler) Generate(w http.ResponseWriter, request *http.Request) {
 		if err != nil {
 			var notFound *types.NotFound
 			if !errors.As(err, &notFound) {
-				logAndRespond(w, fmt.Sprintf("Could not upload file: %v", err), err)
+				respondError(w, request, apperrors.Upstream("s3", "Could not check for existing QR code.", err))
 				return
 			}
 
@@ -140,26 +120,26 @@ func (handler Handler) Generate(w http.ResponseWriter, request *http.Request) {
 
 			qrc, err := qrcode.New(url)
 			if err != nil {
-				logAndRespond(w, "could not generate QRCode: %v", err)
+				respondError(w, request, apperrors.Internal("Could not generate QR code.", err))
 				return
 			}
 
 			writer, err := standard.New(fileLocation)
 			if err != nil {
-				logAndRespond(w, "standard.New failed: %v", err)
+				respondError(w, request, apperrors.Internal("Could not create QR code writer.", err))
 				return
 			}
 
 			// Save TMP file
 			if err = qrc.Save(writer); err != nil {
-				logAndRespond(w, "could not save image: %v", err)
+				respondError(w, request, apperrors.Internal("Could not save QR code image.", err))
 				return
 			}
 
 			// Upload to S3
 			file, err := os.Open(fileLocation)
 			if err != nil {
-				logAndRespond(w, "Couldn't open file: %v", err)
+				respondError(w, request, apperrors.Internal("Couldn't open QR code image.", err))
 				return
 			}
 
@@ -172,14 +152,14 @@ func (handler Handler) Generate(w http.ResponseWriter, request *http.Request) {
 				Body:   file,
 			})
 			if err != nil {
-				logAndRespond(w, fmt.Sprintf("Couldn't upload file: %v\n", err), err)
+				respondError(w, request, apperrors.Upstream("s3", "Couldn't upload QR code.", err))
 				return
 			}
 
 			err = s3.NewObjectExistsWaiter(handler.S3Client).Wait(
 				request.Context(), &s3.HeadObjectInput{Bucket: aws.String(bucketName), Key: aws.String(objectKey)}, time.Minute)
 			if err != nil {
-				logAndRespond(w, fmt.Sprintf("Failed attempt to wait for object %s to exist.\n", fileName), err)
+				respondError(w, request, apperrors.Upstream("s3", fmt.Sprintf("Failed attempt to wait for object %s to exist.", fileName), err))
 				return
 			}
 		}
@@ -191,14 +171,17 @@ func (handler Handler) Generate(w http.ResponseWriter, request *http.Request) {
 			opts.Expires = time.Duration(cacheTTL)
 		})
 		if err != nil {
-			logAndRespond(w, fmt.Sprintf("Couldn't get a presigned request: %v", err), err)
+			respondError(w, request, apperrors.Upstream("s3", "Couldn't get a presigned request.", err))
 			return
 		}
 
 		value = presigned.URL
-		handler.Repository.Cache.Set(request.Context(), string(key), value, cacheTTL)
+		if cacheErr = handler.Repository.Cache.Set(request.Context(), string(key), value, cacheTTL); cacheErr != nil {
+			logger.Ctx(request.Context()).Warnf("error caching QR code: %v", cacheErr)
+		}
 	}
 
+	metrics.CountQRCode(cached)
 	helpers.SuccessResponse(w, value)
 	return
 }
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/helpers"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GetSessions returns void, but sends the list of devices the user is signed in on back to the client.
+func (handler Handler) GetSessions(w http.ResponseWriter, request *http.Request) {
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	currentSession, _ := claims["origin_jti"].(string)
+	if currentSession == "" {
+		currentSession, _ = claims["jti"].(string)
+	}
+
+	sessions, err := handler.Repository.GetSessions(request.Context(), userID)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSession
+	}
+
+	helpers.SuccessResponse(w, sessions)
+}
+
+// LogOutAllDevices returns void, but signs the user out of every device by revoking all of their tokens.
+func (handler Handler) LogOutAllDevices(w http.ResponseWriter, request *http.Request) {
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	accessToken := bearerToken(request)
+	if accessToken == "" {
+		respondError(w, request, apperrors.BadRequest("Missing access token", nil))
+		return
+	}
+
+	_, err := handler.CognitoClient.GlobalSignOut(request.Context(), &cognitoidentityprovider.GlobalSignOutInput{
+		AccessToken: aws.String(accessToken),
+	})
+	if err != nil {
+		respondError(w, request, cognitoError(err, "Couldn't sign out of all devices"))
+		return
+	}
+
+	if err = handler.Repository.RevokeAllSessions(request.Context(), userID); err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	helpers.SuccessResponse(w, "Signed out of all devices")
+}
@@ -1,14 +1,14 @@
 package controllers
 
 import (
-	"encoding/json"
 	"errors"
-	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"willowsuite-vault/apperrors"
 	"willowsuite-vault/config"
 	"willowsuite-vault/helpers"
-	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
@@ -17,44 +17,12 @@ import (
 
 // SignUp signs up a user with Amazon Cognito.
 func (handler Handler) SignUp(w http.ResponseWriter, request *http.Request) {
-	byteData, err := io.ReadAll(request.Body)
-	if err != nil {
-		logAndRespond(w, "Error parsing request", err)
-		return
-	}
-
-	var parsedData map[string]string
-	if err = json.Unmarshal(byteData, &parsedData); err != nil {
-		logAndRespond(w, "Error parsing json", err)
-		return
-	}
-
-	userEmail, password, firstName, lastName, birthday := parsedData["userEmail"], parsedData["password"], parsedData["firstName"], parsedData["lastName"], parsedData["birthday"]
-	if userEmail == "" {
-		logAndRespond(w, "Missing user name", nil)
-		return
-	}
-
-	if password == "" {
-		logAndRespond(w, "Missing password", nil)
-		return
-	}
-
-	if firstName == "" {
-		logAndRespond(w, "Missing first name", nil)
-		return
-	}
-
-	if lastName == "" {
-		logAndRespond(w, "Missing last name", nil)
-		return
-	}
-
-	if birthday == "" {
-		logAndRespond(w, "Missing birthday", nil)
+	var body models.SignUpRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
+	userEmail, password, firstName, lastName, birthday := body.UserEmail, body.Password, body.FirstName, body.LastName, body.Birthday
 	output, err := handler.CognitoClient.SignUp(request.Context(), &cognitoidentityprovider.SignUpInput{
 		ClientId: aws.String(config.CognitoClientID()),
 		Password: aws.String(password),
@@ -67,16 +35,7 @@ func (handler Handler) SignUp(w http.ResponseWriter, request *http.Request) {
 		SecretHash: aws.String(config.CognitoSecretHash(userEmail)),
 	})
 	if err != nil {
-		var invalidPassword *types.InvalidPasswordException
-		var userExists *types.UsernameExistsException
-		if errors.As(err, &invalidPassword) {
-			logAndRespond(w, *invalidPassword.Message, err)
-		} else if errors.As(err, &userExists) {
-			logAndRespond(w, *userExists.Message, err)
-		} else {
-			logAndRespond(w, "Couldn't sign up user", err)
-		}
-
+		respondError(w, request, cognitoError(err, "Couldn't sign up user"))
 		return
 	}
 
@@ -85,30 +44,12 @@ func (handler Handler) SignUp(w http.ResponseWriter, request *http.Request) {
 
 // ConfirmSignUp confirms a user's email with Amazon Cognito.
 func (handler Handler) ConfirmSignUp(w http.ResponseWriter, request *http.Request) {
-	byteData, err := io.ReadAll(request.Body)
-	if err != nil {
-		logAndRespond(w, "Error parsing request", err)
-		return
-	}
-
-	var parsedData map[string]string
-	if err = json.Unmarshal(byteData, &parsedData); err != nil {
-		logAndRespond(w, "Error parsing json", err)
-		return
-	}
-
-	confirmationCode := parsedData["confirmationCode"]
-	if confirmationCode == "" {
-		logAndRespond(w, "Missing confirmation code", nil)
-		return
-	}
-
-	userEmail := parsedData["userEmail"]
-	if userEmail == "" {
-		logAndRespond(w, "Missing user email", nil)
+	var body models.ConfirmSignUpRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
+	confirmationCode, userEmail := body.ConfirmationCode, body.UserEmail
 	output, err := handler.CognitoClient.ConfirmSignUp(request.Context(), &cognitoidentityprovider.ConfirmSignUpInput{
 		ClientId:         aws.String(config.CognitoClientID()),
 		Username:         aws.String(userEmail),
@@ -118,11 +59,11 @@ func (handler Handler) ConfirmSignUp(w http.ResponseWriter, request *http.Reques
 	if err != nil {
 		var invalidCode *types.CodeMismatchException
 		if errors.As(err, &invalidCode) {
-			logAndRespond(w, "Incorrect confirmation code", nil)
+			respondError(w, request, apperrors.BadRequest("Incorrect confirmation code", err))
 			return
 		}
 
-		logAndRespond(w, "Error confirming user", err)
+		respondError(w, request, cognitoError(err, "Error confirming user"))
 		return
 	}
 
@@ -131,95 +72,203 @@ func (handler Handler) ConfirmSignUp(w http.ResponseWriter, request *http.Reques
 
 // SignIn returns an initial JWT from Amazon Cognito.
 func (handler Handler) SignIn(w http.ResponseWriter, request *http.Request) {
-	byteData, err := io.ReadAll(request.Body)
-	if err != nil {
-		logAndRespond(w, "Error parsing request", err)
+	var body models.SignInRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
-	var parsedData map[string]string
-	if err = json.Unmarshal(byteData, &parsedData); err != nil {
-		logAndRespond(w, "Error parsing json", err)
+	userEmail, password := body.UserEmail, body.Password
+	output, err := handler.CognitoClient.InitiateAuth(request.Context(), &cognitoidentityprovider.InitiateAuthInput{
+		AuthFlow: "USER_PASSWORD_AUTH",
+		ClientId: aws.String(config.CognitoClientID()),
+		AuthParameters: map[string]string{
+			"USERNAME":    userEmail,
+			"PASSWORD":    password,
+			"SECRET_HASH": config.CognitoSecretHash(userEmail),
+		},
+	})
+	if err != nil {
+		respondError(w, request, cognitoError(err, "Couldn't sign in user"))
 		return
 	}
 
-	userEmail, password := parsedData["userEmail"], parsedData["password"]
-	if userEmail == "" {
-		logAndRespond(w, "Missing user email", nil)
+	if output.AuthenticationResult == nil {
+		response := buildChallengeResponse(output.ChallengeName, output.Session, output.ChallengeParameters)
+		helpers.SuccessResponse(w, &response)
 		return
 	}
 
-	if password == "" {
-		logAndRespond(w, "Missing password", nil)
+	response := buildAuthResponse(output.AuthenticationResult)
+	helpers.SuccessResponse(w, &response)
+}
+
+// RespondToChallenge answers an authentication challenge issued by Amazon Cognito during sign in.
+func (handler Handler) RespondToChallenge(w http.ResponseWriter, request *http.Request) {
+	var body models.ChallengeRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
-	output, err := handler.CognitoClient.InitiateAuth(request.Context(), &cognitoidentityprovider.InitiateAuthInput{
-		AuthFlow: "USER_PASSWORD_AUTH",
-		ClientId: aws.String(config.CognitoClientID()),
-		AuthParameters: map[string]string{
-			"USERNAME":    userEmail,
-			"PASSWORD":    password,
-			"SECRET_HASH": config.CognitoSecretHash(userEmail),
-		},
+	userEmail, challengeName, session := body.UserEmail, body.ChallengeName, body.Session
+	challengeResponses := map[string]string{
+		"USERNAME":    userEmail,
+		"SECRET_HASH": config.CognitoSecretHash(userEmail),
+	}
+
+	switch types.ChallengeNameType(challengeName) {
+	case types.ChallengeNameTypeSoftwareTokenMfa:
+		challengeResponses["SOFTWARE_TOKEN_MFA_CODE"] = body.Code
+	case types.ChallengeNameTypeSmsMfa:
+		challengeResponses["SMS_MFA_CODE"] = body.Code
+	case types.ChallengeNameTypeNewPasswordRequired:
+		challengeResponses["NEW_PASSWORD"] = body.NewPassword
+	case types.ChallengeNameTypeMfaSetup:
+		// Cognito expects the session returned by VerifySoftwareToken, no extra responses are needed.
+	}
+
+	output, err := handler.CognitoClient.RespondToAuthChallenge(request.Context(), &cognitoidentityprovider.RespondToAuthChallengeInput{
+		ChallengeName:      types.ChallengeNameType(challengeName),
+		ClientId:           aws.String(config.CognitoClientID()),
+		Session:            aws.String(session),
+		ChallengeResponses: challengeResponses,
 	})
 	if err != nil {
-		var invalidPassword *types.NotAuthorizedException
-		if errors.As(err, &invalidPassword) {
-			logger.Errorf("Unauthorized Access: User entered bad username or password.")
-			helpers.UnaunthorizedRequest(w, *invalidPassword.Message)
-		} else {
-			logAndRespond(w, "Couldn't sign in user", err)
+		var invalidCode *types.CodeMismatchException
+		if errors.As(err, &invalidCode) {
+			respondError(w, request, apperrors.Unauthorized(aws.ToString(invalidCode.Message), err))
+			return
 		}
 
+		respondError(w, request, cognitoError(err, "Couldn't respond to challenge"))
 		return
 	}
 
-	response := map[string]string{
-		"AccessToken":  *output.AuthenticationResult.AccessToken,
-		"IdToken":      *output.AuthenticationResult.IdToken,
-		"RefreshToken": *output.AuthenticationResult.RefreshToken,
-		"ExpiresIn":    strconv.Itoa(int(output.AuthenticationResult.ExpiresIn)),
+	if output.AuthenticationResult == nil {
+		response := buildChallengeResponse(output.ChallengeName, output.Session, output.ChallengeParameters)
+		helpers.SuccessResponse(w, &response)
+		return
 	}
 
+	response := buildAuthResponse(output.AuthenticationResult)
 	helpers.SuccessResponse(w, &response)
 }
 
-// Refresh returns a refreshed JWT from Amazon Cognito.
-func (handler Handler) Refresh(w http.ResponseWriter, request *http.Request) {
-	byteData, err := io.ReadAll(request.Body)
+// AssociateSoftwareToken starts TOTP MFA enrolment and returns the secret to load into an authenticator app.
+// Signed in users authenticate with their access token, users answering an MFA_SETUP challenge send their session.
+func (handler Handler) AssociateSoftwareToken(w http.ResponseWriter, request *http.Request) {
+	var body models.AssociateSoftwareTokenRequest
+	if !decodeRequest(w, request, &body) {
+		return
+	}
+
+	accessToken, session := bearerToken(request), body.Session
+	if accessToken == "" && session == "" {
+		respondError(w, request, apperrors.BadRequest("Missing access token or session", nil))
+		return
+	}
+
+	input := &cognitoidentityprovider.AssociateSoftwareTokenInput{}
+	if accessToken != "" {
+		input.AccessToken = aws.String(accessToken)
+	} else {
+		input.Session = aws.String(session)
+	}
+
+	output, err := handler.CognitoClient.AssociateSoftwareToken(request.Context(), input)
 	if err != nil {
-		logAndRespond(w, "Error parsing request", err)
+		respondError(w, request, cognitoError(err, "Couldn't associate software token"))
 		return
 	}
 
-	var parsedData map[string]string
-	if err = json.Unmarshal(byteData, &parsedData); err != nil {
-		logAndRespond(w, "Error parsing json", err)
+	response := map[string]string{
+		"SecretCode": aws.ToString(output.SecretCode),
+		"Session":    aws.ToString(output.Session),
+	}
+
+	helpers.SuccessResponse(w, &response)
+}
+
+// VerifySoftwareToken completes TOTP MFA enrolment and, for signed in users, makes TOTP their preferred MFA method.
+func (handler Handler) VerifySoftwareToken(w http.ResponseWriter, request *http.Request) {
+	var body models.VerifySoftwareTokenRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
-	refreshToken := parsedData["refreshToken"]
-	if refreshToken == "" {
-		logAndRespond(w, "Missing refresh token", nil)
+	code := body.Code
+	accessToken, session := bearerToken(request), body.Session
+	if accessToken == "" && session == "" {
+		respondError(w, request, apperrors.BadRequest("Missing access token or session", nil))
 		return
 	}
 
-	idToken := parsedData["idToken"]
-	if idToken == "" {
-		logAndRespond(w, "Missing id token", nil)
+	input := &cognitoidentityprovider.VerifySoftwareTokenInput{
+		UserCode:           aws.String(code),
+		FriendlyDeviceName: aws.String(body.DeviceName),
+	}
+	if accessToken != "" {
+		input.AccessToken = aws.String(accessToken)
+	} else {
+		input.Session = aws.String(session)
+	}
+
+	output, err := handler.CognitoClient.VerifySoftwareToken(request.Context(), input)
+	if err != nil {
+		var invalidCode *types.CodeMismatchException
+		var invalidToken *types.EnableSoftwareTokenMFAException
+		if errors.As(err, &invalidCode) || errors.As(err, &invalidToken) {
+			respondError(w, request, apperrors.BadRequest("Incorrect code", err))
+			return
+		}
+
+		respondError(w, request, cognitoError(err, "Couldn't verify software token"))
+		return
+	}
+
+	if output.Status != types.VerifySoftwareTokenResponseTypeSuccess {
+		respondError(w, request, apperrors.BadRequest("Software token verification failed", nil))
 		return
 	}
 
+	if accessToken != "" {
+		_, err = handler.CognitoClient.SetUserMFAPreference(request.Context(), &cognitoidentityprovider.SetUserMFAPreferenceInput{
+			AccessToken: aws.String(accessToken),
+			SoftwareTokenMfaSettings: &types.SoftwareTokenMfaSettingsType{
+				Enabled:      true,
+				PreferredMfa: true,
+			},
+		})
+		if err != nil {
+			respondError(w, request, cognitoError(err, "Couldn't enable software token MFA"))
+			return
+		}
+	}
+
+	response := map[string]string{
+		"Status":  string(output.Status),
+		"Session": aws.ToString(output.Session),
+	}
+
+	helpers.SuccessResponse(w, &response)
+}
+
+// Refresh returns a refreshed JWT from Amazon Cognito.
+func (handler Handler) Refresh(w http.ResponseWriter, request *http.Request) {
+	var body models.RefreshRequest
+	if !decodeRequest(w, request, &body) {
+		return
+	}
+
+	refreshToken, idToken := body.RefreshToken, body.IDToken
 	token, err := handler.TokenHelper.VerifyToken(idToken, false)
 	if err != nil {
-		helpers.BadRequest(w, err)
+		respondError(w, request, tokenError(err, "Invalid id token"))
 		return
 	}
 
 	claims, err := handler.TokenHelper.ExtractClaims(token)
 	if err != nil {
-		helpers.BadRequest(w, err)
+		respondError(w, request, apperrors.Unauthorized("Invalid id token", err))
 		return
 	}
 
@@ -232,49 +281,133 @@ func (handler Handler) Refresh(w http.ResponseWriter, request *http.Request) {
 		},
 	})
 	if err != nil {
-		logAndRespond(w, "Couldn't refresh user", err)
+		respondError(w, request, cognitoError(err, "Couldn't refresh user"))
 		return
 	}
 
-	response := map[string]string{
-		"AccessToken": *output.AuthenticationResult.AccessToken,
-		"IdToken":     *output.AuthenticationResult.IdToken,
-		"ExpiresIn":   strconv.Itoa(int(output.AuthenticationResult.ExpiresIn)),
-	}
+	response := buildAuthResponse(output.AuthenticationResult)
 
 	helpers.SuccessResponse(w, &response)
 }
 
 // LogOut revokes all access tokens granted by Cognito.
 func (handler Handler) LogOut(w http.ResponseWriter, request *http.Request) {
-	byteData, err := io.ReadAll(request.Body)
-	if err != nil {
-		logAndRespond(w, "Error parsing request", err)
+	var body models.LogOutRequest
+	if !decodeRequest(w, request, &body) {
 		return
 	}
 
-	var parsedData map[string]string
-	if err = json.Unmarshal(byteData, &parsedData); err != nil {
-		logAndRespond(w, "Error parsing json", err)
-		return
-	}
-
-	refreshToken := parsedData["refreshToken"]
-	if refreshToken == "" {
-		logAndRespond(w, "Missing refresh token", nil)
-		return
-	}
-
-	_, err = handler.CognitoClient.RevokeToken(request.Context(), &cognitoidentityprovider.RevokeTokenInput{
+	refreshToken := body.RefreshToken
+	_, err := handler.CognitoClient.RevokeToken(request.Context(), &cognitoidentityprovider.RevokeTokenInput{
 		ClientId:     aws.String(config.CognitoClientID()),
 		ClientSecret: aws.String(config.CognitoClientSecret()),
 		Token:        aws.String(refreshToken),
 	})
 	if err != nil {
-
-		logAndRespond(w, "Couldn't sign in user", err)
+		respondError(w, request, cognitoError(err, "Couldn't sign out user"))
 		return
 	}
 
+	// Access tokens stay valid until they expire, so denylist the session they belong to.
+	if accessToken := bearerToken(request); accessToken != "" {
+		token, err := handler.TokenHelper.VerifyToken(accessToken, false)
+		if err != nil {
+			respondError(w, request, tokenError(err, "Invalid access token"))
+			return
+		}
+
+		claims, err := handler.TokenHelper.ExtractClaims(token)
+		if err != nil {
+			respondError(w, request, apperrors.Unauthorized("Invalid access token", err))
+			return
+		}
+
+		userID, _ := claims["username"].(string)
+		sessionID, _ := claims["origin_jti"].(string)
+		if sessionID == "" {
+			sessionID, _ = claims["jti"].(string)
+		}
+
+		if err = handler.Repository.RevokeSession(request.Context(), userID, sessionID); err != nil {
+			respondError(w, request, err)
+			return
+		}
+	}
+
 	helpers.SuccessResponse(w, nil)
 }
+
+// cognitoError converts an error returned by Amazon Cognito into an application error.
+func cognitoError(err error, message string) error {
+	var notAuthorized *types.NotAuthorizedException
+	var userNotFound *types.UserNotFoundException
+	var userNotConfirmed *types.UserNotConfirmedException
+	var invalidPassword *types.InvalidPasswordException
+	var invalidParameter *types.InvalidParameterException
+	var userExists *types.UsernameExistsException
+	var expiredCode *types.ExpiredCodeException
+
+	switch {
+	case errors.As(err, &notAuthorized):
+		return apperrors.Unauthorized(aws.ToString(notAuthorized.Message), err)
+	case errors.As(err, &userNotFound):
+		return apperrors.Unauthorized(aws.ToString(userNotFound.Message), err)
+	case errors.As(err, &userNotConfirmed):
+		return apperrors.Unauthorized(aws.ToString(userNotConfirmed.Message), err)
+	case errors.As(err, &invalidPassword):
+		return apperrors.BadRequest(aws.ToString(invalidPassword.Message), err)
+	case errors.As(err, &invalidParameter):
+		return apperrors.BadRequest(aws.ToString(invalidParameter.Message), err)
+	case errors.As(err, &expiredCode):
+		return apperrors.BadRequest(aws.ToString(expiredCode.Message), err)
+	case errors.As(err, &userExists):
+		return apperrors.Conflict(aws.ToString(userExists.Message))
+	default:
+		return apperrors.Upstream("cognito", message, err)
+	}
+}
+
+// tokenError converts an error from the token helper into an application error. Failing to fetch the signing
+// keys is Cognito's problem, anything else means the token itself is bad.
+func tokenError(err error, message string) error {
+	if strings.Contains(err.Error(), "Failed to get JWKS") {
+		return apperrors.Upstream("cognito", "Failed to get JWKS", err)
+	}
+
+	return apperrors.Unauthorized(message, err)
+}
+
+// buildAuthResponse flattens the tokens Cognito issues into the response sent back to the client.
+func buildAuthResponse(result *types.AuthenticationResultType) map[string]string {
+	response := map[string]string{
+		"AccessToken": aws.ToString(result.AccessToken),
+		"IdToken":     aws.ToString(result.IdToken),
+		"ExpiresIn":   strconv.Itoa(int(result.ExpiresIn)),
+	}
+
+	if result.RefreshToken != nil {
+		response["RefreshToken"] = *result.RefreshToken
+	}
+
+	return response
+}
+
+// buildChallengeResponse describes the next challenge the client must answer through RespondToChallenge.
+func buildChallengeResponse(challengeName types.ChallengeNameType, session *string, parameters map[string]string) map[string]interface{} {
+	return map[string]interface{}{
+		"ChallengeName":       string(challengeName),
+		"Session":             aws.ToString(session),
+		"ChallengeParameters": parameters,
+	}
+}
+
+// bearerToken returns the access token from the Authorization header, or an empty string if there isn't one.
+func bearerToken(request *http.Request) string {
+	authHeader := request.Header.Get("Authorization")
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		return ""
+	}
+
+	return tokenString
+}
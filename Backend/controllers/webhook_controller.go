@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// CreateWebhook returns void, but subscribes a URL to the user's entity changes and sends the webhook back with
+// its secret. The secret isn't shown again.
+func (handler Handler) CreateWebhook(w http.ResponseWriter, request *http.Request) {
+	var body models.CreateWebhookRequest
+	if !decodeRequest(w, request, &body) {
+		return
+	}
+
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	webhook := models.Webhook{
+		UserID:     userID,
+		URL:        body.URL,
+		Events:     body.Events,
+		Categories: body.Categories,
+	}
+	if webhook.Events == nil {
+		webhook.Events = models.StringList{}
+	}
+	if webhook.Categories == nil {
+		webhook.Categories = models.StringList{}
+	}
+
+	if err := handler.Repository.CreateWebhook(request.Context(), &webhook); err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	helpers.SuccessResponse(w, webhook)
+}
+
+// GetWebhooks returns void, but sends the user's webhooks back to the client.
+func (handler Handler) GetWebhooks(w http.ResponseWriter, request *http.Request) {
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	webhooks, err := handler.Repository.GetWebhooks(request.Context(), userID)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	helpers.SuccessResponse(w, webhooks)
+}
+
+// DeleteWebhook returns void, but unsubscribes a webhook and drops its queued deliveries.
+func (handler Handler) DeleteWebhook(w http.ResponseWriter, request *http.Request) {
+	id, err := uintParam(request, "id")
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	if err = handler.Repository.DeleteWebhook(request.Context(), userID, id); err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	helpers.SuccessResponse(w, "Successfully Deleted!")
+}
+
+// GetWebhookDeliveries returns void, but sends the latest deliveries of a webhook back to the client.
+func (handler Handler) GetWebhookDeliveries(w http.ResponseWriter, request *http.Request) {
+	id, err := uintParam(request, "id")
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	deliveries, err := handler.Repository.GetWebhookDeliveries(request.Context(), userID, id)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	helpers.SuccessResponse(w, deliveries)
+}
+
+// RedeliverWebhook returns void, but queues the event of an earlier delivery to be sent again and sends the new
+// delivery back to the client.
+func (handler Handler) RedeliverWebhook(w http.ResponseWriter, request *http.Request) {
+	id, err := uintParam(request, "id")
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	deliveryID, err := uintParam(request, "deliveryID")
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	claims := request.Context().Value("user_claims").(jwt.MapClaims)
+	userID := claims["username"].(string)
+
+	delivery, err := handler.Repository.RedeliverWebhook(request.Context(), userID, id, deliveryID)
+	if err != nil {
+		respondError(w, request, err)
+		return
+	}
+
+	helpers.SuccessResponse(w, delivery)
+}
+
+// uintParam reads a numeric URL parameter.
+func uintParam(request *http.Request, name string) (uint64, error) {
+	param := chi.URLParam(request, name)
+
+	value, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return 0, apperrors.BadRequest(fmt.Sprintf("ID must be type integer: %v", param), err)
+	}
+
+	return value, nil
+}
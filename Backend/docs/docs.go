@@ -0,0 +1,22 @@
+// Package docs embeds the OpenAPI document for the WillowSuite Vault API and the page that renders it.
+package docs
+
+import "embed"
+
+//go:generate sh swagger-ui.sh
+
+// Spec is the OpenAPI 3 document describing every route registered in routers.RegisterRoutes.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// UI is the HTML page that renders Spec with Swagger UI.
+//
+//go:embed index.html
+var UI []byte
+
+// Assets holds the Swagger UI files UI loads, vendored under swagger-ui by go generate so the page needs nothing
+// from another origin. The version they were taken from is in swagger-ui/VERSION.
+//
+//go:embed swagger-ui
+var Assets embed.FS
@@ -0,0 +1,18 @@
+#!/bin/sh
+# Vendors the Swagger UI files index.html loads into swagger-ui, taken from the swagger-ui-dist version pinned in
+# swagger-ui/VERSION. Run it through go generate after changing that version and commit what it writes.
+set -eu
+
+cd "$(dirname "$0")"
+version=$(cat swagger-ui/VERSION)
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+
+curl -fsSL "https://registry.npmjs.org/swagger-ui-dist/-/swagger-ui-dist-$version.tgz" -o "$work/swagger-ui-dist.tgz"
+tar -xzf "$work/swagger-ui-dist.tgz" -C "$work"
+
+for file in swagger-ui.css swagger-ui-bundle.js LICENSE; do
+  cp "$work/package/$file" "swagger-ui/$file"
+done
+
+echo "Vendored swagger-ui-dist $version."
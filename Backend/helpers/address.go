@@ -0,0 +1,55 @@
+package helpers
+
+import "net/netip"
+
+// nonPublicPrefixes are the ranges PublicAddress refuses on top of what netip can tell about an address.
+var nonPublicPrefixes = []netip.Prefix{
+	// "This network", of which only 0.0.0.0 itself is unspecified
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// Carrier-grade NAT, where some clouds put their metadata service, such as 100.100.100.200
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// Benchmarking
+	netip.MustParsePrefix("198.18.0.0/15"),
+	// Reserved, including the broadcast address 255.255.255.255
+	netip.MustParsePrefix("240.0.0.0/4"),
+	// Local-use NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// Prefixes of IPv6 addresses that carry an IPv4 address, which is checked in their place.
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFour   = netip.MustParsePrefix("2002::/16")
+)
+
+// PublicAddress reports whether addr is on the internet, rather than this host, a private network or a link-local
+// one such as the cloud metadata service at 169.254.169.254. IPv6 addresses that carry an IPv4 address, through
+// NAT64 or 6to4, are only public when that address is.
+func PublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsValid() ||
+		addr.IsUnspecified() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	bytes := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr):
+		return PublicAddress(netip.AddrFrom4([4]byte(bytes[12:16])))
+	case sixToFour.Contains(addr):
+		return PublicAddress(netip.AddrFrom4([4]byte(bytes[2:6])))
+	}
+
+	return true
+}
@@ -0,0 +1,76 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"willowsuite-vault/apperrors"
+)
+
+// ETag returns the entity tag for a version of an entity.
+func ETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// IfMatchVersion returns the version of the entity a write was based on, read from its If-Match header. The
+// header is required so that concurrent edits can't silently overwrite each other. It returns 0 for If-Match: *,
+// which matches whatever version is current.
+func IfMatchVersion(r *http.Request) (uint64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, apperrors.PreconditionRequired("An If-Match header with the ETag of the entity is required.")
+	}
+
+	if header == "*" {
+		return 0, nil
+	}
+
+	// Versions are compared strongly, so weak tags and lists of tags never match.
+	version, err := strconv.ParseUint(strings.Trim(header, `"`), 10, 64)
+	if err != nil || header != ETag(version) {
+		return 0, apperrors.PreconditionFailed("The entity has been changed since it was read.")
+	}
+
+	return version, nil
+}
+
+// ConditionalResponse sends data like SuccessResponse with an ETag of the body, or 304 Not Modified with no body
+// when the client already has it according to If-None-Match.
+func ConditionalResponse(w http.ResponseWriter, r *http.Request, data interface{}) interface{} {
+	body, err := json.Marshal(map[string]interface{}{
+		"message": "success",
+		"data":    &data,
+	})
+	if err != nil {
+		return ErrorResponse(w, r, apperrors.Internal("Error encoding response.", err))
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+
+	if noneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// noneMatch reports whether an If-None-Match header lists etag, comparing weakly as RFC 9110 asks.
+func noneMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
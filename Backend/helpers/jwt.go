@@ -2,7 +2,6 @@ package helpers
 
 import (
 	"errors"
-	"fmt"
 	"willowsuite-vault/config"
 
 	"github.com/MicahParks/keyfunc/v2"
@@ -17,10 +16,8 @@ type TokenHelper interface {
 type DefaultTokenHelper struct{}
 
 func (h *DefaultTokenHelper) VerifyToken(tokenString string, performValidation bool) (*jwt.Token, error) {
-	region := config.AWSRegion()
-	userPool := config.CognitoUserPoolID()
-	jwksURL := fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s/.well-known/jwks.json", region, userPool)
-	issuer := fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPool)
+	jwksURL := config.CognitoJWKSURL()
+	issuer := config.CognitoIssuer()
 
 	// Get the JWKS from Cognito
 	jwks, err := keyfunc.Get(jwksURL, keyfunc.Options{})
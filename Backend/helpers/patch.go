@@ -0,0 +1,181 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+	"willowsuite-vault/apperrors"
+)
+
+// Media types a PATCH request body can be sent as.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// patchOperation is one operation of a JSON Patch document.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyPatch applies a PATCH request body to the members of a JSON object, as a JSON Patch (RFC 6902) or a JSON
+// Merge Patch (RFC 7396) depending on its content type. Plain JSON is taken to be a merge patch. The document is
+// only changed when every operation of the patch can be applied.
+func ApplyPatch(contentType string, document map[string]json.RawMessage, patch []byte) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case MergePatchType, "application/json":
+		return mergePatch(document, patch)
+	case JSONPatchType:
+		return jsonPatch(document, patch)
+	}
+
+	return apperrors.UnsupportedMedia(fmt.Sprintf("Patches must be sent as %s or %s.", MergePatchType, JSONPatchType))
+}
+
+// mergePatch sets every member of the patch on the document, removing the members set to null.
+func mergePatch(document map[string]json.RawMessage, patch []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &members); err != nil || members == nil {
+		return apperrors.BadRequest("A merge patch must be a JSON object.", err)
+	}
+
+	for name, value := range members {
+		if isNull(value) {
+			delete(document, name)
+			continue
+		}
+
+		current, ok := document[name]
+		if !ok || !isObject(current) || !isObject(value) {
+			document[name] = value
+			continue
+		}
+
+		// Objects are merged member by member rather than replaced.
+		var nested map[string]json.RawMessage
+		if err := json.Unmarshal(current, &nested); err != nil {
+			return apperrors.BadRequest(fmt.Sprintf("Error merging %s.", name), err)
+		}
+
+		if err := mergePatch(nested, value); err != nil {
+			return err
+		}
+
+		merged, err := json.Marshal(nested)
+		if err != nil {
+			return apperrors.Internal(fmt.Sprintf("Error merging %s.", name), err)
+		}
+		document[name] = merged
+	}
+
+	return nil
+}
+
+// jsonPatch runs the operations of the patch in order against a copy of the document, and copies the result back
+// when they have all succeeded. Only the top level members of the document can be addressed.
+func jsonPatch(document map[string]json.RawMessage, patch []byte) error {
+	var operations []patchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil || operations == nil {
+		return apperrors.BadRequest("A JSON patch must be an array of operations.", err)
+	}
+
+	result := make(map[string]json.RawMessage, len(document))
+	for name, value := range document {
+		result[name] = value
+	}
+
+	for i, operation := range operations {
+		name, err := patchMember(operation.Path)
+		if err != nil {
+			return apperrors.BadRequest(fmt.Sprintf("Operation %d: %s.", i, err), nil)
+		}
+
+		current, exists := result[name]
+
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return apperrors.BadRequest(fmt.Sprintf("Operation %d: %s needs a value.", i, operation.Op), nil)
+			}
+		case "move", "copy":
+			from, err := patchMember(operation.From)
+			if err != nil {
+				return apperrors.BadRequest(fmt.Sprintf("Operation %d: %s.", i, err), nil)
+			}
+
+			value, ok := result[from]
+			if !ok {
+				return apperrors.Conflict(fmt.Sprintf("Operation %d: %s does not exist.", i, operation.From))
+			}
+
+			if operation.Op == "move" {
+				delete(result, from)
+			}
+			result[name] = value
+			continue
+		case "remove":
+		default:
+			return apperrors.BadRequest(fmt.Sprintf("Operation %d: unknown op %q.", i, operation.Op), nil)
+		}
+
+		if !exists && operation.Op != "add" {
+			return apperrors.Conflict(fmt.Sprintf("Operation %d: %s does not exist.", i, operation.Path))
+		}
+
+		switch operation.Op {
+		case "remove":
+			delete(result, name)
+		case "test":
+			if !jsonEqual(current, operation.Value) {
+				return apperrors.Conflict(fmt.Sprintf("Operation %d: test of %s failed.", i, operation.Path))
+			}
+		default:
+			result[name] = operation.Value
+		}
+	}
+
+	for name := range document {
+		delete(document, name)
+	}
+	for name, value := range result {
+		document[name] = value
+	}
+
+	return nil
+}
+
+// patchMember returns the name of the top level member a JSON Pointer refers to.
+func patchMember(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Contains(pointer[1:], "/") {
+		return "", fmt.Errorf("path %q must point at a member of the entity", pointer)
+	}
+
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), nil
+}
+
+func isNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
+
+func isObject(value json.RawMessage) bool {
+	value = bytes.TrimSpace(value)
+	return len(value) > 0 && value[0] == '{'
+}
+
+// jsonEqual reports whether two JSON values are the same, ignoring formatting and the order of members.
+func jsonEqual(a json.RawMessage, b json.RawMessage) bool {
+	var left, right interface{}
+	if json.Unmarshal(a, &left) != nil || json.Unmarshal(b, &right) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(left, right)
+}
@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP returns the address of the client. The forwarded headers are only believed when the request comes from
+// one of proxies, the parsed TRUSTED_PROXIES, since anyone else can send whatever they like in them. Even then every proxy appends to
+// X-Forwarded-For, so the client is the last address in it that isn't a proxy: anything before that was sent by
+// the client itself.
+func ClientIP(r *http.Request, proxies []netip.Prefix) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+
+	if !isTrustedProxy(remote, proxies) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" || isTrustedProxy(hop, proxies) {
+			continue
+		}
+
+		if _, err := netip.ParseAddr(hop); err != nil {
+			return remote
+		}
+
+		return hop
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if _, err := netip.ParseAddr(realIP); err == nil {
+			return realIP
+		}
+	}
+
+	return remote
+}
+
+// isTrustedProxy reports whether ip is one of proxies.
+func isTrustedProxy(ip string, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
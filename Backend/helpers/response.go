@@ -3,8 +3,40 @@ package helpers
 import (
 	"encoding/json"
 	"net/http"
+	"willowsuite-vault/apperrors"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
+// legacyMessages keeps the message field our clients already read from error responses.
+var legacyMessages = map[apperrors.Kind]string{
+	apperrors.KindBadRequest:           "data validation failed",
+	apperrors.KindValidation:           "data validation failed",
+	apperrors.KindNotFound:             "not found",
+	apperrors.KindConflict:             "conflict",
+	apperrors.KindPreconditionFailed:   "precondition failed",
+	apperrors.KindPreconditionRequired: "precondition required",
+	apperrors.KindUnsupportedMedia:     "unsupported media type",
+	apperrors.KindUnauthorized:         "unauthorized",
+	apperrors.KindTooManyRequests:      "too many requests",
+	apperrors.KindUpstream:             "upstream service error",
+	apperrors.KindInternal:             "internal server error",
+}
+
+// Problem is an RFC 7807 problem details body. Message and Data mirror our success envelope so existing clients
+// can keep reading errors the way they always have.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail"`
+	Instance  string      `json:"instance"`
+	RequestID string      `json:"requestId,omitempty"`
+	Errors    interface{} `json:"errors,omitempty"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+}
+
 func SuccessResponse(w http.ResponseWriter, data interface{}) interface{} {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -14,29 +46,35 @@ func SuccessResponse(w http.ResponseWriter, data interface{}) interface{} {
 	})
 }
 
-func UnaunthorizedRequest(w http.ResponseWriter, data interface{}) interface{} {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-	return json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "unauthorized",
-		"data":    &data,
-	})
-}
+// ErrorResponse maps any error to its HTTP status and sends it as application/problem+json.
+func ErrorResponse(w http.ResponseWriter, request *http.Request, err error) interface{} {
+	problem := NewProblem(request, err)
 
-func BadRequest(w http.ResponseWriter, data interface{}) interface{} {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
-	return json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "data validation failed",
-		"data":    &data,
-	})
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	return json.NewEncoder(w).Encode(problem)
 }
 
-func InternalServerError(w http.ResponseWriter, data interface{}) interface{} {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-	return json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "internal server error",
-		"data":    &data,
-	})
+// NewProblem describes any error as the problem ErrorResponse would send for it.
+func NewProblem(request *http.Request, err error) Problem {
+	appErr := apperrors.As(err)
+	status := appErr.Status()
+
+	problem := Problem{
+		Type:      "/problems/" + string(appErr.Kind),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    appErr.Message,
+		Instance:  request.URL.Path,
+		RequestID: middleware.GetReqID(request.Context()),
+		Errors:    appErr.Details,
+		Message:   legacyMessages[appErr.Kind],
+		Data:      appErr.Message,
+	}
+
+	if appErr.Details != nil {
+		problem.Data = appErr.Details
+	}
+
+	return problem
 }
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"willowsuite-vault/infra/logger"
+)
+
+const (
+	// breakerThreshold is how many calls in a row have to fail before the breaker opens.
+	breakerThreshold = 5
+	// breakerCooldown is how long the breaker stays open before it lets a call through to try the cache again.
+	breakerCooldown = 30 * time.Second
+	// breakerMissedIncrs caps how many failed counter increments are kept to be made up once the cache is back.
+	breakerMissedIncrs = 10000
+)
+
+// ErrOpen is returned instead of calling the cache while the breaker is open.
+var ErrOpen = errors.New("cache: circuit open")
+
+// Breaker stops calling a cache that keeps failing, so an outage costs each request one quick error rather than
+// a timeout. Once it has been open for Cooldown it lets one call through, and closes again if that call works.
+//
+// An increment that fails would leave whatever was cached under the old count looking current once the cache is
+// back, so the breaker keeps the keys of failed increments and makes them up after the next call that works.
+type Breaker struct {
+	Cache     Cache
+	Threshold int
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trying    bool
+	missed    map[string]struct{}
+}
+
+// NewBreaker returns a breaker around c that opens after five failures in a row and tries again after 30 seconds.
+func NewBreaker(c Cache) *Breaker {
+	return &Breaker{Cache: c, Threshold: breakerThreshold, Cooldown: breakerCooldown, missed: map[string]struct{}{}}
+}
+
+// Get returns the value cached under key, ErrMiss, or ErrOpen while the breaker is open.
+func (b *Breaker) Get(ctx context.Context, key string) (string, error) {
+	if !b.allow() {
+		return "", ErrOpen
+	}
+
+	value, err := b.Cache.Get(ctx, key)
+	b.done(ctx, err)
+	return value, err
+}
+
+// Set caches value under key for ttl, or returns ErrOpen while the breaker is open.
+func (b *Breaker) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if !b.allow() {
+		return ErrOpen
+	}
+
+	err := b.Cache.Set(ctx, key, value, ttl)
+	b.done(ctx, err)
+	return err
+}
+
+// Incr adds one to the number cached under key. When it can't, the increment is made up once the cache is back.
+func (b *Breaker) Incr(ctx context.Context, key string) (int64, error) {
+	if !b.allow() {
+		b.miss(key)
+		return 0, ErrOpen
+	}
+
+	count, err := b.Cache.Incr(ctx, key)
+	if err != nil {
+		b.miss(key)
+	}
+
+	b.done(ctx, err)
+	return count, err
+}
+
+// Do runs call against the cache behind the breaker, for reads the Cache interface doesn't cover. It returns
+// ErrOpen without running call while the breaker is open.
+func (b *Breaker) Do(ctx context.Context, call func(ctx context.Context) error) error {
+	if !b.allow() {
+		return ErrOpen
+	}
+
+	err := call(ctx)
+	b.done(ctx, err)
+	return err
+}
+
+// Open reports whether the breaker is open, including while it lets a call through to try the cache again.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.failures >= b.Threshold
+}
+
+// allow reports whether a call may go through to the cache. While open, one call is let through after Cooldown.
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.Threshold {
+		return true
+	}
+
+	if b.trying || time.Now().Before(b.openUntil) {
+		return false
+	}
+
+	b.trying = true
+	return true
+}
+
+// done records how a call that was let through went.
+func (b *Breaker) done(ctx context.Context, err error) {
+	b.mu.Lock()
+
+	b.trying = false
+	switch {
+	case err == nil || errors.Is(err, ErrMiss):
+		if b.failures >= b.Threshold {
+			logger.Infof("cache is back, closing the circuit breaker")
+		}
+		b.failures = 0
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the cache.
+	default:
+		b.failures++
+		if b.failures == b.Threshold {
+			logger.Warnf("cache failed %d times in a row, reading from the database for %s: %v", b.failures, b.Cooldown, err)
+		}
+		if b.failures >= b.Threshold {
+			b.openUntil = time.Now().Add(b.Cooldown)
+		}
+	}
+
+	var missed []string
+	if b.failures == 0 && len(b.missed) > 0 {
+		for key := range b.missed {
+			missed = append(missed, key)
+		}
+		b.missed = map[string]struct{}{}
+	}
+
+	b.mu.Unlock()
+
+	for _, key := range missed {
+		if _, err := b.Cache.Incr(context.WithoutCancel(ctx), key); err != nil {
+			b.miss(key)
+		}
+	}
+}
+
+// miss keeps the key of an increment that didn't happen.
+func (b *Breaker) miss(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.missed == nil {
+		b.missed = map[string]struct{}{}
+	}
+
+	if len(b.missed) < breakerMissedIncrs {
+		b.missed[key] = struct{}{}
+	}
+}
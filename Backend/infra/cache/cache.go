@@ -2,8 +2,11 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
-	"willowsuite-vault/infra/logger"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -11,22 +14,37 @@ import (
 var (
 	// Client is a singleton redis client connection
 	Client *redis.Client
-	once   sync.Once
-	err    error
+	// Default is the cache the API keeps its reads in, set up by CacheInit.
+	Default Cache
+	once    sync.Once
+	err     error
 )
 
-// ClientConnection create redis connection
+// ErrMiss is returned by Get when nothing is cached under the key.
+var ErrMiss = errors.New("cache: miss")
+
+// Cache keeps values that can always be read again from the database, so losing them only costs time. Sessions,
+// idempotency keys and events aren't cached values and use the Redis client directly.
+type Cache interface {
+	// Get returns the value cached under key, or ErrMiss.
+	Get(ctx context.Context, key string) (string, error)
+	// Set caches value under key for ttl.
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	// Incr adds one to the number cached under key, starting from 0, and returns it. Counters don't expire.
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// ClientConnection create redis connection. The client connects on first use, so Redis doesn't have to be up yet.
 func ClientConnection(redisConnectionString string) error {
-	var client = Client
-	var err error
 	once.Do(func() {
-		opt, err := redis.ParseURL(redisConnectionString)
+		var opt *redis.Options
+		opt, err = redis.ParseURL(redisConnectionString)
 		if err != nil {
-			logger.Fatalf("error connecting to redis: %v", err)
+			err = fmt.Errorf("parsing redis url: %w", err)
+			return
 		}
 
-		client = redis.NewClient(opt)
-		Client = client
+		Client = redis.NewClient(opt)
 	})
 
 	return err
@@ -36,3 +54,28 @@ func ClientConnection(redisConnectionString string) error {
 func GetClient() *redis.Client {
 	return Client
 }
+
+// CacheInit sets up the Default cache for a driver: "redis" keeps it in Redis behind a circuit breaker, "memory"
+// in an in-process LRU of size entries and "none" turns caching off. Redis has to be connected first.
+func CacheInit(driver string, size int) error {
+	switch driver {
+	case "redis":
+		if Client == nil {
+			return errors.New("redis is not connected")
+		}
+		Default = NewBreaker(NewRedisCache(Client))
+	case "memory":
+		Default = NewLRUCache(size)
+	case "none":
+		Default = NoopCache{}
+	default:
+		return fmt.Errorf("unknown cache driver %q", driver)
+	}
+
+	return nil
+}
+
+// GetCache returns the Default cache.
+func GetCache() Cache {
+	return Default
+}
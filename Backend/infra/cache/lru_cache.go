@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LRUCache keeps cached values in process memory and drops the least recently used once it holds Size entries.
+// Each instance of the API has its own, so one instance can't clear what another cached: it only suits a single
+// instance, development and tests.
+type LRUCache struct {
+	Size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// NewLRUCache returns an empty in-process cache of size entries.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{Size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Get returns the value cached under key, or ErrMiss when there is none or it has expired.
+func (c *LRUCache) Get(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.lookup(key)
+	if !ok {
+		return "", ErrMiss
+	}
+
+	return entry.value, nil
+}
+
+// Set caches value under key for ttl.
+func (c *LRUCache) Set(_ context.Context, key string, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(key, value, time.Now().Add(ttl))
+	return nil
+}
+
+// Incr adds one to the number cached under key and returns it. A value that isn't a number starts again from 0,
+// where Redis would refuse it.
+func (c *LRUCache) Incr(_ context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var count int64
+	if entry, ok := c.lookup(key); ok {
+		count, _ = strconv.ParseInt(entry.value, 10, 64)
+	}
+
+	count++
+	c.store(key, strconv.FormatInt(count, 10), time.Time{})
+	return count, nil
+}
+
+// Len returns how many entries are cached, including any that expired but haven't been dropped yet.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// lookup finds the live entry for key and marks it as just used. Callers hold mu.
+func (c *LRUCache) lookup(key string) (*lruEntry, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+// store caches value under key until expires, which never passes when zero. Callers hold mu.
+func (c *LRUCache) store(key string, value string, expires time.Time) {
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+
+	for c.Size > 0 && c.order.Len() > c.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// LookupStats is how many cached reads of one function found a value and how many had to go to the database.
+type LookupStats struct {
+	Function string
+	Hits     uint64
+	Misses   uint64
+}
+
+type lookupCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// lookups maps each function to its counters, for the life of the process.
+var lookups sync.Map
+
+// CountLookup records a cached read of function as a hit or a miss.
+func CountLookup(function string, hit bool) {
+	value, _ := lookups.LoadOrStore(function, &lookupCounters{})
+	counters := value.(*lookupCounters)
+
+	if hit {
+		counters.hits.Add(1)
+	} else {
+		counters.misses.Add(1)
+	}
+}
+
+// Lookups returns the hits and misses of every function read so far, by function name.
+func Lookups() []LookupStats {
+	var stats []LookupStats
+
+	lookups.Range(func(key, value interface{}) bool {
+		counters := value.(*lookupCounters)
+		stats = append(stats, LookupStats{Function: key.(string), Hits: counters.hits.Load(), Misses: counters.misses.Load()})
+		return true
+	})
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Function < stats[j].Function })
+	return stats
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache keeps cached values in Redis, where every instance of the API shares them.
+type RedisCache struct {
+	Client *redis.Client
+}
+
+// NewRedisCache returns a cache kept in Redis through client.
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{Client: client}
+}
+
+// Get returns the value cached under key, or ErrMiss.
+func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.Client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrMiss
+	}
+
+	return value, err
+}
+
+// Set caches value under key for ttl.
+func (c *RedisCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return c.Client.Set(ctx, key, value, ttl).Err()
+}
+
+// Incr adds one to the number cached under key and returns it.
+func (c *RedisCache) Incr(ctx context.Context, key string) (int64, error) {
+	return c.Client.Incr(ctx, key).Result()
+}
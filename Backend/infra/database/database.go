@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"log"
 	"sync"
 
@@ -13,11 +15,13 @@ import (
 
 var (
 	database *gorm.DB
+	replica  *gorm.DB
 	once     sync.Once
 	err      error
 )
 
-// DbConnection create database connection
+// DbConnection create database connection. Outside of debug mode reads go to the replica, or to the primary while
+// the replica lags behind, see MonitorReplicas.
 func DbConnection(masterDSN, replicaDSN string) error {
 	var db = database
 	var err error
@@ -31,20 +35,33 @@ func DbConnection(masterDSN, replicaDSN string) error {
 			loglevel = logger.Info
 		}
 
-		db, err = gorm.Open(postgres.Open(masterDSN), &gorm.Config{
+		config := &gorm.Config{
 			Logger: logger.Default.LogMode(loglevel),
-		})
+		}
+
+		db, err = gorm.Open(postgres.Open(masterDSN), config)
+		if err != nil {
+			log.Fatalf("Db connection error: %s", err)
+		}
 		if !debug {
+			replica, err = gorm.Open(postgres.Open(replicaDSN), config)
+			if err != nil {
+				log.Fatalf("Db replica connection error: %s", err)
+			}
+
+			primaryPool, replicaPool := db.ConnPool, replica.ConnPool
+			policy.setPools(primaryPool, []gorm.ConnPool{replicaPool}, []string{"replica"})
+
+			// The primary is listed as a replica too, so the policy always gets to choose and has somewhere to send
+			// reads when every replica lags.
 			db.Use(dbresolver.Register(dbresolver.Config{
 				Replicas: []gorm.Dialector{
-					postgres.Open(replicaDSN),
+					postgres.New(postgres.Config{Conn: replicaPool}),
+					postgres.New(postgres.Config{Conn: primaryPool}),
 				},
-				Policy: dbresolver.RandomPolicy{},
+				Policy: policy,
 			}))
 		}
-		if err != nil {
-			log.Fatalf("Db connection error: %s", err)
-		}
 		database = db
 	})
 
@@ -55,3 +72,31 @@ func DbConnection(masterDSN, replicaDSN string) error {
 func GetDB() *gorm.DB {
 	return database
 }
+
+// PingPrimary checks that the primary answers, with a connection of its own rather than through the replica policy.
+func PingPrimary(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the connection pools of the primary and the replica. Queries running on them are waited for.
+func Close() error {
+	var errs []error
+	for _, db := range []*gorm.DB{database, replica} {
+		if db == nil {
+			continue
+		}
+
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
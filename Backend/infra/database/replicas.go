@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+	applogger "willowsuite-vault/infra/logger"
+
+	"gorm.io/gorm"
+)
+
+// replicaLagSQL is how far behind the primary a replica is, in seconds. A replica that has replayed everything it
+// received is not behind, however long ago the last change was.
+const replicaLagSQL = `SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 ` +
+	`ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`
+
+// ReplicaHealth is how a replica was doing when it was last checked.
+type ReplicaHealth struct {
+	Name      string
+	Healthy   bool
+	Lag       time.Duration
+	Error     string
+	CheckedAt time.Time
+}
+
+// ReplicaPolicy is the dbresolver policy that sends reads to a healthy replica at random, or to the primary when
+// none is healthy. Replicas are healthy until a check finds otherwise.
+type ReplicaPolicy struct {
+	mu       sync.RWMutex
+	primary  gorm.ConnPool
+	replicas []gorm.ConnPool
+	health   []ReplicaHealth
+}
+
+// policy routes the reads of the database set up by DbConnection.
+var policy = &ReplicaPolicy{}
+
+// NewReplicaPolicy returns a policy for reading from replicas, named by names, that falls back to primary.
+func NewReplicaPolicy(primary gorm.ConnPool, replicas []gorm.ConnPool, names []string) *ReplicaPolicy {
+	p := &ReplicaPolicy{}
+	p.setPools(primary, replicas, names)
+	return p
+}
+
+func (p *ReplicaPolicy) setPools(primary gorm.ConnPool, replicas []gorm.ConnPool, names []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.primary, p.replicas = primary, replicas
+	p.health = make([]ReplicaHealth, len(replicas))
+	for i := range replicas {
+		p.health[i] = ReplicaHealth{Name: names[i], Healthy: true}
+	}
+}
+
+// Resolve picks the pool a read goes to. It is handed every replica, the primary among them.
+func (p *ReplicaPolicy) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	healthy := make([]gorm.ConnPool, 0, len(pools))
+	for _, pool := range pools {
+		for i, replica := range p.replicas {
+			if pool == replica && p.health[i].Healthy {
+				healthy = append(healthy, pool)
+			}
+		}
+	}
+
+	if len(healthy) == 0 {
+		if p.primary != nil {
+			return p.primary
+		}
+		return pools[rand.Intn(len(pools))]
+	}
+
+	return healthy[rand.Intn(len(healthy))]
+}
+
+// Check measures the lag of every replica and takes any further behind than maxLag, or that can't be reached, out
+// of rotation until it catches up.
+func (p *ReplicaPolicy) Check(ctx context.Context, maxLag time.Duration) {
+	p.mu.RLock()
+	replicas := p.replicas
+	p.mu.RUnlock()
+
+	for i, replica := range replicas {
+		var seconds float64
+		err := replica.QueryRowContext(ctx, replicaLagSQL).Scan(&seconds)
+
+		health := ReplicaHealth{Lag: time.Duration(seconds * float64(time.Second)), CheckedAt: time.Now()}
+		health.Healthy = err == nil && health.Lag <= maxLag
+		if err != nil {
+			health.Error = err.Error()
+		}
+
+		p.mu.Lock()
+		health.Name = p.health[i].Name
+		if health.Healthy != p.health[i].Healthy {
+			if health.Healthy {
+				applogger.Infof("replica %s caught up, reading from it again", health.Name)
+			} else {
+				applogger.Warnf("replica %s is %s behind (%s), reading from the primary instead", health.Name, health.Lag, health.Error)
+			}
+		}
+		p.health[i] = health
+		p.mu.Unlock()
+	}
+}
+
+// MonitorReplicas checks how far the replicas lag every interval until ctx is done, and stops reading from any that
+// are more than maxLag behind. It does nothing in debug mode, where there are no replicas.
+func MonitorReplicas(ctx context.Context, maxLag time.Duration, interval time.Duration) {
+	if !HasReplicas() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		policy.Check(checkCtx, maxLag)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Health returns how every replica was doing as of its last check.
+func (p *ReplicaPolicy) Health() []ReplicaHealth {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return append([]ReplicaHealth(nil), p.health...)
+}
+
+// HasReplicas reports whether reads can go to a replica at all.
+func HasReplicas() bool {
+	return len(policy.Health()) > 0
+}
+
+// Replicas returns how the replicas of the database set up by DbConnection were doing as of their last check.
+func Replicas() []ReplicaHealth {
+	return policy.Health()
+}
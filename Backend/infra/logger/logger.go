@@ -1,82 +1,197 @@
+// Package logger writes the structured logs of the API. Lines are JSON or logfmt, and lines logged with Ctx carry
+// the fields of the request they belong to, such as its request ID and user.
 package logger
 
 import (
-	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"sync"
+
 	"github.com/sirupsen/logrus"
-	"strings"
-	"time"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var logger = logrus.New()
 
 func init() {
 	logger.Level = logrus.InfoLevel
-	logger.Formatter = &formatter{}
+	logger.Formatter = &logrus.JSONFormatter{}
+}
+
+// Init sets the format lines are written in, "json" or "logfmt", and the lowest level written: "debug", "info",
+// "warn", "error" or "fatal".
+func Init(format string, level string) error {
+	switch format {
+	case "json":
+		logger.Formatter = &logrus.JSONFormatter{}
+	case "logfmt":
+		logger.Formatter = &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
 
-	logger.SetReportCaller(true)
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	logger.Level = parsed
+
+	return nil
 }
 
 func SetLogLevel(level logrus.Level) {
 	logger.Level = level
 }
 
+// SetOutput sets where lines are written, os.Stderr by default.
+func SetOutput(out io.Writer) {
+	logger.Out = out
+}
+
 type Fields logrus.Fields
 
-// Debugf logs a message at level Debug on the standard logger.
-func Debugf(format string, args ...interface{}) {
-	if logger.Level >= logrus.DebugLevel {
-		entry := logger.WithFields(logrus.Fields{})
-		entry.Debugf(format, args...)
-	}
+// requestFields are the fields every line of a request is logged with. They are shared by every context derived
+// from the request's, so fields added deep in the middleware chain also reach the line logged when it completes.
+type requestFields struct {
+	mu     sync.Mutex
+	fields Fields
 }
 
-// Infof logs a message at level Info on the standard logger.
-func Infof(format string, args ...interface{}) {
-	if logger.Level >= logrus.InfoLevel {
-		entry := logger.WithFields(logrus.Fields{})
-		entry.Infof(format, args...)
+type contextKey struct{}
+
+// NewContext returns a copy of ctx with an empty set of request fields, for AddFields to fill.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestFields{fields: Fields{}})
+}
+
+// AddFields adds fields to the request of ctx, so every line logged with Ctx for it from now on carries them. It does
+// nothing for a context that NewContext didn't make.
+func AddFields(ctx context.Context, fields Fields) {
+	request, ok := ctx.Value(contextKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+
+	request.mu.Lock()
+	defer request.mu.Unlock()
+	for key, value := range fields {
+		request.fields[key] = value
 	}
 }
 
-// Warnf logs a message at level Warn on the standard logger.
-func Warnf(format string, args ...interface{}) {
-	if logger.Level >= logrus.WarnLevel {
-		entry := logger.WithFields(logrus.Fields{})
-		entry.Warnf(format, args...)
+// Entry logs lines with a set of fields.
+type Entry struct {
+	fields Fields
+}
+
+// Ctx returns an Entry for the request of ctx, with its fields and, when ctx holds a recorded span, the trace and
+// span IDs so the line can be found from the trace.
+func Ctx(ctx context.Context) Entry {
+	fields := Fields{}
+
+	if request, ok := ctx.Value(contextKey{}).(*requestFields); ok {
+		request.mu.Lock()
+		for key, value := range request.fields {
+			fields[key] = value
+		}
+		request.mu.Unlock()
 	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields["trace_id"] = spanContext.TraceID().String()
+		fields["span_id"] = spanContext.SpanID().String()
+	}
+
+	return Entry{fields: fields}
 }
 
-// Errorf logs a message at level Error on the standard logger.
-func Errorf(format string, args ...interface{}) {
-	if logger.Level >= logrus.ErrorLevel {
-		entry := logger.WithFields(logrus.Fields{})
-		entry.Errorf(format, args...)
+// WithFields returns an Entry with fields added to those of entry.
+func (entry Entry) WithFields(fields Fields) Entry {
+	merged := Fields{}
+	for key, value := range entry.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
 	}
+
+	return Entry{fields: merged}
 }
 
-// Fatalf logs a message at level Fatal on the standard logger.
-func Fatalf(format string, args ...interface{}) {
-	if logger.Level >= logrus.FatalLevel {
-		entry := logger.WithFields(logrus.Fields{})
-		entry.Fatalf(format, args...)
+// WithFields returns an Entry that logs lines with fields.
+func WithFields(fields Fields) Entry {
+	return Entry{}.WithFields(fields)
+}
+
+// Debugf logs a message at level Debug.
+func (entry Entry) Debugf(format string, args ...interface{}) {
+	entry.logf(logrus.DebugLevel, format, args...)
+}
+
+// Infof logs a message at level Info.
+func (entry Entry) Infof(format string, args ...interface{}) {
+	entry.logf(logrus.InfoLevel, format, args...)
+}
+
+// Warnf logs a message at level Warn.
+func (entry Entry) Warnf(format string, args ...interface{}) {
+	entry.logf(logrus.WarnLevel, format, args...)
+}
+
+// Errorf logs a message at level Error.
+func (entry Entry) Errorf(format string, args ...interface{}) {
+	entry.logf(logrus.ErrorLevel, format, args...)
+}
+
+// Fatalf logs a message at level Fatal and exits.
+func (entry Entry) Fatalf(format string, args ...interface{}) {
+	entry.logf(logrus.FatalLevel, format, args...)
+	logger.Exit(1)
+}
+
+// logf logs a line with the fields of entry and the file and line it was logged from. It has to be called straight
+// from the exported logging functions for the caller to be right.
+func (entry Entry) logf(level logrus.Level, format string, args ...interface{}) {
+	if !logger.IsLevelEnabled(level) {
+		return
+	}
+
+	fields := logrus.Fields{}
+	for key, value := range entry.fields {
+		fields[key] = value
 	}
+	if _, file, line, ok := runtime.Caller(2); ok {
+		fields["caller"] = fmt.Sprintf("%s:%d", filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)), line)
+	}
+
+	logger.WithFields(fields).Logf(level, format, args...)
 }
 
-// Formatter implements logrus.Formatter interface.
-type formatter struct {
-	prefix string
+// Debugf logs a message at level Debug on the standard logger.
+func Debugf(format string, args ...interface{}) {
+	Entry{}.logf(logrus.DebugLevel, format, args...)
 }
 
-// Format building log message.
-func (f *formatter) Format(entry *logrus.Entry) ([]byte, error) {
-	var sb bytes.Buffer
+// Infof logs a message at level Info on the standard logger.
+func Infof(format string, args ...interface{}) {
+	Entry{}.logf(logrus.InfoLevel, format, args...)
+}
 
-	sb.WriteString(strings.ToUpper(entry.Level.String()))
-	sb.WriteString(" ")
-	sb.WriteString(entry.Time.Format(time.RFC3339))
-	sb.WriteString(" ")
-	sb.WriteString(f.prefix)
-	sb.WriteString(entry.Message)
+// Warnf logs a message at level Warn on the standard logger.
+func Warnf(format string, args ...interface{}) {
+	Entry{}.logf(logrus.WarnLevel, format, args...)
+}
 
-	return sb.Bytes(), nil
+// Errorf logs a message at level Error on the standard logger.
+func Errorf(format string, args ...interface{}) {
+	Entry{}.logf(logrus.ErrorLevel, format, args...)
+}
+
+// Fatalf logs a message at level Fatal on the standard logger and exits.
+func Fatalf(format string, args ...interface{}) {
+	Entry{}.logf(logrus.FatalLevel, format, args...)
+	logger.Exit(1)
 }
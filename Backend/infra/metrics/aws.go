@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// InstrumentAWS is an AWS SDK API option that times every call a client makes, retries included, and counts the
+// calls that fail. Add it to the APIOptions of the client's config.
+func InstrumentAWS(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("VaultMetrics", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		start := time.Now()
+		out, metadata, err := next.HandleInitialize(ctx, in)
+
+		service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+		upstreamDuration.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+		if err != nil {
+			upstreamErrors.WithLabelValues(service, operation).Inc()
+		}
+
+		return out, metadata, err
+	}), middleware.Before)
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"willowsuite-vault/infra/cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheLookupsDesc = prometheus.NewDesc(
+		"vault_cache_lookups_total",
+		"Cached reads, by repository function and whether they hit.",
+		[]string{"function", "result"}, nil,
+	)
+
+	cacheOpenDesc = prometheus.NewDesc(
+		"vault_cache_circuit_open",
+		"1 while the circuit breaker in front of Redis is open and reads skip the cache.",
+		nil, nil,
+	)
+)
+
+// cacheCollector exports the lookups counted by the cache package, and the state of its circuit breaker.
+type cacheCollector struct{}
+
+func (cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheLookupsDesc
+	ch <- cacheOpenDesc
+}
+
+func (cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range cache.Lookups() {
+		ch <- prometheus.MustNewConstMetric(cacheLookupsDesc, prometheus.CounterValue, float64(stats.Hits), stats.Function, "hit")
+		ch <- prometheus.MustNewConstMetric(cacheLookupsDesc, prometheus.CounterValue, float64(stats.Misses), stats.Function, "miss")
+	}
+
+	if breaker, ok := cache.GetCache().(*cache.Breaker); ok {
+		open := 0.0
+		if breaker.Open() {
+			open = 1
+		}
+		ch <- prometheus.MustNewConstMetric(cacheOpenDesc, prometheus.GaugeValue, open)
+	}
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startedKey = "metrics:started"
+
+// InstrumentDB times every query db runs, by GORM operation.
+func InstrumentDB(db *gorm.DB) error {
+	callback := db.Callback()
+
+	return errors.Join(
+		callback.Create().Before("*").Register("metrics:before_create", startTimer),
+		callback.Create().After("*").Register("metrics:after_create", observe("create")),
+		callback.Query().Before("*").Register("metrics:before_query", startTimer),
+		callback.Query().After("*").Register("metrics:after_query", observe("query")),
+		callback.Update().Before("*").Register("metrics:before_update", startTimer),
+		callback.Update().After("*").Register("metrics:after_update", observe("update")),
+		callback.Delete().Before("*").Register("metrics:before_delete", startTimer),
+		callback.Delete().After("*").Register("metrics:after_delete", observe("delete")),
+		callback.Row().Before("*").Register("metrics:before_row", startTimer),
+		callback.Row().After("*").Register("metrics:after_row", observe("row")),
+		callback.Raw().Before("*").Register("metrics:before_raw", startTimer),
+		callback.Raw().After("*").Register("metrics:after_raw", observe("raw")),
+	)
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(startedKey, time.Now())
+}
+
+func observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		started, ok := db.InstanceGet(startedKey)
+		if !ok {
+			return
+		}
+
+		dbDuration.WithLabelValues(operation).Observe(time.Since(started.(time.Time)).Seconds())
+
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			dbErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
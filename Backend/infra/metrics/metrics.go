@@ -0,0 +1,72 @@
+// Package metrics keeps the Prometheus metrics of the API.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Registry holds every metric the API exports on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vault_http_requests_total",
+		Help: "HTTP requests handled, by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vault_http_request_duration_seconds",
+		Help:    "How long HTTP requests took to handle, by method and route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vault_db_query_duration_seconds",
+		Help:    "How long database queries took, by GORM operation.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vault_db_query_errors_total",
+		Help: "Database queries that failed, by GORM operation. Records that weren't found aren't counted.",
+	}, []string{"operation"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vault_upstream_request_duration_seconds",
+		Help:    "How long calls to AWS services took, retries included, by service and operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "operation"})
+
+	upstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vault_upstream_errors_total",
+		Help: "Calls to AWS services that failed, by service and operation.",
+	}, []string{"service", "operation"})
+
+	qrCodes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vault_qr_codes_generated_total",
+		Help: "QR codes generated, by whether their link came from the cache or was presigned.",
+	}, []string{"source"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		dbDuration, dbErrors,
+		upstreamDuration, upstreamErrors,
+		qrCodes,
+		cacheCollector{},
+	)
+}
+
+// CountQRCode records a generated QR code, whether its link was cached or had to be presigned.
+func CountQRCode(cached bool) {
+	source := "s3"
+	if cached {
+		source = "cache"
+	}
+
+	qrCodes.WithLabelValues(source).Inc()
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"sync"
 	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/infra/metrics"
+	"willowsuite-vault/infra/tracing"
 
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -22,6 +24,7 @@ var (
 type S3Client interface {
 	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
 }
 
 type S3PresignClient interface {
@@ -36,10 +39,12 @@ func S3ClientInit() error {
 			logger.Fatalf("Issue creating S3 Client: %v", err)
 		}
 
-		newClient := s3.NewFromConfig(cfg)
+		client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.APIOptions = append(o.APIOptions, metrics.InstrumentAWS, tracing.InstrumentAWS)
+		})
 
-		client = newClient
-		presignClient = s3.NewPresignClient(newClient)
+		// Presigning doesn't call S3, so there is nothing to time.
+		presignClient = s3.NewPresignClient(s3.NewFromConfig(cfg))
 	})
 
 	return err
@@ -0,0 +1,28 @@
+package tracing
+
+import (
+	"context"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentAWS is an AWS SDK API option that gives every call a client makes a client span, retries included. Add
+// it to the APIOptions of the client's config.
+func InstrumentAWS(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("VaultTracing", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+		ctx, span := Tracer().Start(ctx, service+"."+operation, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.RPCSystemKey.String("aws-api"), semconv.RPCService(service), semconv.RPCMethod(operation)))
+		defer span.End()
+
+		out, metadata, err := next.HandleInitialize(ctx, in)
+		if err != nil {
+			fail(span, err)
+		}
+
+		return out, metadata, err
+	}), middleware.Before)
+}
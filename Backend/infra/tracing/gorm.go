@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// InstrumentDB gives every query db runs a client span, under the span of the context the query was given with
+// WithContext. Spans carry the SQL with its placeholders, never the values bound to them.
+func InstrumentDB(db *gorm.DB) error {
+	callback := db.Callback()
+
+	return errors.Join(
+		callback.Create().Before("*").Register("tracing:before_create", startSpan("create")),
+		callback.Create().After("*").Register("tracing:after_create", endSpan),
+		callback.Query().Before("*").Register("tracing:before_query", startSpan("query")),
+		callback.Query().After("*").Register("tracing:after_query", endSpan),
+		callback.Update().Before("*").Register("tracing:before_update", startSpan("update")),
+		callback.Update().After("*").Register("tracing:after_update", endSpan),
+		callback.Delete().Before("*").Register("tracing:before_delete", startSpan("delete")),
+		callback.Delete().After("*").Register("tracing:after_delete", endSpan),
+		callback.Row().Before("*").Register("tracing:before_row", startSpan("row")),
+		callback.Row().After("*").Register("tracing:after_row", endSpan),
+		callback.Raw().Before("*").Register("tracing:before_raw", startSpan("raw")),
+		callback.Raw().After("*").Register("tracing:after_raw", endSpan),
+	)
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		_, span := Tracer().Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation)),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		fail(span, db.Error)
+	}
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentRedis gives every command and pipeline client sends a client span. Keys and values aren't recorded.
+func InstrumentRedis(client *redis.Client) {
+	client.AddHook(redisHook{})
+}
+
+type redisHook struct{}
+
+func (redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		ctx, span := Tracer().Start(ctx, "redis.dial", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, semconv.ServerAddress(addr)))
+		defer span.End()
+
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			fail(span, err)
+		}
+
+		return conn, err
+	}
+}
+
+func (redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := Tracer().Start(ctx, "redis."+cmd.Name(), trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationName(cmd.Name())))
+		defer span.End()
+
+		// A missing key answers redis.Nil, which is a result rather than a failure.
+		err := next(ctx, cmd)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			fail(span, err)
+		}
+
+		return err
+	}
+}
+
+func (redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := Tracer().Start(ctx, "redis.pipeline", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, attribute.Int("db.operation.batch.size", len(cmds))))
+		defer span.End()
+
+		err := next(ctx, cmds)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			fail(span, err)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,95 @@
+// Package tracing sets up OpenTelemetry tracing for the API and instruments the router, GORM, Redis and the AWS
+// SDK with it.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "willowsuite-vault"
+
+// Tracer returns the tracer spans of the API are started with. It is looked up on every call so a provider set
+// later, as tests do, is picked up.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Init sets the global tracer provider up to send spans to exporter: "otlp", "stdout", "file" (appending to file)
+// or "none". W3C trace context and baggage are propagated whatever the exporter, so traces that pass through
+// keep their IDs. The returned function flushes the spans still buffered and has to be called before exiting.
+func Init(ctx context.Context, exporter string, file string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		spanExporter sdktrace.SpanExporter
+		closer       io.Closer
+		err          error
+	)
+	switch exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		// The endpoint, headers and TLS are read from the standard OTEL_EXPORTER_OTLP_* variables.
+		spanExporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		var f *os.File
+		f, err = os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening trace file: %w", err)
+		}
+		closer = f
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s exporter: %w", exporter, err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(instrumentationName)),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("describing the service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// fail marks span as failed with err.
+func fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -1,16 +1,26 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 	"willowsuite-vault/config"
+	"willowsuite-vault/controllers"
 	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/infra/cognito"
 	"willowsuite-vault/infra/database"
 	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/infra/metrics"
 	"willowsuite-vault/infra/s3"
+	"willowsuite-vault/infra/tracing"
 	"willowsuite-vault/migrations"
+	"willowsuite-vault/repository"
 	"willowsuite-vault/routers"
+	"willowsuite-vault/webhooks"
 
 	"github.com/spf13/viper"
 )
@@ -25,18 +35,56 @@ func main() {
 	if err := config.SetupConfig(); err != nil {
 		logger.Fatalf("config SetupConfig() error: %s", err)
 	}
+	if err := logger.Init(config.LogFormat(), config.LogLevel()); err != nil {
+		logger.Fatalf("logger Init error: %s", err)
+	}
+	trustedProxies, err := config.TrustedProxies()
+	if err != nil {
+		logger.Fatalf("config TrustedProxies error: %s", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), config.TracingExporter(), config.TracingFile(), config.TracingSampleRatio())
+	if err != nil {
+		logger.Fatalf("tracing Init error: %s", err)
+	}
 
 	masterDSN, replicaDSN := config.DbConfiguration()
 	if err := database.DbConnection(masterDSN, replicaDSN); err != nil {
 		logger.Fatalf("database DbConnection error: %s", err)
 	}
-	//later separate migration
-	migrations.Migrate()
+	if err := metrics.InstrumentDB(database.GetDB()); err != nil {
+		logger.Fatalf("metrics InstrumentDB error: %s", err)
+	}
+	if err := tracing.InstrumentDB(database.GetDB()); err != nil {
+		logger.Fatalf("tracing InstrumentDB error: %s", err)
+	}
+	if err := migrations.Migrate(); err != nil {
+		logger.Fatalf("migrations Migrate() error: %s", err)
+	}
+
+	// Background work runs until shutdown, which waits for it to stop before closing the pools it uses.
+	background, stopBackground := context.WithCancel(context.Background())
+	var backgroundWork sync.WaitGroup
+	backgroundWork.Add(1)
+	go func() {
+		defer backgroundWork.Done()
+		database.MonitorReplicas(background, config.ReplicaMaxLag(), config.ReplicaCheckInterval())
+	}()
 
+	// Redis being down doesn't stop the API from starting: cached reads go to the database until it is back.
 	redisConnectionString := config.RedisConfiguration()
 	if err := cache.ClientConnection(redisConnectionString); err != nil {
 		logger.Fatalf("redis ClientConnection error: %s", err)
 	}
+	tracing.InstrumentRedis(cache.GetClient())
+	pingCtx, cancelPing := context.WithTimeout(context.Background(), 2*time.Second)
+	if err := cache.GetClient().Ping(pingCtx).Err(); err != nil {
+		logger.Warnf("redis is unreachable, starting without it: %s", err)
+	}
+	cancelPing()
+	if err := cache.CacheInit(config.CacheDriver(), config.CacheSize()); err != nil {
+		logger.Fatalf("cache CacheInit error: %s", err)
+	}
 
 	if err := cognito.CognitoClientInit(); err != nil {
 		logger.Fatalf("Cognito Connection error: %s", err)
@@ -46,7 +94,58 @@ func main() {
 		logger.Fatalf("S3 Connection error: %s", err)
 	}
 
-	router := routers.SetupRoute()
-	logger.Fatalf("%v", http.ListenAndServe(config.ServerConfig(), router))
+	// Every replica sends webhook deliveries, the queue makes sure each one is only sent once.
+	dispatcher := webhooks.NewDispatcher(&repository.Repository{Database: database.GetDB(), Cache: cache.GetCache(), Redis: cache.GetClient()})
+	backgroundWork.Add(1)
+	go func() {
+		defer backgroundWork.Done()
+		dispatcher.Run(background)
+	}()
+
+	server := &http.Server{
+		Addr:              config.ServerConfig(),
+		Handler:           routers.SetupRoute(trustedProxies),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       config.ServerReadTimeout(),
+		WriteTimeout:      config.ServerWriteTimeout(),
+		IdleTimeout:       config.ServerIdleTimeout(),
+	}
 
+	stopped, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe() }()
+
+	select {
+	case err := <-serverErr:
+		logger.Fatalf("%v", err)
+	case <-stopped.Done():
+	}
+	stop()
+
+	// Fail readiness first and give load balancers time to notice before new connections are refused.
+	logger.Infof("shutting down, draining requests")
+	controllers.Shutdown()
+	time.Sleep(config.ShutdownDelay())
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), config.ShutdownTimeout())
+	defer cancelDrain()
+	if err := server.Shutdown(drainCtx); err != nil {
+		logger.Errorf("server shutdown error, requests were cut off: %s", err)
+	}
+
+	stopBackground()
+	backgroundWork.Wait()
+
+	if err := shutdownTracing(drainCtx); err != nil {
+		logger.Errorf("tracing shutdown error: %s", err)
+	}
+	if err := cache.GetClient().Close(); err != nil {
+		logger.Errorf("redis close error: %s", err)
+	}
+	if err := database.Close(); err != nil {
+		logger.Errorf("database close error: %s", err)
+	}
+	logger.Infof("shut down")
 }
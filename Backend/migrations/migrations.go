@@ -1,16 +1,260 @@
-// Package migrations is used to handle all of our GORM DB migrations.
+// Package migrations is used to handle all of our versioned DB migrations.
+//
+// Migrations are pairs of SQL files in the sql directory named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. They are embedded in the binary, applied in version order and recorded in the
+// schema_migrations table. Never edit a migration that has been released, add a new one instead.
 package migrations
 
 import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
 	"willowsuite-vault/infra/database"
-	"willowsuite-vault/models"
+
+	"gorm.io/gorm"
 )
 
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey is the Postgres advisory lock held while migrating, so servers starting together take turns.
+const lockKey int64 = 7391026454
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one version of the schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration and when it was applied, if it has been.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Load returns the embedded migrations in version order.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		parts := fileName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("migration %s isn't named <version>_<name>.<up|down>.sql", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(parts[1], 10, 64)
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = migration
+		} else if migration.Name != parts[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, parts[2])
+		}
+
+		contents, err := fs.ReadFile(files, "sql/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", entry.Name(), err)
+		}
+
+		if parts[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
 // Migrate is called in main.go to migrate out DB to the latest version.
-func Migrate() {
-	var migrationModels = []interface{}{&models.Building{}, &models.Room{}, &models.ShelvingUnit{}, &models.Shelf{}, &models.Container{}, &models.Item{}}
-	err := database.GetDB().AutoMigrate(migrationModels...)
+func Migrate() error {
+	_, err := Up(database.GetDB())
+	return err
+}
+
+// Up applies every migration that hasn't been applied yet and returns them. Everything runs in one transaction,
+// so when a migration fails none of them are kept.
+func Up(db *gorm.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	err = db.Transaction(func(tx *gorm.DB) error {
+		applied, err := lockAndListApplied(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			if err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, migration.Version, migration.Name).Error; err != nil {
+				return fmt.Errorf("recording migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			ran = append(ran, migration)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ran, nil
+}
+
+// Down reverts the most recently applied migrations, up to steps of them, and returns them.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	migrations, err := Load()
 	if err != nil {
-		return
+		return nil, err
 	}
+
+	byVersion := map[int64]Migration{}
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var reverted []Migration
+	err = db.Transaction(func(tx *gorm.DB) error {
+		applied, err := lockAndListApplied(tx)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			migration, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("migration %d_%s was applied by a newer build and can't be reverted by this one", version, applied[version].Name)
+			}
+
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			if err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, migration.Version).Error; err != nil {
+				return fmt.Errorf("recording revert of migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return reverted, nil
+}
+
+// Status lists every migration this build knows about, and any the database has that it doesn't, without
+// changing anything.
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]schemaMigration{}
+	if db.Migrator().HasTable("schema_migrations") {
+		if applied, err = listApplied(db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			delete(applied, migration.Version)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for _, row := range applied {
+		statuses = append(statuses, MigrationStatus{Version: row.Version, Name: row.Name, AppliedAt: &row.AppliedAt})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// lockAndListApplied waits for the migration lock, which is released when tx ends, then lists what has been
+// applied.
+func lockAndListApplied(tx *gorm.DB) (map[int64]schemaMigration, error) {
+	if err := tx.Exec(`SELECT pg_advisory_xact_lock(?)`, lockKey).Error; err != nil {
+		return nil, fmt.Errorf("locking migrations: %w", err)
+	}
+
+	err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`).Error
+	if err != nil {
+		return nil, fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	return listApplied(tx)
+}
+
+func listApplied(db *gorm.DB) (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := db.Raw(`SELECT version, name, applied_at FROM schema_migrations`).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("listing applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
 }
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS items;
+DROP TABLE IF EXISTS containers;
+DROP TABLE IF EXISTS shelves;
+DROP TABLE IF EXISTS shelving_units;
+DROP TABLE IF EXISTS rooms;
+DROP TABLE IF EXISTS buildings;
@@ -0,0 +1,72 @@
+-- The schema GORM AutoMigrate used to create. IF NOT EXISTS lets databases that were auto migrated adopt it as is.
+
+CREATE TABLE IF NOT EXISTS buildings (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    address TEXT
+);
+
+CREATE TABLE IF NOT EXISTS rooms (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+CREATE TABLE IF NOT EXISTS shelving_units (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+CREATE TABLE IF NOT EXISTS shelves (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+CREATE TABLE IF NOT EXISTS containers (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+CREATE TABLE IF NOT EXISTS items (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
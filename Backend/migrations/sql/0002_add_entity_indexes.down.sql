@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_buildings_user_id_created_at;
+DROP INDEX IF EXISTS idx_rooms_user_id_created_at;
+DROP INDEX IF EXISTS idx_rooms_user_id_parent;
+DROP INDEX IF EXISTS idx_shelving_units_user_id_created_at;
+DROP INDEX IF EXISTS idx_shelving_units_user_id_parent;
+DROP INDEX IF EXISTS idx_shelves_user_id_created_at;
+DROP INDEX IF EXISTS idx_shelves_user_id_parent;
+DROP INDEX IF EXISTS idx_containers_user_id_created_at;
+DROP INDEX IF EXISTS idx_containers_user_id_parent;
+DROP INDEX IF EXISTS idx_items_user_id_created_at;
+DROP INDEX IF EXISTS idx_items_user_id_parent;
@@ -0,0 +1,19 @@
+-- Listing filters on the owner and sorts by creation, finding children filters on the owner and the parent.
+-- Both only ever look at rows that haven't been soft deleted.
+
+CREATE INDEX IF NOT EXISTS idx_buildings_user_id_created_at ON buildings (user_id, created_at) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_rooms_user_id_created_at ON rooms (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_rooms_user_id_parent ON rooms (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_shelving_units_user_id_created_at ON shelving_units (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_shelving_units_user_id_parent ON shelving_units (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_shelves_user_id_created_at ON shelves (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_shelves_user_id_parent ON shelves (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_containers_user_id_created_at ON containers (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_containers_user_id_parent ON containers (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_items_user_id_created_at ON items (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_items_user_id_parent ON items (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
@@ -0,0 +1,121 @@
+-- Splits entities back into a table per category, with the indexes migration 2 added.
+
+CREATE TABLE buildings (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    address TEXT
+);
+
+CREATE TABLE rooms (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+CREATE TABLE shelving_units (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+CREATE TABLE shelves (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+CREATE TABLE containers (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+CREATE TABLE items (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    notes TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    parent_id BIGINT,
+    parent_category TEXT
+);
+
+INSERT INTO buildings (id, name, notes, user_id, created_at, updated_at, deleted_at, address)
+SELECT id, name, notes, user_id, created_at, updated_at, deleted_at, attributes->>'address'
+FROM entities WHERE category = 'building';
+SELECT setval('buildings_id_seq', COALESCE((SELECT max(id) FROM buildings), 0) + 1, false);
+
+INSERT INTO rooms (id, name, notes, user_id, created_at, updated_at, deleted_at, parent_id, parent_category)
+SELECT id, name, notes, user_id, created_at, updated_at, deleted_at, COALESCE(parent_id, 0), COALESCE(parent_category, '')
+FROM entities WHERE category = 'room';
+SELECT setval('rooms_id_seq', COALESCE((SELECT max(id) FROM rooms), 0) + 1, false);
+
+INSERT INTO shelving_units (id, name, notes, user_id, created_at, updated_at, deleted_at, parent_id, parent_category)
+SELECT id, name, notes, user_id, created_at, updated_at, deleted_at, COALESCE(parent_id, 0), COALESCE(parent_category, '')
+FROM entities WHERE category = 'shelving_unit';
+SELECT setval('shelving_units_id_seq', COALESCE((SELECT max(id) FROM shelving_units), 0) + 1, false);
+
+INSERT INTO shelves (id, name, notes, user_id, created_at, updated_at, deleted_at, parent_id, parent_category)
+SELECT id, name, notes, user_id, created_at, updated_at, deleted_at, COALESCE(parent_id, 0), COALESCE(parent_category, '')
+FROM entities WHERE category = 'shelf';
+SELECT setval('shelves_id_seq', COALESCE((SELECT max(id) FROM shelves), 0) + 1, false);
+
+INSERT INTO containers (id, name, notes, user_id, created_at, updated_at, deleted_at, parent_id, parent_category)
+SELECT id, name, notes, user_id, created_at, updated_at, deleted_at, COALESCE(parent_id, 0), COALESCE(parent_category, '')
+FROM entities WHERE category = 'container';
+SELECT setval('containers_id_seq', COALESCE((SELECT max(id) FROM containers), 0) + 1, false);
+
+INSERT INTO items (id, name, notes, user_id, created_at, updated_at, deleted_at, parent_id, parent_category)
+SELECT id, name, notes, user_id, created_at, updated_at, deleted_at, COALESCE(parent_id, 0), COALESCE(parent_category, '')
+FROM entities WHERE category = 'item';
+SELECT setval('items_id_seq', COALESCE((SELECT max(id) FROM items), 0) + 1, false);
+
+CREATE INDEX idx_buildings_user_id_created_at ON buildings (user_id, created_at) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_rooms_user_id_created_at ON rooms (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_rooms_user_id_parent ON rooms (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_shelving_units_user_id_created_at ON shelving_units (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_shelving_units_user_id_parent ON shelving_units (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_shelves_user_id_created_at ON shelves (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_shelves_user_id_parent ON shelves (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_containers_user_id_created_at ON containers (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_containers_user_id_parent ON containers (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_items_user_id_created_at ON items (user_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_items_user_id_parent ON items (user_id, parent_id, parent_category) WHERE deleted_at IS NULL;
+
+DROP TABLE entities;
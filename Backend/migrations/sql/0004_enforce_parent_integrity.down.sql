@@ -0,0 +1,12 @@
+-- Goes back to only checking that a parent exists.
+
+DROP TRIGGER entities_check_parent ON entities;
+DROP FUNCTION entities_check_parent();
+
+ALTER TABLE entities DROP CONSTRAINT entities_parent_category_check;
+
+ALTER TABLE entities DROP CONSTRAINT entities_parent_fkey;
+ALTER TABLE entities ADD CONSTRAINT entities_parent_fkey
+    FOREIGN KEY (parent_category, parent_id) REFERENCES entities (category, id) NOT VALID;
+
+ALTER TABLE entities DROP CONSTRAINT entities_category_id_user_id_key;
@@ -0,0 +1,45 @@
+-- A parent has to belong to the same user as its children, be of a category they can be placed under, and can't
+-- be soft deleted while it still has live children. Like entities_parent_fkey before it, the new constraints are
+-- NOT VALID so rows copied from the old tables don't block the migration, vault-admin orphans lists those.
+
+ALTER TABLE entities ADD CONSTRAINT entities_category_id_user_id_key UNIQUE (category, id, user_id);
+
+ALTER TABLE entities DROP CONSTRAINT entities_parent_fkey;
+ALTER TABLE entities ADD CONSTRAINT entities_parent_fkey
+    FOREIGN KEY (parent_category, parent_id, user_id) REFERENCES entities (category, id, user_id) NOT VALID;
+
+ALTER TABLE entities ADD CONSTRAINT entities_parent_category_check CHECK (
+    parent_category IS NULL
+    OR (category = 'room' AND parent_category = 'building')
+    OR (category = 'shelving_unit' AND parent_category = 'room')
+    OR (category = 'shelf' AND parent_category = 'shelving_unit')
+    OR (category = 'container' AND parent_category IN ('shelf', 'room'))
+    OR (category = 'item' AND parent_category IN ('container', 'shelf', 'room'))
+) NOT VALID;
+
+-- Soft deletes are updates, so the foreign key can't see them. restrict_violation tells the API to answer 409.
+CREATE FUNCTION entities_check_parent() RETURNS trigger AS $$
+BEGIN
+    IF NEW.deleted_at IS NULL AND NEW.parent_id IS NOT NULL AND EXISTS (
+        SELECT 1 FROM entities
+        WHERE category = NEW.parent_category AND id = NEW.parent_id AND deleted_at IS NOT NULL
+    ) THEN
+        RAISE EXCEPTION 'parent % % of % % has been deleted', NEW.parent_category, NEW.parent_id, NEW.category, NEW.id
+            USING ERRCODE = 'restrict_violation';
+    END IF;
+
+    IF TG_OP = 'UPDATE' AND NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL AND EXISTS (
+        SELECT 1 FROM entities
+        WHERE parent_category = NEW.category AND parent_id = NEW.id AND deleted_at IS NULL
+    ) THEN
+        RAISE EXCEPTION '% % still has children', NEW.category, NEW.id
+            USING ERRCODE = 'restrict_violation';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER entities_check_parent
+    BEFORE INSERT OR UPDATE OF parent_category, parent_id, deleted_at ON entities
+    FOR EACH ROW EXECUTE FUNCTION entities_check_parent();
@@ -0,0 +1,3 @@
+-- Every edit bumps the version, clients send it back in If-Match so concurrent edits can't overwrite each other.
+
+ALTER TABLE entities ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
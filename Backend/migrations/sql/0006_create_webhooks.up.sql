@@ -0,0 +1,32 @@
+-- Webhooks send entity changes to other systems. Every change a webhook wants becomes a row in
+-- webhook_deliveries, which is both the queue the dispatcher works through and the delivery log users can read.
+-- An empty events or categories list matches everything.
+
+CREATE TABLE webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events JSONB NOT NULL DEFAULT '[]',
+    categories JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_webhooks_user_id ON webhooks (user_id);
+
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id BIGINT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    last_status INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,137 @@
+package models
+
+import "fmt"
+
+// Modes a batch can run in.
+const (
+	// BatchAtomic saves nothing unless every operation succeeds.
+	BatchAtomic = "atomic"
+	// BatchIndependent saves every operation that succeeds and reports the rest.
+	BatchIndependent = "independent"
+)
+
+// MaxBatchOperations is the most operations a single batch can hold.
+const MaxBatchOperations = 100
+
+// BatchOperation is one create, update, move or delete in a batch. The fields of the entity are the same as in
+// EditEntityRequest. Creates can set a Ref that later operations name in ParentRef instead of a ParentID, so new
+// children can be placed under new parents. Version works like If-Match, 0 matches any version.
+type BatchOperation struct {
+	Op        string    `json:"op"`
+	Ref       string    `json:"ref"`
+	ParentRef string    `json:"parentRef"`
+	Version   NumericID `json:"version"`
+	EditEntityRequest
+}
+
+// BatchRequest is the body of a request to run several entity operations in one transaction.
+type BatchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult is the outcome of one operation of a batch. Data is the entity it touched, Error is the problem it
+// ran into.
+type BatchResult struct {
+	Index  int         `json:"index"`
+	Op     string      `json:"op"`
+	Ref    string      `json:"ref,omitempty"`
+	Status int         `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  interface{} `json:"error,omitempty"`
+}
+
+// BatchResponse lists the result of every operation of a batch, in the order they were sent.
+type BatchResponse struct {
+	Mode    string        `json:"mode"`
+	Results []BatchResult `json:"results"`
+}
+
+// Validate returns every problem with the request body. The fields of each operation are reported as
+// operations[i].field.
+func (body BatchRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.Mode != "" && body.Mode != BatchAtomic && body.Mode != BatchIndependent {
+		errs.Add("mode", ValidationInvalidChoice, fmt.Sprintf("Mode must be %v or %v.", BatchAtomic, BatchIndependent))
+	}
+
+	if len(body.Operations) == 0 {
+		errs.Add("operations", ValidationRequired, "Missing operations")
+	} else if len(body.Operations) > MaxBatchOperations {
+		errs.Add("operations", ValidationInvalidType, fmt.Sprintf("A batch can have at most %d operations.", MaxBatchOperations))
+	}
+
+	// refs maps the ref of every create seen so far to the category of the entity it creates.
+	refs := map[string]string{}
+	for i, op := range body.Operations {
+		for _, fieldErr := range op.validate(refs) {
+			errs.Add(fmt.Sprintf("operations[%d].%s", i, fieldErr.Field), fieldErr.Code, fieldErr.Message)
+		}
+
+		if op.Op == "create" && op.Ref != "" {
+			refs[op.Ref] = op.Category
+		}
+	}
+
+	return errs
+}
+
+// validate returns the problems with one operation, given the refs of the creates before it.
+func (op BatchOperation) validate(refs map[string]string) ValidationErrors {
+	errs := ValidationErrors{}
+	body := op.CreateEntityRequest
+
+	if op.Ref != "" {
+		if op.Op != "create" {
+			errs.Add("ref", ValidationInvalidChoice, "Only creates can have a ref.")
+		} else if _, taken := refs[op.Ref]; taken {
+			errs.Add("ref", ValidationInvalidChoice, fmt.Sprintf("Ref %v is already used by an earlier create.", op.Ref))
+		}
+	}
+
+	if op.ParentRef != "" {
+		category, ok := refs[op.ParentRef]
+		switch {
+		case !ok:
+			errs.Add("parentRef", ValidationInvalidChoice, fmt.Sprintf("Ref %v doesn't name an earlier create.", op.ParentRef))
+		case body.ParentID.Set:
+			errs.Add("parentRef", ValidationInvalidChoice, "Send either parentID or parentRef.")
+		case body.ParentCategory != "" && body.ParentCategory != category:
+			errs.Add("parentCategory", ValidationInvalidParent, fmt.Sprintf("Ref %v creates a %v.", op.ParentRef, category))
+		}
+
+		// The parent doesn't have an ID yet, stand in for it so the rest of the placement is checked as usual.
+		body.ParentID = NumericID{Set: true, Valid: true}
+	}
+
+	switch op.Op {
+	case "create":
+		errs = append(errs, body.Validate()...)
+		return errs
+	case "update":
+		errs = append(errs, validateNumber("id", "ID", op.ID)...)
+		errs = append(errs, body.Validate()...)
+	case "move":
+		errs = append(errs, validateNumber("id", "ID", op.ID)...)
+		errs = append(errs, body.validatePlacement()...)
+		if body.Category == "building" {
+			errs.Add("category", ValidationInvalidChoice, "Buildings can't be moved.")
+		}
+	case "delete":
+		errs = append(errs, validateNumber("id", "ID", op.ID)...)
+		if body.Category == "" {
+			errs.Add("category", ValidationRequired, "Missing category")
+		} else if _, ok := ParentCategories[body.Category]; !ok {
+			errs.Add("category", ValidationInvalidChoice, fmt.Sprintf("Invalid category %v.", body.Category))
+		}
+	case "":
+		errs.Add("op", ValidationRequired, "Missing op")
+		return errs
+	default:
+		errs.Add("op", ValidationInvalidChoice, "Op must be create, update, move or delete.")
+		return errs
+	}
+
+	return append(errs, validateNumber("version", "Version", op.Version)...)
+}
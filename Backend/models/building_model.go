@@ -1,8 +1,8 @@
 // Package models provides all the various models for our ORM.
 package models
 
-// Building describes our building table and objects.
+// Building describes a building as the API returns it.
 type Building struct {
-	Entity  Entity `gorm:"embedded"`
+	Entity  Entity
 	Address *string
 }
@@ -1,8 +1,8 @@
 // Package models provides all the various models for our ORM.
 package models
 
-// Container describes our room table and objects.
+// Container describes a container as the API returns it.
 type Container struct {
-	Entity Entity `gorm:"embedded"`
-	Parent Parent `gorm:"embedded"`
+	Entity Entity
+	Parent Parent
 }
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of change sent on the event stream.
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+	EventMove   = "move"
+	EventDelete = "delete"
+)
+
+// EntityEvent is a change to one of a user's entities, as sent on the event stream. ID is given by the stream the
+// event is kept in and is what clients send back in Last-Event-ID. Entity is the entity after the change, or as it
+// was when it was deleted.
+type EntityEvent struct {
+	ID       string      `json:"eventId,omitempty"`
+	Type     string      `json:"type"`
+	Category string      `json:"category"`
+	EntityID uint64      `json:"id"`
+	Version  uint64      `json:"version"`
+	Entity   interface{} `json:"entity"`
+	At       time.Time   `json:"at"`
+}
+
+// NewEntityEvent describes a change of the given type to record.
+func NewEntityEvent(eventType string, record *EntityRecord) EntityEvent {
+	return EntityEvent{
+		Type:     eventType,
+		Category: record.Category,
+		EntityID: record.Entity.ID,
+		Version:  record.Entity.Version,
+		Entity:   record.View(),
+		At:       time.Now().UTC(),
+	}
+}
+
+// EventIDBefore reports whether event ID a came before b. Both are Redis stream IDs, a millisecond timestamp and a
+// sequence number joined by a dash. IDs that can't be read come before everything.
+func EventIDBefore(a string, b string) bool {
+	aTime, aSeq, aOK := splitEventID(a)
+	bTime, bSeq, bOK := splitEventID(b)
+	if !aOK || !bOK {
+		return !aOK && bOK
+	}
+
+	return aTime < bTime || (aTime == bTime && aSeq < bSeq)
+}
+
+// ValidEventID reports whether id is a Redis stream ID.
+func ValidEventID(id string) bool {
+	_, _, ok := splitEventID(id)
+	return ok
+}
+
+func splitEventID(id string) (uint64, uint64, bool) {
+	timePart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	ms, err := strconv.ParseUint(timePart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return ms, seq, true
+}
@@ -9,11 +9,13 @@ import (
 
 // Entity describes the attributes all entities have in common
 type Entity struct {
-	ID        uint64
+	ID        uint64 `gorm:"primaryKey"`
 	Name      string
 	Notes     *string
 	UserID    string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt
+	// Version goes up by one on every edit, it is the ETag of the entity.
+	Version uint64
 }
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Categories lists every category of entity from the top of the hierarchy down.
+var Categories = []string{"building", "room", "shelving_unit", "shelf", "container", "item"}
+
+// EntityRecord describes our entities table, which holds every category of entity. Entities are identified by
+// their category and ID together, so the IDs the separate category tables handed out stay valid.
+type EntityRecord struct {
+	Category       string `gorm:"primaryKey"`
+	Entity         Entity `gorm:"embedded"`
+	ParentCategory *string
+	ParentID       *uint64
+	Attributes     EntityAttributes
+}
+
+// TableName tells GORM which table entity records live in.
+func (EntityRecord) TableName() string {
+	return "entities"
+}
+
+// EntityAttributes holds the fields only some categories of entity have. It is stored as JSONB.
+type EntityAttributes struct {
+	Address *string `json:"address,omitempty"`
+}
+
+// Value encodes the attributes for the database.
+func (attributes EntityAttributes) Value() (driver.Value, error) {
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan decodes the attributes from the database.
+func (attributes *EntityAttributes) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*attributes = EntityAttributes{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, attributes)
+	case string:
+		return json.Unmarshal([]byte(data), attributes)
+	default:
+		return fmt.Errorf("unsupported type %T for entity attributes", value)
+	}
+}
+
+// NewEntityRecord builds the record for an entity of category. It reports false when the category doesn't exist.
+// Buildings keep the address and drop the parent, every other category does the opposite.
+func NewEntityRecord(category string, entity Entity, parent Parent, address *string) (*EntityRecord, bool) {
+	if _, ok := ParentCategories[category]; !ok {
+		return nil, false
+	}
+
+	record := &EntityRecord{Category: category, Entity: entity}
+	if category == "building" {
+		record.Attributes.Address = address
+	} else {
+		record.SetParent(parent)
+	}
+
+	return record, true
+}
+
+// Parent returns the parent of the entity, or the zero Parent when it has none.
+func (record EntityRecord) Parent() Parent {
+	if record.ParentCategory == nil || record.ParentID == nil {
+		return Parent{}
+	}
+
+	return Parent{ParentID: *record.ParentID, ParentCategory: *record.ParentCategory}
+}
+
+// SetParent places the entity under parent, or at the top when parent is the zero Parent.
+func (record *EntityRecord) SetParent(parent Parent) {
+	if parent.ParentCategory == "" || parent.ParentID == 0 {
+		record.ParentCategory, record.ParentID = nil, nil
+		return
+	}
+
+	record.ParentCategory, record.ParentID = &parent.ParentCategory, &parent.ParentID
+}
+
+// Request returns the entity as the body of a request that would create it. PATCH requests are applied to it.
+func (record EntityRecord) Request() CreateEntityRequest {
+	body := CreateEntityRequest{
+		Name:     record.Entity.Name,
+		Notes:    record.Entity.Notes,
+		Category: record.Category,
+		Address:  record.Attributes.Address,
+	}
+
+	if parent := record.Parent(); parent.ParentCategory != "" {
+		body.ParentID = NumericID{Value: parent.ParentID, Set: true, Valid: true}
+		body.ParentCategory = parent.ParentCategory
+	}
+
+	return body
+}
+
+// View returns the entity in the shape the API has always used for its category.
+func (record EntityRecord) View() interface{} {
+	switch record.Category {
+	case "building":
+		return &Building{Entity: record.Entity, Address: record.Attributes.Address}
+	case "room":
+		return &Room{Entity: record.Entity, Parent: record.Parent()}
+	case "shelving_unit":
+		return &ShelvingUnit{Entity: record.Entity, Parent: record.Parent()}
+	case "shelf":
+		return &Shelf{Entity: record.Entity, Parent: record.Parent()}
+	case "container":
+		return &Container{Entity: record.Entity, Parent: record.Parent()}
+	default:
+		return &Item{Entity: record.Entity, Parent: record.Parent()}
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ParentCategories lists the categories each category of entity can be placed under.
+var ParentCategories = map[string][]string{
+	"building":      {},
+	"room":          {"building"},
+	"shelving_unit": {"room"},
+	"shelf":         {"shelving_unit"},
+	"container":     {"shelf", "room"},
+	"item":          {"container", "shelf", "room"},
+}
+
+// CreateEntityRequest is the body of a request to create an entity.
+type CreateEntityRequest struct {
+	Name           string    `json:"name"`
+	Notes          *string   `json:"notes"`
+	Category       string    `json:"category"`
+	Address        *string   `json:"address"`
+	ParentID       NumericID `json:"parentID"`
+	ParentCategory string    `json:"parentCategory"`
+}
+
+// EditEntityRequest is the body of a request to replace an entity.
+type EditEntityRequest struct {
+	ID NumericID `json:"id"`
+	CreateEntityRequest
+}
+
+// Validate returns every problem with the request body.
+func (body CreateEntityRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.Name == "" {
+		errs.Add("name", ValidationRequired, "Missing name")
+	}
+
+	return append(errs, body.validatePlacement()...)
+}
+
+// validatePlacement returns the problems with the category of the entity and the parent it is placed under.
+func (body CreateEntityRequest) validatePlacement() ValidationErrors {
+	errs := ValidationErrors{}
+
+	validParents, validCategory := ParentCategories[body.Category]
+	if body.Category == "" {
+		errs.Add("category", ValidationRequired, "Missing category")
+	} else if !validCategory {
+		errs.Add("category", ValidationInvalidChoice, fmt.Sprintf("Invalid category %v.", body.Category))
+	}
+
+	if body.Category == "building" {
+		return errs
+	}
+
+	if !body.ParentID.Set {
+		errs.Add("parentID", ValidationRequired, "Missing parent id")
+	} else if !body.ParentID.Valid {
+		errs.Add("parentID", ValidationInvalidType, "Parent ID must be type integer")
+	}
+
+	if body.ParentCategory == "" {
+		errs.Add("parentCategory", ValidationRequired, "Missing parent category")
+	} else if validCategory && !slices.Contains(validParents, body.ParentCategory) {
+		errs.Add("parentCategory", ValidationInvalidParent, "Invalid parent.")
+	}
+
+	return errs
+}
+
+// Validate returns every problem with the request body.
+func (body EditEntityRequest) Validate() ValidationErrors {
+	errs := validateNumber("id", "ID", body.ID)
+
+	return append(errs, body.CreateEntityRequest.Validate()...)
+}
+
+// validateNumber returns the problem with a required numeric field, if there is one.
+func validateNumber(field string, label string, value NumericID) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if !value.Set {
+		errs.Add(field, ValidationRequired, "Missing "+strings.ToLower(label))
+	} else if !value.Valid {
+		errs.Add(field, ValidationInvalidType, label+" must be type integer")
+	}
+
+	return errs
+}
@@ -7,4 +7,5 @@ type GetEntitiesEntity struct {
 	Parent   []GetEntitiesParentData
 	Notes    *string
 	Address  *string
+	Version  uint64
 }
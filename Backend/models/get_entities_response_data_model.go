@@ -8,4 +8,5 @@ type GetEntitiesResponseData struct {
 	ParentCategory string
 	Notes          *string
 	Address        *string
+	Version        uint64
 }
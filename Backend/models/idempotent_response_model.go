@@ -0,0 +1,13 @@
+package models
+
+import "net/http"
+
+// IdempotentResponse is what is kept for a request sent with an Idempotency-Key, so that retries of it get the same
+// answer instead of running again. Done is false while the first request is still being handled.
+type IdempotentResponse struct {
+	Fingerprint string      `json:"fingerprint"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
@@ -1,8 +1,8 @@
 // Package models provides all the various models for our ORM.
 package models
 
-// Item describes our room table and objects.
+// Item describes an item as the API returns it.
 type Item struct {
-	Entity Entity `gorm:"embedded"`
-	Parent Parent `gorm:"embedded"`
+	Entity Entity
+	Parent Parent
 }
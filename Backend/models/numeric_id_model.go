@@ -0,0 +1,57 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// NumericID is an id field in a request body. It accepts a JSON number, a numeric string or null,
+// and records a malformed value instead of failing the whole decode so it can be reported as a field error.
+type NumericID struct {
+	Value uint64
+	Set   bool
+	Valid bool
+}
+
+// UnmarshalJSON decodes a number, numeric string or null into the id.
+func (id *NumericID) UnmarshalJSON(data []byte) error {
+	*id = NumericID{}
+
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	raw := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			id.Set = true
+			return nil
+		}
+		raw = text
+	}
+
+	if raw == "" {
+		return nil
+	}
+
+	id.Set = true
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	id.Value = value
+	id.Valid = true
+	return nil
+}
+
+// MarshalJSON encodes the id as a JSON number, or null when it was never set.
+func (id NumericID) MarshalJSON() ([]byte, error) {
+	if !id.Set || !id.Valid {
+		return []byte("null"), nil
+	}
+
+	return []byte(strconv.FormatUint(id.Value, 10)), nil
+}
@@ -0,0 +1,11 @@
+package models
+
+// Orphan is an entity whose parent has been deleted or never existed.
+type Orphan struct {
+	Category       string
+	ID             uint64
+	Name           string
+	UserID         string
+	ParentID       uint64
+	ParentCategory string
+}
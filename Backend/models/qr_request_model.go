@@ -0,0 +1,28 @@
+package models
+
+import "fmt"
+
+// GenerateQRRequest is the body of a request to generate a QR code for an entity.
+type GenerateQRRequest struct {
+	Category string    `json:"category"`
+	ID       NumericID `json:"id"`
+}
+
+// Validate returns every problem with the request body.
+func (body GenerateQRRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if _, validCategory := ParentCategories[body.Category]; body.Category == "" {
+		errs.Add("category", ValidationRequired, "Missing category")
+	} else if !validCategory {
+		errs.Add("category", ValidationInvalidChoice, fmt.Sprintf("Invalid category %v.", body.Category))
+	}
+
+	if !body.ID.Set {
+		errs.Add("id", ValidationRequired, "Missing id")
+	} else if !body.ID.Valid {
+		errs.Add("id", ValidationInvalidType, "ID must be type integer")
+	}
+
+	return errs
+}
@@ -0,0 +1,25 @@
+package models
+
+// Statuses a dependency, or the API as a whole, can be in.
+const (
+	HealthUp       = "up"
+	HealthDown     = "down"
+	HealthDegraded = "degraded"
+)
+
+// DependencyHealth is how a dependency answered a readiness check. The API is only unready when a required
+// dependency is down.
+type DependencyHealth struct {
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMS float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness is the answer to /readyz: "up", "degraded" when an optional dependency is down, or "down" when a
+// required one is or the server is shutting down. Dependencies are only listed for callers with the metrics token.
+type Readiness struct {
+	Status       string                      `json:"status"`
+	ShuttingDown bool                        `json:"shuttingDown,omitempty"`
+	Dependencies map[string]DependencyHealth `json:"dependencies,omitempty"`
+}
@@ -1,8 +1,8 @@
 // Package models provides all the various models for our ORM.
 package models
 
-// Room describes our room table and objects.
+// Room describes a room as the API returns it.
 type Room struct {
-	Entity Entity `gorm:"embedded"`
-	Parent Parent `gorm:"embedded"`
+	Entity Entity
+	Parent Parent
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Session describes a signed in device, tracked by the origin_jti Cognito stamps on every access token it issues.
+type Session struct {
+	ID         string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	Current    bool
+}
@@ -1,8 +1,8 @@
 // Package models provides all the various models for our ORM.
 package models
 
-// Shelf describes our shelf table and objects.
+// Shelf describes a shelf as the API returns it.
 type Shelf struct {
-	Entity Entity `gorm:"embedded"`
-	Parent Parent `gorm:"embedded"`
+	Entity Entity
+	Parent Parent
 }
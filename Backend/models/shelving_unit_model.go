@@ -1,8 +1,8 @@
 // Package models provides all the various models for our ORM.
 package models
 
-// ShelvingUnit describes our shelving_unit table and objects.
+// ShelvingUnit describes a shelving unit as the API returns it.
 type ShelvingUnit struct {
-	Entity Entity `gorm:"embedded"`
-	Parent Parent `gorm:"embedded"`
+	Entity Entity
+	Parent Parent
 }
@@ -0,0 +1,186 @@
+package models
+
+import "fmt"
+
+// SignUpRequest is the body of a request to register a new user.
+type SignUpRequest struct {
+	UserEmail string `json:"userEmail"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Birthday  string `json:"birthday"`
+}
+
+// Validate returns every problem with the request body.
+func (body SignUpRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.UserEmail == "" {
+		errs.Add("userEmail", ValidationRequired, "Missing user name")
+	}
+
+	if body.Password == "" {
+		errs.Add("password", ValidationRequired, "Missing password")
+	}
+
+	if body.FirstName == "" {
+		errs.Add("firstName", ValidationRequired, "Missing first name")
+	}
+
+	if body.LastName == "" {
+		errs.Add("lastName", ValidationRequired, "Missing last name")
+	}
+
+	if body.Birthday == "" {
+		errs.Add("birthday", ValidationRequired, "Missing birthday")
+	}
+
+	return errs
+}
+
+// ConfirmSignUpRequest is the body of a request to confirm a user's email.
+type ConfirmSignUpRequest struct {
+	UserEmail        string `json:"userEmail"`
+	ConfirmationCode string `json:"confirmationCode"`
+}
+
+// Validate returns every problem with the request body.
+func (body ConfirmSignUpRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.ConfirmationCode == "" {
+		errs.Add("confirmationCode", ValidationRequired, "Missing confirmation code")
+	}
+
+	if body.UserEmail == "" {
+		errs.Add("userEmail", ValidationRequired, "Missing user email")
+	}
+
+	return errs
+}
+
+// SignInRequest is the body of a request to sign in.
+type SignInRequest struct {
+	UserEmail string `json:"userEmail"`
+	Password  string `json:"password"`
+}
+
+// Validate returns every problem with the request body.
+func (body SignInRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.UserEmail == "" {
+		errs.Add("userEmail", ValidationRequired, "Missing user email")
+	}
+
+	if body.Password == "" {
+		errs.Add("password", ValidationRequired, "Missing password")
+	}
+
+	return errs
+}
+
+// ChallengeRequest is the body of a request answering a sign in challenge.
+type ChallengeRequest struct {
+	UserEmail     string `json:"userEmail"`
+	ChallengeName string `json:"challengeName"`
+	Session       string `json:"session"`
+	Code          string `json:"code"`
+	NewPassword   string `json:"newPassword"`
+}
+
+// Validate returns every problem with the request body.
+func (body ChallengeRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.UserEmail == "" {
+		errs.Add("userEmail", ValidationRequired, "Missing user email")
+	}
+
+	if body.Session == "" {
+		errs.Add("session", ValidationRequired, "Missing session")
+	}
+
+	switch body.ChallengeName {
+	case "":
+		errs.Add("challengeName", ValidationRequired, "Missing challenge name")
+	case "SOFTWARE_TOKEN_MFA", "SMS_MFA":
+		if body.Code == "" {
+			errs.Add("code", ValidationRequired, "Missing code")
+		}
+	case "NEW_PASSWORD_REQUIRED":
+		if body.NewPassword == "" {
+			errs.Add("newPassword", ValidationRequired, "Missing new password")
+		}
+	case "MFA_SETUP":
+	default:
+		errs.Add("challengeName", ValidationInvalidChoice, fmt.Sprintf("Unsupported challenge: %v", body.ChallengeName))
+	}
+
+	return errs
+}
+
+// AssociateSoftwareTokenRequest is the body of a request to start TOTP MFA enrolment.
+type AssociateSoftwareTokenRequest struct {
+	Session string `json:"session"`
+}
+
+// Validate returns every problem with the request body.
+func (body AssociateSoftwareTokenRequest) Validate() ValidationErrors {
+	return ValidationErrors{}
+}
+
+// VerifySoftwareTokenRequest is the body of a request to finish TOTP MFA enrolment.
+type VerifySoftwareTokenRequest struct {
+	Session    string `json:"session"`
+	Code       string `json:"code"`
+	DeviceName string `json:"deviceName"`
+}
+
+// Validate returns every problem with the request body.
+func (body VerifySoftwareTokenRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.Code == "" {
+		errs.Add("code", ValidationRequired, "Missing code")
+	}
+
+	return errs
+}
+
+// RefreshRequest is the body of a request to refresh a user's tokens.
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	IDToken      string `json:"idToken"`
+}
+
+// Validate returns every problem with the request body.
+func (body RefreshRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.RefreshToken == "" {
+		errs.Add("refreshToken", ValidationRequired, "Missing refresh token")
+	}
+
+	if body.IDToken == "" {
+		errs.Add("idToken", ValidationRequired, "Missing id token")
+	}
+
+	return errs
+}
+
+// LogOutRequest is the body of a request to sign out.
+type LogOutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Validate returns every problem with the request body.
+func (body LogOutRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.RefreshToken == "" {
+		errs.Add("refreshToken", ValidationRequired, "Missing refresh token")
+	}
+
+	return errs
+}
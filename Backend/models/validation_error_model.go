@@ -0,0 +1,39 @@
+package models
+
+import "strings"
+
+// Validation error codes returned to the client so it can react to each field without parsing messages.
+const (
+	ValidationRequired      = "required"
+	ValidationInvalidType   = "invalid_type"
+	ValidationInvalidChoice = "invalid_choice"
+	ValidationInvalidParent = "invalid_parent"
+	ValidationReadOnly      = "read_only"
+	ValidationUnknownField  = "unknown_field"
+	ValidationKeyReused     = "key_reused"
+)
+
+// FieldError describes a single problem with one field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every field error found in a request body.
+type ValidationErrors []FieldError
+
+// Add appends a field error to the list.
+func (errs *ValidationErrors) Add(field string, code string, message string) {
+	*errs = append(*errs, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Error joins all of the messages so ValidationErrors can be logged like any other error.
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		messages[i] = fieldErr.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Statuses a webhook delivery can be in.
+const (
+	// DeliveryPending is waiting for its first or next attempt.
+	DeliveryPending = "pending"
+	// DeliverySucceeded got a 2xx response.
+	DeliverySucceeded = "succeeded"
+	// DeliveryFailed ran out of attempts.
+	DeliveryFailed = "failed"
+)
+
+// EventTypes lists every type of event webhooks can be filtered on.
+var EventTypes = []string{EventCreate, EventUpdate, EventMove, EventDelete}
+
+// Webhook is a URL a user wants their entity changes sent to. Events and Categories filter which changes are
+// sent, empty lists match everything. Secret signs every delivery and is only sent back when the webhook is
+// created.
+type Webhook struct {
+	ID         uint64     `json:"id" gorm:"primaryKey"`
+	UserID     string     `json:"-"`
+	URL        string     `json:"url"`
+	Secret     string     `json:"secret,omitempty"`
+	Events     StringList `json:"events"`
+	Categories StringList `json:"categories"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// WebhookDelivery is one event queued for a webhook, along with how sending it has gone so far.
+type WebhookDelivery struct {
+	ID            uint64         `json:"id" gorm:"primaryKey"`
+	WebhookID     uint64         `json:"webhookId"`
+	EventType     string         `json:"eventType"`
+	Payload       WebhookPayload `json:"payload"`
+	Status        string         `json:"status"`
+	Attempts      int            `json:"attempts"`
+	NextAttemptAt *time.Time     `json:"nextAttemptAt,omitempty"`
+	LastStatus    int            `json:"lastStatus,omitempty"`
+	LastError     string         `json:"lastError,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	DeliveredAt   *time.Time     `json:"deliveredAt,omitempty"`
+}
+
+// StringList is a list of strings stored as a JSONB array.
+type StringList []string
+
+// Value encodes the list for the database.
+func (list StringList) Value() (driver.Value, error) {
+	if list == nil {
+		list = StringList{}
+	}
+
+	data, err := json.Marshal([]string(list))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan decodes the list from the database.
+func (list *StringList) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*list = StringList{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, list)
+	case string:
+		return json.Unmarshal([]byte(data), list)
+	default:
+		return fmt.Errorf("unsupported type %T for string list", value)
+	}
+}
+
+// WebhookPayload is the event a delivery sends, stored as JSONB.
+type WebhookPayload struct {
+	EntityEvent
+}
+
+// Value encodes the payload for the database.
+func (payload WebhookPayload) Value() (driver.Value, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan decodes the payload from the database.
+func (payload *WebhookPayload) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, payload)
+	case string:
+		return json.Unmarshal([]byte(data), payload)
+	default:
+		return fmt.Errorf("unsupported type %T for webhook payload", value)
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"willowsuite-vault/helpers"
+)
+
+// CreateWebhookRequest is the body of a request to subscribe a URL to entity changes.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	Events     []string `json:"events"`
+	Categories []string `json:"categories"`
+}
+
+// Validate returns every problem with the request body.
+func (body CreateWebhookRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if body.URL == "" {
+		errs.Add("url", ValidationRequired, "Missing url")
+	} else if target, err := url.Parse(body.URL); err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		errs.Add("url", ValidationInvalidType, "URL must be an absolute http or https URL.")
+	} else if !publicHost(target.Hostname()) {
+		errs.Add("url", ValidationInvalidType, "URL must point to a public address.")
+	}
+
+	for i, event := range body.Events {
+		if !slices.Contains(EventTypes, event) {
+			errs.Add(fmt.Sprintf("events[%d]", i), ValidationInvalidChoice, fmt.Sprintf("Invalid event %v.", event))
+		}
+	}
+
+	for i, category := range body.Categories {
+		if !slices.Contains(Categories, category) {
+			errs.Add(fmt.Sprintf("categories[%d]", i), ValidationInvalidChoice, fmt.Sprintf("Invalid category %v.", category))
+		}
+	}
+
+	return errs
+}
+
+// publicHost reports whether host could be on the internet. Names are checked again once they are resolved, when
+// the delivery is sent, this only turns away the ones that plainly aren't.
+func publicHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return helpers.PublicAddress(addr)
+	}
+
+	return true
+}
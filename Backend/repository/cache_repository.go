@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/logger"
+)
+
+// The cached reads below never fail a request. When the cache can't be reached the read goes to the database and
+// its result isn't cached.
+
+// entitiesGenerationKey holds a counter that goes up whenever a user's entities change. Every cached read of a
+// user's entities has the generation it was made at in its key, so moving the counter on retires all of them at
+// once, whatever shape their keys have. The retired entries expire on their own.
+func entitiesGenerationKey(userID string) string {
+	key, _ := json.Marshal(cache.CacheKey{User: userID, Function: "EntitiesGeneration"})
+	return string(key)
+}
+
+// entitiesGeneration returns the current generation of a user's entities, 0 until they first change. An error
+// means the cache can't be used for this read.
+func (repo Repository) entitiesGeneration(ctx context.Context, userID string) (int64, error) {
+	value, err := repo.Cache.Get(ctx, entitiesGenerationKey(userID))
+	if errors.Is(err, cache.ErrMiss) {
+		return 0, nil
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Warnf("error reading from the cache, reading from the database instead: %v", err)
+		return 0, err
+	}
+
+	generation, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("error reading the generation of cached entities: %v", err)
+	}
+
+	return generation, err
+}
+
+// getCached reads a cached value and counts the read as a hit or a miss for function. A missing key reads as "".
+// An error means the cache can't be used for this read.
+func (repo Repository) getCached(ctx context.Context, function string, key string) (string, error) {
+	value, err := repo.Cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrMiss) {
+		cache.CountLookup(function, false)
+		return "", nil
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Warnf("error reading from the cache, reading from the database instead: %v", err)
+		cache.CountLookup(function, false)
+		return "", err
+	}
+
+	cache.CountLookup(function, true)
+	return value, nil
+}
+
+// setCached caches value under key for ttl. A value that can't be cached is only logged.
+func (repo Repository) setCached(ctx context.Context, key string, value string, ttl time.Duration) {
+	if err := repo.Cache.Set(ctx, key, value, ttl); err != nil {
+		logger.Ctx(ctx).Warnf("error writing to the cache: %v", err)
+	}
+}
+
+// FlushEntities retires every cached read of a user's entities by moving them on to a new generation. It costs one
+// INCR however much is cached.
+func (repo Repository) FlushEntities(ctx context.Context, userID string) {
+	if _, err := repo.Cache.Incr(ctx, entitiesGenerationKey(userID)); err != nil {
+		logger.Ctx(ctx).Errorf("error clearing cache: %v", err)
+	}
+}
+
+// guarded runs call against Redis behind the cache's circuit breaker, so the Redis calls made on every request fail
+// at once rather than each waiting for a timeout while Redis is down. Without a breaker, call just runs. call must
+// only return an error when Redis failed, not for a missing key.
+func (repo Repository) guarded(ctx context.Context, call func(ctx context.Context) error) error {
+	if breaker, ok := repo.Cache.(*cache.Breaker); ok {
+		return breaker.Do(ctx, call)
+	}
+
+	return call(ctx)
+}
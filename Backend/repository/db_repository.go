@@ -10,65 +10,153 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"willowsuite-vault/apperrors"
 	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/infra/tracing"
 	"willowsuite-vault/models"
 
+	"github.com/jackc/pgconn"
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"gorm.io/gorm"
 )
 
-// Repository is the type we use to pass the infrastructure components to the functions
+// Repository is the type we use to pass the infrastructure components to the functions. Cache holds reads that can
+// be made again from the database, while Redis holds state that can't, such as sessions and events.
 type Repository struct {
 	Database *gorm.DB
-	Cache    *redis.Client
+	Cache    cache.Cache
+	Redis    *redis.Client
 }
 
 // GetEntitiesCacheKey is an extension of cachekey that represents the structure of the keys in our cache for the paginated getentities data.
 type GetEntitiesCacheKey struct {
-	CacheKey cache.CacheKey
-	Offset   string
-	Limit    string
-	Search   string
-	Filters  []string
+	CacheKey   cache.CacheKey
+	Generation int64
+	Offset     string
+	Limit      string
+	Search     string
+	Filters    []string
 }
 
 // CountEntitiesCacheKey is an extension of cachekey that represents the structure of the keys in our cache for the count entities data.
 type CountEntitiesCacheKey struct {
-	CacheKey cache.CacheKey
-	Search   string
-	Filters  []string
+	CacheKey   cache.CacheKey
+	Generation int64
+	Search     string
+	Filters    []string
 }
 
-type getEntitiesTables struct {
-	category    string
-	tableName   string
-	tableWeight int
+// GetParentsCacheKey is an extension of cachekey that represents the structure of the keys in our cache for the possible parents of a category.
+type GetParentsCacheKey struct {
+	CacheKey   cache.CacheKey
+	Generation int64
+	Category   string
 }
 
-type countEntitiesTables struct {
-	category  string
-	tableName string
+// Transaction runs fn with a repository whose queries all belong to one database transaction, which is committed
+// when fn returns nil and rolled back otherwise. Calling Transaction on that repository again makes a savepoint,
+// so a failed step can be rolled back without losing the rest of the transaction.
+func (repo Repository) Transaction(ctx context.Context, fn func(repo Repository) error) error {
+	return repo.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(Repository{Database: tx, Cache: repo.Cache, Redis: repo.Redis})
+	})
 }
 
-// Save is used to create a new record in the DB
-func (repo Repository) Save(model interface{}) interface{} {
-	err := repo.Database.Save(model).Error
+// Save is used to create a new record in the DB. The parent has to exist, belong to the same user and not be
+// deleted. It is locked until the entity is saved so it can't be deleted in the meantime.
+func (repo Repository) Save(ctx context.Context, model *models.EntityRecord) error {
+	model.Entity.Version = 1
+
+	err := repo.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkParent(tx, model); err != nil {
+			return err
+		}
+
+		return tx.Save(model).Error
+	})
+
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
 
 	if err != nil {
-		logger.Errorf("error, not save data %v", err)
+		logger.Ctx(ctx).Errorf("error, not save data %v", err)
+		return integrityError(err, "Error saving entity.", model.Parent())
 	}
 
-	return err
+	return nil
+}
+
+// Update replaces the name, notes, parent and attributes of an existing record, as long as it is still at version.
+// A version of 0 replaces whatever version is current. The new version is set on model, and the parent the record
+// had before is returned so callers can tell a move from an edit.
+func (repo Repository) Update(ctx context.Context, model *models.EntityRecord, version uint64) (models.Parent, error) {
+	var previous models.Parent
+
+	err := repo.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current []struct {
+			Version        uint64
+			ParentCategory string
+			ParentID       uint64
+		}
+		dbErr := tx.Raw(
+			`SELECT version, COALESCE(parent_category, '') AS parent_category, COALESCE(parent_id, 0) AS parent_id FROM entities `+
+				`WHERE user_id = ? AND category = ? AND id = ? AND deleted_at IS NULL FOR UPDATE`,
+			model.Entity.UserID, model.Category, model.Entity.ID,
+		).Scan(&current).Error
+		if dbErr != nil {
+			return dbErr
+		}
+
+		if len(current) == 0 {
+			return apperrors.NotFound("Entity not found.")
+		}
+
+		if version != 0 && version != current[0].Version {
+			return apperrors.PreconditionFailed("The entity has been changed since it was read.")
+		}
+
+		if err := checkParent(tx, model); err != nil {
+			return err
+		}
+
+		previous = models.Parent{ParentID: current[0].ParentID, ParentCategory: current[0].ParentCategory}
+		model.Entity.Version = current[0].Version + 1
+		return tx.Model(model).
+			Select("name", "notes", "updated_at", "parent_category", "parent_id", "attributes", "version").
+			Updates(model).Error
+	})
+
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return previous, err
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Errorf("error, not save data %v", err)
+		return previous, integrityError(err, "Error saving entity.", model.Parent())
+	}
+
+	return previous, nil
 }
 
 // GetOne is used to get a single record from the DB
-func (repo Repository) GetOne(model interface{}, userID string) interface{} {
-	err := repo.Database.Where("user_id = ?", userID).First(model).Error
-	return err
+func (repo Repository) GetOne(ctx context.Context, model *models.EntityRecord, userID string) error {
+	err := repo.reader(ctx).Where("user_id = ?", userID).First(model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.NotFound("Entity not found.")
+	}
+
+	if err != nil {
+		return apperrors.Internal("Error getting entity.", err)
+	}
+
+	return nil
 }
 
 // GetAllEntities returns all entities that belong to the user.
@@ -77,118 +165,50 @@ func (repo Repository) GetAllEntities(ctx context.Context, userID string, offset
 	stringLimit := strconv.Itoa(limit)
 	var data []models.GetEntitiesEntity
 
+	generation, cacheErr := repo.entitiesGeneration(ctx, userID)
+
 	cacheTTL := 5 * time.Minute
 	keyStructured := GetEntitiesCacheKey{
 		CacheKey: cache.CacheKey{
 			User:     userID,
 			Function: "GetAllEntities",
 		},
-		Offset:  stringOffset,
-		Limit:   stringLimit,
-		Search:  search,
-		Filters: filters,
+		Generation: generation,
+		Offset:     stringOffset,
+		Limit:      stringLimit,
+		Search:     search,
+		Filters:    filters,
 	}
 	key, jsonErr := json.Marshal(keyStructured)
 	if jsonErr != nil {
-		logger.Errorf("Error encoding Redis key: %v", jsonErr)
-		return nil, fmt.Errorf("JSON: %v", jsonErr)
+		logger.Ctx(ctx).Errorf("Error encoding Redis key: %v", jsonErr)
+		return nil, apperrors.Internal("Error encoding cache key.", jsonErr)
 	}
 
-	value, redisErr := repo.Cache.Get(ctx, string(key)).Result()
-	if redisErr != nil && !errors.Is(redisErr, redis.Nil) {
-		logger.Errorf("Error retriving entites from Redis: %v", redisErr)
-		return nil, fmt.Errorf("Redis: %v", redisErr)
+	value := ""
+	if cacheErr == nil {
+		value, cacheErr = repo.getCached(ctx, keyStructured.CacheKey.Function, string(key))
 	}
 
 	if value == "" {
 		var results []models.GetEntitiesResponseData
-		stringValues := []string{}
-		mainSQL := []string{}
-
-		searchSQL := ""
-		buildingSearchSQL := ""
-		addSearch := false
-
-		// Dynamically build search query
-		if search != "" {
-			search = strings.ToLower(search)
-			search = "%" + search + "%"
-			searchSQL = " AND (LOWER(name) LIKE ? OR LOWER(notes) LIKE ?)"
-			buildingSearchSQL = " AND (LOWER(name) LIKE ? OR LOWER(notes) LIKE ? OR LOWER(address) LIKE ?)"
-			addSearch = true
-		}
-
-		tables := []getEntitiesTables{
-			{"building", "buildings", 1},
-			{"room", "rooms", 2},
-			{"shelving_unit", "shelving_units", 3},
-			{"shelf", "shelves", 4},
-			{"container", "containers", 5},
-			{"item", "items", 6},
-		}
 
-		for _, table := range tables {
-			if len(filters) == 0 || slices.Contains(filters, table.category) {
-				query := ""
-				if table.category == "building" {
-					query = fmt.Sprintf(`(SELECT %d AS tableWeight, created_at, '%s' AS category, id, name, notes, address, 0 AS parent_id, ' ' AS parent_category FROM %s WHERE user_id = ? AND deleted_at IS NULL`, table.tableWeight, table.category, table.tableName)
-				} else {
-					query = fmt.Sprintf(`(SELECT %d AS tableWeight, created_at, '%s' AS category, id, name, notes, '' AS address, parent_id, parent_category FROM %s WHERE user_id = ? AND deleted_at IS NULL`, table.tableWeight, table.category, table.tableName)
-				}
-
-				stringValues = append(stringValues, userID)
-
-				if addSearch && table.category == "building" {
-					query += buildingSearchSQL
-					stringValues = append(stringValues, search, search, search)
-				} else if addSearch {
-					query += searchSQL
-					stringValues = append(stringValues, search, search)
-				}
-
-				query += ` ORDER BY created_at ASC LIMIT ?)`
-				stringValues = append(stringValues, stringLimit)
-
-				mainSQL = append(mainSQL, query)
-			}
-		}
-
-		// Union all dynamically built queries
-		unionQuery := strings.Join(mainSQL, " UNION ALL ")
-
-		// Default Order
-		if len(filters) == 0 || len(filters) > 1 {
-			unionQuery += " ORDER BY tableWeight"
-		}
-
-		// Add offset
-		unionQuery += " OFFSET ?"
-		stringValues = append(stringValues, stringOffset)
-
-		// Add union limit if more than one filter applied
-		if len(filters) == 0 || len(filters) > 1 {
-			unionQuery += " LIMIT ?"
-			stringValues = append(stringValues, stringLimit)
-		}
-
-		// Convert string slice to interface slice
-		values := make([]interface{}, len(stringValues))
-		for i, stringID := range stringValues {
-			values[i] = stringID
-		}
+		query, values := entitiesQuery(`SELECT category, id, name, notes, attributes->>'address' AS address, COALESCE(parent_id, 0) AS parent_id, COALESCE(parent_category, '') AS parent_category, version FROM entities`, userID, search, filters)
+		query += ` ORDER BY ` + categoryWeightSQL + `, created_at, id OFFSET ? LIMIT ?`
+		values = append(values, offset, limit)
 
 		// Run dynamically built query
-		dbErr := repo.Database.Raw(unionQuery, values...).Scan(&results).Error
+		dbErr := repo.reader(ctx).Raw(query, values...).Scan(&results).Error
 		if dbErr != nil {
-			logger.Errorf("error executing query: %v", dbErr)
-			return nil, fmt.Errorf("DB: %v", dbErr)
+			logger.Ctx(ctx).Errorf("error executing query: %v", dbErr)
+			return nil, apperrors.Internal("Error getting entities.", dbErr)
 		}
 
 		// Generate results
 		for _, entity := range results {
 			if entity.ParentID != 0 && entity.ParentCategory != "" {
 				var parents []models.GetEntitiesParentData
-				repo.getParents(entity.ParentID, entity.ParentCategory, userID, &parents)
+				repo.getParents(ctx, entity.ParentID, entity.ParentCategory, userID, &parents)
 
 				data = append(data, models.GetEntitiesEntity{
 					ID:       entity.ID,
@@ -196,6 +216,7 @@ func (repo Repository) GetAllEntities(ctx context.Context, userID string, offset
 					Category: entity.Category,
 					Parent:   parents,
 					Notes:    entity.Notes,
+					Version:  entity.Version,
 				})
 			} else {
 				var parents []models.GetEntitiesParentData
@@ -212,6 +233,7 @@ func (repo Repository) GetAllEntities(ctx context.Context, userID string, offset
 					Parent:   parents,
 					Address:  entity.Address,
 					Notes:    entity.Notes,
+					Version:  entity.Version,
 				})
 			}
 		}
@@ -219,15 +241,17 @@ func (repo Repository) GetAllEntities(ctx context.Context, userID string, offset
 		// Set cache
 		byteData, jsonErr := json.Marshal(data)
 		if jsonErr != nil {
-			logger.Errorf("error encoding data: %v", dbErr)
-			return nil, fmt.Errorf("Set Redis JSON: %v", jsonErr)
+			logger.Ctx(ctx).Errorf("error encoding data: %v", jsonErr)
+			return nil, apperrors.Internal("Error encoding entities.", jsonErr)
 		}
 
-		repo.Cache.Set(ctx, string(key), byteData, cacheTTL)
+		if cacheErr == nil {
+			repo.setCached(ctx, string(key), string(byteData), cacheTTL)
+		}
 	} else {
 		jsonErr := json.Unmarshal([]byte(value), &data)
 		if jsonErr != nil {
-			logger.Errorf("error encoding data: %v", jsonErr)
+			logger.Ctx(ctx).Errorf("error encoding data: %v", jsonErr)
 		}
 	}
 
@@ -238,91 +262,42 @@ func (repo Repository) GetAllEntities(ctx context.Context, userID string, offset
 func (repo Repository) CountEntities(ctx context.Context, userID string, search string, filters []string) int {
 	var entityCount int
 
+	generation, cacheErr := repo.entitiesGeneration(ctx, userID)
+
 	cacheTTL := 5 * time.Minute
 	keyStructured := CountEntitiesCacheKey{
 		CacheKey: cache.CacheKey{
 			User:     userID,
 			Function: "CountEntities",
 		},
-		Search:  search,
-		Filters: filters,
+		Generation: generation,
+		Search:     search,
+		Filters:    filters,
 	}
 
 	key, _ := json.Marshal(keyStructured)
-	value, redisErr := repo.Cache.Get(ctx, string(key)).Result()
-	if redisErr != nil && !errors.Is(redisErr, redis.Nil) {
-		logger.Errorf("error retriving entites from Redis: %v", redisErr)
-		return entityCount
+	value := ""
+	if cacheErr == nil {
+		value, cacheErr = repo.getCached(ctx, keyStructured.CacheKey.Function, string(key))
 	}
 
 	if value == "" {
-		searchSQL := ""
-		buildingSearchSQL := ""
-		addSearch := false
-
-		stringValues := []string{}
-		mainSQL := []string{}
-
-		if search != "" {
-			search = strings.ToLower(search)
-			search = "%" + search + "%"
-			searchSQL = "AND (LOWER(name) LIKE ? OR LOWER(notes) LIKE ?)"
-			buildingSearchSQL = "AND (LOWER(name) LIKE ? OR LOWER(notes) LIKE ? OR LOWER(address) LIKE ?)"
-			addSearch = true
-		}
-
-		tables := []countEntitiesTables{
-			{"building", "buildings"},
-			{"room", "rooms"},
-			{"shelving_unit", "shelving_units"},
-			{"shelf", "shelves"},
-			{"container", "containers"},
-			{"item", "items"},
-		}
-
-		for _, table := range tables {
-			if len(filters) == 0 || slices.Contains(filters, table.category) {
-				query := fmt.Sprintf(`(SELECT COUNT(*) FROM %s WHERE user_id = ? AND deleted_at IS NULL `, table.tableName)
-
-				stringValues = append(stringValues, userID)
+		query, values := entitiesQuery(`SELECT COUNT(*) FROM entities`, userID, search, filters)
 
-				if addSearch && table.category == "building" {
-					query += buildingSearchSQL
-					stringValues = append(stringValues, search, search, search)
-				} else if addSearch {
-					query += searchSQL
-					stringValues = append(stringValues, search, search)
-				}
-
-				query += `)`
-
-				mainSQL = append(mainSQL, query)
-			}
-		}
-
-		// Union all dynamically built queries
-		joinedQueries := strings.Join(mainSQL, " + ")
-
-		additionQuery := "SELECT " + joinedQueries + " AS EntityCount"
-
-		// Convert string slice to interface slice
-		values := make([]interface{}, len(stringValues))
-		for i, stringID := range stringValues {
-			values[i] = stringID
-		}
-
-		err := repo.Database.Raw(additionQuery, values...).Scan(&entityCount).Error
+		err := repo.reader(ctx).Raw(query, values...).Scan(&entityCount).Error
 		if err != nil {
-			logger.Errorf("error executing query: %v", err)
+			logger.Ctx(ctx).Errorf("error executing query: %v", err)
 			return entityCount
 		}
 
-		repo.Cache.Set(ctx, string(key), entityCount, cacheTTL)
+		if cacheErr == nil {
+			repo.setCached(ctx, string(key), strconv.Itoa(entityCount), cacheTTL)
+		}
 	} else {
 		var typeErr error
 		entityCount, typeErr = strconv.Atoi(value)
 		if typeErr != nil {
-			logger.Errorf("error converting string to int from cache: %v", typeErr)
+			logger.Ctx(ctx).Errorf("error converting string to int from cache: %v", typeErr)
 			return entityCount
 		}
 	}
@@ -330,87 +305,93 @@ func (repo Repository) CountEntities(ctx context.Context, userID string, search
 	return entityCount
 }
 
-// Delete is used to soft delete a record from the DB
-func (repo Repository) Delete(model interface{}, userID string) interface{} {
-	err := repo.Database.Where("user_id = ?", userID).Delete(model).Error
-	return err
+// Delete is used to soft delete a record from the DB, as long as it is still at version. A version of 0 deletes
+// whatever version is current. When nothing was deleted, the record was either deleted in the meantime or changed
+// to another version, which are told apart by whether it is still there.
+func (repo Repository) Delete(ctx context.Context, model *models.EntityRecord, userID string, version uint64) error {
+	query := repo.Database.WithContext(ctx).Where("user_id = ?", userID)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.Delete(model)
+	if result.Error != nil {
+		return integrityError(result.Error, "Error deleting entity.", models.Parent{})
+	}
+
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var remaining int64
+	dbErr := repo.reader(WithPrimaryReads(ctx)).Raw(
+		`SELECT count(id) FROM entities WHERE user_id = ? AND category = ? AND id = ? AND deleted_at IS NULL`,
+		userID, model.Category, model.Entity.ID,
+	).Scan(&remaining).Error
+	if dbErr != nil {
+		logger.Ctx(ctx).Errorf("error checking for a deleted entity: %v", dbErr)
+		return apperrors.Internal("Error deleting entity.", dbErr)
+	}
+
+	if remaining == 0 {
+		return apperrors.NotFound("Entity not found.")
+	}
+
+	return apperrors.PreconditionFailed("The entity has been changed since it was read.")
 }
 
 // GetParents returns all the possible parents for an item.
 func (repo Repository) GetParents(ctx context.Context, category string, userID string) ([]models.GetEntitiesParentData, error) {
 	var results []models.GetEntitiesParentData
 
-	caser := cases.Title(language.AmericanEnglish)
-	capitalizedCategory := caser.String(category)
+	generation, cacheErr := repo.entitiesGeneration(ctx, userID)
 
 	cacheTTL := 5 * time.Minute
-	keyStructured := cache.CacheKey{
-		User:     userID,
-		Function: "Get" + capitalizedCategory + "Parents",
+	keyStructured := GetParentsCacheKey{
+		CacheKey: cache.CacheKey{
+			User:     userID,
+			Function: "GetParents",
+		},
+		Generation: generation,
+		Category:   category,
 	}
 
 	key, _ := json.Marshal(keyStructured)
-	value, redisErr := repo.Cache.Get(ctx, string(key)).Result()
-	if redisErr != nil && !errors.Is(redisErr, redis.Nil) {
-		logger.Errorf("Error retriving entites from Redis: %v", redisErr)
-		return nil, redisErr
+	value := ""
+	if cacheErr == nil {
+		value, cacheErr = repo.getCached(ctx, keyStructured.CacheKey.Function, string(key))
 	}
 
 	if value == "" {
-		var dbErr error
-		switch category {
-		case "item":
-			dbErr = repo.Database.Raw(`
-			(SELECT 'room' AS category, id, name FROM rooms WHERE user_id = ? AND deleted_at IS NULL)
-			UNION ALL
-			(SELECT 'shelf' AS category, id, name FROM shelves WHERE user_id = ? AND deleted_at IS NULL)
-			UNION ALL
-			(SELECT 'container' AS category, id, name FROM containers WHERE user_id = ? AND deleted_at IS NULL)`,
-				userID, userID, userID).Scan(&results).Error
-			break
-		case "container":
-			dbErr = repo.Database.Raw(`
-			(SELECT 'room' AS category, id, name FROM rooms WHERE user_id = ? AND deleted_at IS NULL)
-			UNION ALL
-			(SELECT 'shelf' AS category, id, name FROM shelves WHERE user_id = ? AND deleted_at IS NULL)`,
-				userID, userID).Scan(&results).Error
-			break
-		case "shelf":
-			dbErr = repo.Database.Raw(`
-			(SELECT 'shelving_unit' AS category, id, name FROM shelving_units WHERE user_id = ? AND deleted_at IS NULL)`,
-				userID).Scan(&results).Error
-			break
-		case "shelving_unit":
-			dbErr = repo.Database.Raw(`
-			(SELECT 'room' AS category, id, name FROM rooms WHERE user_id = ? AND deleted_at IS NULL)`,
-				userID).Scan(&results).Error
-			break
-		case "room":
-			dbErr = repo.Database.Raw(`
-			(SELECT 'building' AS category, id, name FROM buildings WHERE user_id = ? AND deleted_at IS NULL)`,
-				userID).Scan(&results).Error
-			break
-		default:
-			logger.Errorf("Invalid category for entity.")
-			return nil, fmt.Errorf("Invalid category: %v", category)
+		parentCategories, ok := models.ParentCategories[category]
+		if !ok || len(parentCategories) == 0 {
+			logger.Ctx(ctx).Errorf("Invalid category for entity.")
+			return nil, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil)
 		}
 
+		dbErr := repo.reader(ctx).Raw(
+			`SELECT category, id, name FROM entities WHERE user_id = ? AND category IN ? AND deleted_at IS NULL ORDER BY `+categoryWeightSQL+`, id`,
+			userID, parentCategories,
+		).Scan(&results).Error
+
 		if dbErr != nil {
-			logger.Errorf("error executing query: %v", dbErr)
-			return nil, dbErr
+			logger.Ctx(ctx).Errorf("error executing query: %v", dbErr)
+			return nil, apperrors.Internal("Error getting parents.", dbErr)
 		}
 
 		byteData, jsonErr := json.Marshal(results)
 		if jsonErr != nil {
-			logger.Errorf("error executing query: %v", dbErr)
-			return nil, jsonErr
+			logger.Ctx(ctx).Errorf("error executing query: %v", jsonErr)
+			return nil, apperrors.Internal("Error encoding parents.", jsonErr)
 		}
 
-		repo.Cache.Set(ctx, string(key), byteData, cacheTTL)
+		if cacheErr == nil {
+			repo.setCached(ctx, string(key), string(byteData), cacheTTL)
+		}
 	} else {
 		jsonErr := json.Unmarshal([]byte(value), &results)
 		if jsonErr != nil {
-			logger.Errorf("error executing query: %v", jsonErr)
+			logger.Ctx(ctx).Errorf("error executing query: %v", jsonErr)
 		}
 	}
 
@@ -418,282 +399,176 @@ func (repo Repository) GetParents(ctx context.Context, category string, userID s
 }
 
 // HasChildren returns all the children for an entity.
-func (repo Repository) HasChildren(id uint64, category string, userID string) (bool, int, error) {
+func (repo Repository) HasChildren(ctx context.Context, id uint64, category string, userID string) (bool, int, error) {
 	var childrenCount int
-	hasChildren := false
-
-	var dbErr error
-	switch category {
-	case "building":
-		dbErr = repo.Database.Raw(`
-			(SELECT count(id) AS childrenCount FROM rooms WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)`,
-			userID,
-			id,
-			category,
-		).Scan(&childrenCount).Error
-		break
-	case "room":
-		dbErr = repo.Database.Raw(`
-			SELECT
-				(SELECT count(id) FROM shelving_units WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL) +
-				(SELECT count(id) FROM containers WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL) +
-				(SELECT count(id) FROM items WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)
-			AS childrenCount`,
-			userID, id, category,
-			userID, id, category,
-			userID, id, category,
-		).Scan(&childrenCount).Error
-		break
-	case "shelving_unit":
-		dbErr = repo.Database.Raw(`
-			(SELECT count(id) AS childrenCount FROM shelves WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)`,
-			userID,
-			id,
-			category,
-		).Scan(&childrenCount).Error
-		break
-	case "shelf":
-		dbErr = repo.Database.Raw(`
-			SELECT
-				(SELECT count(id) FROM containers WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL) +
-				(SELECT count(id) FROM items WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)
-			AS childrenCount`,
-			userID, id, category,
-			userID, id, category,
-		).Scan(&childrenCount).Error
-		break
-	case "container":
-		dbErr = repo.Database.Raw(`
-			(SELECT count(id) AS childrenCount FROM items WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)`,
-			userID,
-			id,
-			category,
-		).Scan(&childrenCount).Error
-		break
-	default:
-		logger.Errorf("Invalid category for retriving children.")
-		return false, 0, fmt.Errorf("Invalid category for retriving children: %v", category)
-	}
 
-	if dbErr != nil {
-		logger.Errorf("error executing query: %v", dbErr)
-		return false, 0, dbErr
+	if !canHaveChildren(category) {
+		logger.Ctx(ctx).Errorf("Invalid category for retriving children.")
+		return false, 0, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil)
 	}
 
-	if childrenCount > 0 {
-		hasChildren = true
+	dbErr := repo.reader(ctx).Raw(
+		`SELECT count(id) AS childrenCount FROM entities WHERE user_id = ? AND parent_category = ? AND parent_id = ? AND deleted_at IS NULL`,
+		userID, category, id,
+	).Scan(&childrenCount).Error
+	if dbErr != nil {
+		logger.Ctx(ctx).Errorf("error executing query: %v", dbErr)
+		return false, 0, apperrors.Internal("Error getting children.", dbErr)
 	}
 
-	return hasChildren, childrenCount, nil
+	return childrenCount > 0, childrenCount, nil
 }
 
 // GetChildren returns all the children for an entity.
-func (repo Repository) GetChildren(id uint64, category string, userID string) ([]models.GetChildrenResponseData, error) {
+func (repo Repository) GetChildren(ctx context.Context, id uint64, category string, userID string) ([]models.GetChildrenResponseData, error) {
 	var results []models.GetChildrenResponseData
 
-	var dbErr error
-	switch category {
-	case "building":
-		dbErr = repo.Database.Raw(`
-			(SELECT id, name, 'room' AS category FROM rooms WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)`,
-			userID,
-			id,
-			category,
-		).Scan(&results).Error
-		break
-	case "room":
-		dbErr = repo.Database.Raw(`
-			(SELECT id, name, 'shelving_unit' AS category FROM shelving_units WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL) UNION ALL
-			(SELECT id, name, 'container' AS category FROM containers WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL) UNION ALL
-			(SELECT id, name, 'item' AS category FROM items WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)`,
-			userID, id, category,
-			userID, id, category,
-			userID, id, category,
-		).Scan(&results).Error
-		break
-	case "shelving_unit":
-		dbErr = repo.Database.Raw(`
-			(SELECT id, name, 'shelf' AS category FROM shelves WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)`,
-			userID,
-			id,
-			category,
-		).Scan(&results).Error
-		break
-	case "shelf":
-		dbErr = repo.Database.Raw(`
-			(SELECT id, name, 'container' AS category FROM containers WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL) UNION ALL
-			(SELECT id, name, 'item' AS category FROM items WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)`,
-			userID, id, category,
-			userID, id, category,
-		).Scan(&results).Error
-		break
-	case "container":
-		dbErr = repo.Database.Raw(`
-			(SELECT id, name, 'item' AS category FROM items WHERE user_id = ? AND parent_id = ? AND parent_category = ? AND deleted_at IS NULL)`,
-			userID,
-			id,
-			category,
-		).Scan(&results).Error
-		break
-	default:
-		logger.Errorf("Invalid category for retriving children.")
-		return nil, fmt.Errorf("Invalid category for retriving children: %v", category)
+	if !canHaveChildren(category) {
+		logger.Ctx(ctx).Errorf("Invalid category for retriving children.")
+		return nil, apperrors.BadRequest(fmt.Sprintf("Invalid category %v.", category), nil)
 	}
 
+	dbErr := repo.reader(ctx).Raw(
+		`SELECT id, name, category FROM entities WHERE user_id = ? AND parent_category = ? AND parent_id = ? AND deleted_at IS NULL ORDER BY `+categoryWeightSQL+`, id`,
+		userID, category, id,
+	).Scan(&results).Error
 	if dbErr != nil {
-		logger.Errorf("error executing query: %v", dbErr)
-		return nil, dbErr
+		logger.Ctx(ctx).Errorf("error executing query: %v", dbErr)
+		return nil, apperrors.Internal("Error getting children.", dbErr)
 	}
 
 	return results, nil
 }
 
-// FlushEntities clears the redis cache of all things relating to entities
-func (repo Repository) FlushEntities(ctx context.Context, userID string) {
+func (repo Repository) getParents(ctx context.Context, parentID uint, parentCategory string, userID string, array *[]models.GetEntitiesParentData) error {
+	// Each level of the chain nests a span in the one above, so deep chains show up in traces.
+	ctx, span := tracing.Tracer().Start(ctx, "repository.getParents", trace.WithAttributes(
+		attribute.String("entity.category", parentCategory),
+		attribute.Int64("entity.id", int64(parentID)),
+	))
+	defer span.End()
+
+	model := &models.EntityRecord{
+		Category: parentCategory,
+		Entity:   models.Entity{ID: uint64(parentID)},
+	}
+
+	err := repo.GetOne(ctx, model, userID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("error executing query: %v", err)
+		return nil
+	}
 
-	getAllEntitiesPattern := `{"CacheKey":{"User":"` + userID + `","Function":"GetAllEntities"},*`
-	countEntitiesPattern := `{"CacheKey":{"User":"` + userID + `","Function":"CountEntities"},*`
+	*array = append((*array), models.GetEntitiesParentData{
+		ID:       parentID,
+		Name:     model.Entity.Name,
+		Category: parentCategory,
+	})
 
-	patterns := []string{
-		`{"User":"` + userID + `","Function":"GetItemParents"}`,
-		`{"User":"` + userID + `","Function":"GetContainerParents"}`,
-		`{"User":"` + userID + `","Function":"GetShelfParents"}`,
-		`{"User":"` + userID + `","Function":"GetShelving_unitParents"}`,
-		`{"User":"` + userID + `","Function":"GetRoomParents"}`,
+	parent := model.Parent()
+	if parentCategory != "building" && parent.ParentID != 0 && parent.ParentCategory != "" {
+		repo.getParents(ctx, uint(parent.ParentID), parent.ParentCategory, userID, array)
 	}
 
-	entitiesKeys, err := repo.Cache.Keys(ctx, getAllEntitiesPattern).Result()
-	if err != nil {
-		logger.Errorf("error getting cache keys: %v", err)
-		return
+	return nil
+}
+
+// checkParent makes sure the parent of record can hold it, and locks it against deletion for the rest of tx.
+// Another user's parent is reported as missing so IDs can't be probed.
+func checkParent(tx *gorm.DB, record *models.EntityRecord) error {
+	parent := record.Parent()
+	if parent.ParentCategory == "" {
+		return nil
 	}
 
-	countKeys, err := repo.Cache.Keys(ctx, countEntitiesPattern).Result()
-	if err != nil {
-		logger.Errorf("error getting cache keys: %v", err)
-		return
+	var rows []struct {
+		UserID    string
+		DeletedAt *time.Time
 	}
 
-	keys := append(entitiesKeys, countKeys...)
-	keys = append(keys, patterns...)
+	dbErr := tx.Raw(
+		`SELECT user_id, deleted_at FROM entities WHERE category = ? AND id = ? FOR SHARE`,
+		parent.ParentCategory, parent.ParentID,
+	).Scan(&rows).Error
+	if dbErr != nil {
+		logger.Ctx(tx.Statement.Context).Errorf("error executing query: %v", dbErr)
+		return apperrors.Internal("Error getting parent.", dbErr)
+	}
 
-	for _, key := range keys {
-		err := repo.Cache.Del(ctx, key).Err()
-		if err != nil {
-			logger.Errorf("error clearing cache: %v", err)
-			return
-		}
+	if len(rows) == 0 || rows[0].UserID != record.Entity.UserID {
+		return apperrors.NotFound(fmt.Sprintf("Parent category of %v with id %v not found.", parent.ParentCategory, parent.ParentID))
 	}
-}
 
-func (repo Repository) getParents(parentID uint, parentCategory string, userID string, array *[]models.GetEntitiesParentData) error {
-	findEntity := models.Entity{
-		ID: uint64(parentID),
+	if rows[0].DeletedAt != nil {
+		return apperrors.Conflict(fmt.Sprintf("Parent category of %v with id %v has been deleted.", parent.ParentCategory, parent.ParentID))
 	}
 
-	var parent models.GetEntitiesParentData
-	var recursiveID uint
-	var recursiveCategory string
-	switch parentCategory {
-	case "container":
-		model := &models.Container{
-			Entity: findEntity,
-		}
+	return nil
+}
 
-		err := repo.GetOne(&model, userID)
-		if err != nil {
-			logger.Errorf("error executing query: %v", err)
-			return nil
-		}
+// The Postgres error codes integrityError understands.
+const (
+	restrictViolation   = "23001"
+	foreignKeyViolation = "23503"
+	checkViolation      = "23514"
+)
 
-		parent = models.GetEntitiesParentData{
-			ID:       parentID,
-			Name:     model.Entity.Name,
-			Category: parentCategory,
-		}
+// integrityError turns the constraint violations the entities table raises into the errors we send the client. Any
+// other error is internal.
+func integrityError(err error, message string, parent models.Parent) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return apperrors.Internal(message, err)
+	}
 
-		recursiveID = uint(model.Parent.ParentID)
-		recursiveCategory = model.Parent.ParentCategory
-		break
-	case "shelf":
-		model := &models.Shelf{
-			Entity: findEntity,
-		}
-		err := repo.GetOne(&model, userID)
-		if err != nil {
-			logger.Errorf("error executing query: %v", err)
-			return nil
-		}
+	switch pgErr.Code {
+	case foreignKeyViolation:
+		return apperrors.NotFound(fmt.Sprintf("Parent category of %v with id %v not found.", parent.ParentCategory, parent.ParentID))
+	case checkViolation:
+		return apperrors.BadRequest("Invalid parent.", err)
+	case restrictViolation:
+		return apperrors.Conflict(pgErr.Message)
+	}
 
-		parent = models.GetEntitiesParentData{
-			ID:       parentID,
-			Name:     model.Entity.Name,
-			Category: parentCategory,
-		}
-		recursiveID = uint(model.Parent.ParentID)
-		recursiveCategory = model.Parent.ParentCategory
-		break
-	case "shelving_unit":
-		model := &models.ShelvingUnit{
-			Entity: findEntity,
-		}
-		err := repo.GetOne(&model, userID)
-		if err != nil {
-			logger.Errorf("error executing query: %v", err)
-			return nil
-		}
+	return apperrors.Internal(message, err)
+}
 
-		parent = models.GetEntitiesParentData{
-			ID:       parentID,
-			Name:     model.Entity.Name,
-			Category: parentCategory,
-		}
-		recursiveID = uint(model.Parent.ParentID)
-		recursiveCategory = model.Parent.ParentCategory
-		break
-	case "room":
-		model := &models.Room{
-			Entity: findEntity,
-		}
-		err := repo.GetOne(&model, userID)
-		if err != nil {
-			logger.Errorf("error executing query: %v", err)
-			return nil
-		}
+// categoryWeightSQL sorts entities from the top of the hierarchy down.
+var categoryWeightSQL = func() string {
+	weights := "CASE category"
+	for i, category := range models.Categories {
+		weights += fmt.Sprintf(" WHEN '%s' THEN %d", category, i+1)
+	}
 
-		parent = models.GetEntitiesParentData{
-			ID:       parentID,
-			Name:     model.Entity.Name,
-			Category: parentCategory,
-		}
-		recursiveID = uint(model.Parent.ParentID)
-		recursiveCategory = model.Parent.ParentCategory
-		break
-	case "building":
-		model := &models.Building{
-			Entity: findEntity,
-		}
-		err := repo.GetOne(&model, userID)
-		if err != nil {
-			logger.Errorf("error executing query: %v", err)
-			return nil
-		}
+	return weights + " END"
+}()
 
-		parent = models.GetEntitiesParentData{
-			ID:       parentID,
-			Name:     model.Entity.Name,
-			Category: parentCategory,
-		}
-		break
-	default:
-		logger.Errorf("Invalid Category: %v", parentCategory)
+// entitiesQuery adds the conditions GetAllEntities and CountEntities share to selectSQL.
+func entitiesQuery(selectSQL string, userID string, search string, filters []string) (string, []interface{}) {
+	query := selectSQL + ` WHERE user_id = ? AND deleted_at IS NULL`
+	values := []interface{}{userID}
+
+	if len(filters) > 0 {
+		query += ` AND category IN ?`
+		values = append(values, filters)
+	}
+
+	if search != "" {
+		search = "%" + strings.ToLower(search) + "%"
+		query += ` AND (LOWER(name) LIKE ? OR LOWER(notes) LIKE ? OR LOWER(attributes->>'address') LIKE ?)`
+		values = append(values, search, search, search)
 	}
 
-	*array = append((*array), parent)
+	return query, values
+}
 
-	if parent.Category != "building" && recursiveID != 0 && recursiveCategory != "" {
-		repo.getParents(recursiveID, recursiveCategory, userID, array)
+// canHaveChildren reports whether any category can be placed under category.
+func canHaveChildren(category string) bool {
+	for _, parents := range models.ParentCategories {
+		if slices.Contains(parents, category) {
+			return true
+		}
 	}
 
-	return nil
+	return false
 }
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxStoredEvents is about how many events are kept for each user, for clients that reconnect with Last-Event-ID.
+const maxStoredEvents = 1000
+
+// eventsKey is both the stream the events of a user are kept in and the channel they are published on.
+func eventsKey(userID string) string {
+	key, _ := json.Marshal(cache.CacheKey{User: userID, Function: "Events"})
+	return string(key)
+}
+
+// PublishEvents sends changes to a user's entities to every event stream the user has open, on any replica. Each
+// event is added to a capped Redis stream, which gives it its ID and lets clients catch up on what they missed, and
+// then published on the user's channel. Failures are only logged since the changes themselves are already saved.
+func (repo Repository) PublishEvents(ctx context.Context, userID string, events ...models.EntityEvent) {
+	key := eventsKey(userID)
+
+	for _, event := range events {
+		data, jsonErr := json.Marshal(event)
+		if jsonErr != nil {
+			logger.Ctx(ctx).Errorf("error encoding event: %v", jsonErr)
+			continue
+		}
+
+		id, redisErr := repo.Redis.XAdd(ctx, &redis.XAddArgs{
+			Stream: key,
+			MaxLen: maxStoredEvents,
+			Approx: true,
+			Values: map[string]interface{}{"event": data},
+		}).Result()
+		if redisErr != nil {
+			logger.Ctx(ctx).Errorf("error storing event: %v", redisErr)
+			continue
+		}
+
+		event.ID = id
+		if data, jsonErr = json.Marshal(event); jsonErr != nil {
+			logger.Ctx(ctx).Errorf("error encoding event: %v", jsonErr)
+			continue
+		}
+
+		if redisErr = repo.Redis.Publish(ctx, key, data).Err(); redisErr != nil {
+			logger.Ctx(ctx).Errorf("error publishing event: %v", redisErr)
+		}
+	}
+}
+
+// SubscribeEvents starts listening for the events of a user. The subscription is confirmed before it is returned,
+// so nothing published afterwards can be missed.
+func (repo Repository) SubscribeEvents(ctx context.Context, userID string) (*redis.PubSub, error) {
+	subscription := repo.Redis.Subscribe(ctx, eventsKey(userID))
+	if _, err := subscription.Receive(ctx); err != nil {
+		subscription.Close()
+		logger.Ctx(ctx).Errorf("error subscribing to events: %v", err)
+		return nil, apperrors.Upstream("redis", "Unable to subscribe to events", err)
+	}
+
+	return subscription, nil
+}
+
+// EventsSince returns the stored events of a user that came after lastID, oldest first. It also reports whether
+// they are all the events since lastID. When lastID is older than anything still stored, some may have been
+// trimmed and the client has to reload the entities.
+func (repo Repository) EventsSince(ctx context.Context, userID string, lastID string) ([]models.EntityEvent, bool, error) {
+	key := eventsKey(userID)
+
+	if !models.ValidEventID(lastID) {
+		return nil, false, nil
+	}
+
+	oldest, redisErr := repo.Redis.XRangeN(ctx, key, "-", "+", 1).Result()
+	if redisErr != nil {
+		logger.Ctx(ctx).Errorf("error reading events: %v", redisErr)
+		return nil, false, apperrors.Upstream("redis", "Unable to read events", redisErr)
+	}
+
+	complete := len(oldest) == 0 || !models.EventIDBefore(lastID, oldest[0].ID)
+
+	messages, redisErr := repo.Redis.XRange(ctx, key, "("+lastID, "+").Result()
+	if redisErr != nil {
+		logger.Ctx(ctx).Errorf("error reading events: %v", redisErr)
+		return nil, false, apperrors.Upstream("redis", "Unable to read events", redisErr)
+	}
+
+	events := make([]models.EntityEvent, 0, len(messages))
+	for _, message := range messages {
+		data, _ := message.Values["event"].(string)
+
+		var event models.EntityEvent
+		if jsonErr := json.Unmarshal([]byte(data), &event); jsonErr != nil {
+			logger.Ctx(ctx).Errorf("error decoding event %s: %v", message.ID, jsonErr)
+			continue
+		}
+
+		event.ID = message.ID
+		events = append(events, event)
+	}
+
+	return events, complete, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// IdempotencyTTL is how long the response to a request with an Idempotency-Key is replayed for.
+	IdempotencyTTL = 24 * time.Hour
+	// AnonymousIdempotencyTTL is how long the response to a request made before signing in is replayed for. Those
+	// responses carry credentials, so they are only kept long enough for a client's retries.
+	AnonymousIdempotencyTTL = 5 * time.Minute
+	// idempotencyLockTTL is how long a key stays claimed by a request that never finishes, for example because the
+	// server stopped while handling it.
+	idempotencyLockTTL = time.Minute
+	// idempotencyClaimAttempts is how many times a key is claimed again after the claim on it expired between
+	// claiming and reading it, before giving up.
+	idempotencyClaimAttempts = 3
+)
+
+// IdempotencyCacheKey is an extension of cachekey that represents the structure of the keys in our cache for idempotent responses.
+// Keys sent before signing in belong to the client's IP instead of a user.
+type IdempotencyCacheKey struct {
+	CacheKey cache.CacheKey
+	IP       string
+	Key      string
+}
+
+// IdempotencyScope is who an Idempotency-Key belongs to: a user, or an IP when no one is signed in.
+type IdempotencyScope struct {
+	UserID string
+	IP     string
+}
+
+func idempotencyKey(scope IdempotencyScope, key string) string {
+	cacheKey, _ := json.Marshal(IdempotencyCacheKey{
+		CacheKey: cache.CacheKey{User: scope.UserID, Function: "Idempotency"},
+		IP:       scope.IP,
+		Key:      key,
+	})
+	return string(cacheKey)
+}
+
+// ClaimIdempotencyKey claims an Idempotency-Key for a request with the given fingerprint. It returns nil when the key
+// is new and the request should run, or what is stored for the key when it has been used before.
+func (repo Repository) ClaimIdempotencyKey(ctx context.Context, scope IdempotencyScope, key string, fingerprint string) (*models.IdempotentResponse, error) {
+	cacheKey := idempotencyKey(scope, key)
+
+	claim, jsonErr := json.Marshal(models.IdempotentResponse{Fingerprint: fingerprint})
+	if jsonErr != nil {
+		return nil, apperrors.Internal("Unable to encode idempotency key", jsonErr)
+	}
+
+	for attempt := 0; attempt < idempotencyClaimAttempts; attempt++ {
+		claimed, redisErr := repo.Redis.SetNX(ctx, cacheKey, claim, idempotencyLockTTL).Result()
+		if redisErr != nil {
+			logger.Ctx(ctx).Errorf("Error claiming idempotency key in Redis: %v", redisErr)
+			return nil, apperrors.Upstream("redis", "Unable to check idempotency key", redisErr)
+		}
+
+		if claimed {
+			return nil, nil
+		}
+
+		value, redisErr := repo.Redis.Get(ctx, cacheKey).Result()
+		if errors.Is(redisErr, redis.Nil) {
+			// The claim expired in between, so whoever held it has given up.
+			continue
+		}
+
+		if redisErr != nil {
+			logger.Ctx(ctx).Errorf("Error retriving idempotency key from Redis: %v", redisErr)
+			return nil, apperrors.Upstream("redis", "Unable to check idempotency key", redisErr)
+		}
+
+		stored := &models.IdempotentResponse{}
+		if jsonErr = json.Unmarshal([]byte(value), stored); jsonErr != nil {
+			return nil, apperrors.Internal("Unable to decode idempotency key", jsonErr)
+		}
+
+		return stored, nil
+	}
+
+	return nil, apperrors.Conflict("A request with this Idempotency-Key is still being handled.")
+}
+
+// SaveIdempotentResponse stores the response to the request that claimed an Idempotency-Key, for IdempotencyTTL, or
+// AnonymousIdempotencyTTL when no one was signed in.
+func (repo Repository) SaveIdempotentResponse(ctx context.Context, scope IdempotencyScope, key string, response models.IdempotentResponse) error {
+	response.Done = true
+
+	byteData, jsonErr := json.Marshal(response)
+	if jsonErr != nil {
+		return apperrors.Internal("Unable to encode response", jsonErr)
+	}
+
+	ttl := IdempotencyTTL
+	if scope.UserID == "" {
+		ttl = AnonymousIdempotencyTTL
+	}
+
+	if err := repo.Redis.Set(ctx, idempotencyKey(scope, key), byteData, ttl).Err(); err != nil {
+		logger.Ctx(ctx).Errorf("error saving idempotent response: %v", err)
+		return apperrors.Upstream("redis", "Unable to save response", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey gives up the claim on an Idempotency-Key, so the request can be retried.
+func (repo Repository) ReleaseIdempotencyKey(ctx context.Context, scope IdempotencyScope, key string) error {
+	if err := repo.Redis.Del(ctx, idempotencyKey(scope, key)).Err(); err != nil {
+		logger.Ctx(ctx).Errorf("error releasing idempotency key: %v", err)
+		return apperrors.Upstream("redis", "Unable to release idempotency key", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
+)
+
+// CategoryCount is the number of entities of one category a maintenance task touched.
+type CategoryCount struct {
+	Category string
+	Rows     int64
+}
+
+// FindOrphans returns the live entities whose parent is soft deleted, missing, owned by someone else or of a
+// category they can't be placed under. An empty userID searches every user.
+func (repo Repository) FindOrphans(userID string) ([]models.Orphan, error) {
+	var results []models.Orphan
+	var values []interface{}
+
+	var validPairs []string
+	for _, category := range models.Categories {
+		for _, parent := range models.ParentCategories[category] {
+			validPairs = append(validPairs, fmt.Sprintf("('%s', '%s')", category, parent))
+		}
+	}
+
+	query := `SELECT c.category, c.id, c.name, c.user_id, COALESCE(c.parent_id, 0) AS parent_id, COALESCE(c.parent_category, '') AS parent_category FROM entities c ` +
+		`LEFT JOIN entities p ON p.category = c.parent_category AND p.id = c.parent_id AND p.user_id = c.user_id AND p.deleted_at IS NULL ` +
+		`WHERE c.deleted_at IS NULL AND c.category <> 'building'`
+	if userID != "" {
+		query += ` AND c.user_id = ?`
+		values = append(values, userID)
+	}
+
+	query += ` AND (p.id IS NULL OR (c.category, c.parent_category) NOT IN (` + strings.Join(validPairs, ", ") + `)) ORDER BY c.user_id, c.category, c.id`
+
+	dbErr := repo.Database.Raw(query, values...).Scan(&results).Error
+	if dbErr != nil {
+		logger.Errorf("error executing query: %v", dbErr)
+		return nil, apperrors.Internal("Error finding orphaned entities.", dbErr)
+	}
+
+	return results, nil
+}
+
+// purgeableSQL matches entities soft deleted before the cutoff that no newer or live entity still points at.
+const purgeableSQL = `deleted_at IS NOT NULL AND deleted_at < ? AND NOT EXISTS ` +
+	`(SELECT 1 FROM entities c WHERE c.parent_category = entities.category AND c.parent_id = entities.id AND (c.deleted_at IS NULL OR c.deleted_at >= ?))`
+
+// CountDeleted returns how many entities of each category PurgeDeleted would remove.
+func (repo Repository) CountDeleted(before time.Time) ([]CategoryCount, error) {
+	var rows []CategoryCount
+
+	dbErr := repo.Database.Raw(`SELECT category, count(id) AS rows FROM entities WHERE `+purgeableSQL+` GROUP BY category`, before, before).Scan(&rows).Error
+	if dbErr != nil {
+		logger.Errorf("error executing query: %v", dbErr)
+		return nil, apperrors.Internal("Error counting deleted entities.", dbErr)
+	}
+
+	return categoryCounts(rows), nil
+}
+
+// PurgeDeleted permanently removes the entities that were soft deleted before the cutoff. Entities that still
+// have children deleted after the cutoff are kept until their children can go too.
+func (repo Repository) PurgeDeleted(before time.Time) ([]CategoryCount, error) {
+	var categories []string
+
+	dbErr := repo.Database.Raw(`DELETE FROM entities WHERE `+purgeableSQL+` RETURNING category`, before, before).Scan(&categories).Error
+	if dbErr != nil {
+		logger.Errorf("error executing query: %v", dbErr)
+		return nil, apperrors.Internal("Error purging deleted entities.", dbErr)
+	}
+
+	var rows []CategoryCount
+	for _, category := range categories {
+		rows = append(rows, CategoryCount{Category: category, Rows: 1})
+	}
+
+	return categoryCounts(rows), nil
+}
+
+// ReindexSearch rebuilds the indexes and planner statistics of the entities table that search runs against.
+func (repo Repository) ReindexSearch() error {
+	for _, statement := range []string{"REINDEX TABLE entities", "ANALYZE entities"} {
+		if dbErr := repo.Database.Exec(statement).Error; dbErr != nil {
+			logger.Errorf("error executing query: %v", dbErr)
+			return apperrors.Internal("Error reindexing entities.", dbErr)
+		}
+	}
+
+	return nil
+}
+
+// EntityUserIDs returns every user that owns at least one entity, deleted or not.
+func (repo Repository) EntityUserIDs() ([]string, error) {
+	var userIDs []string
+
+	dbErr := repo.Database.Raw(`SELECT DISTINCT user_id FROM entities ORDER BY user_id`).Scan(&userIDs).Error
+	if dbErr != nil {
+		logger.Errorf("error executing query: %v", dbErr)
+		return nil, apperrors.Internal("Error listing entity owners.", dbErr)
+	}
+
+	return userIDs, nil
+}
+
+// FlushAllEntities clears the cached entities of every user that owns any.
+func (repo Repository) FlushAllEntities(ctx context.Context) (int, error) {
+	userIDs, err := repo.EntityUserIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userID := range userIDs {
+		repo.FlushEntities(ctx, userID)
+	}
+
+	return len(userIDs), nil
+}
+
+// categoryCounts totals rows by category, listing every category from the top of the hierarchy down.
+func categoryCounts(rows []CategoryCount) []CategoryCount {
+	counts := make([]CategoryCount, len(models.Categories))
+	for i, category := range models.Categories {
+		counts[i].Category = category
+		for _, row := range rows {
+			if row.Category == category {
+				counts[i].Rows += row.Rows
+			}
+		}
+	}
+
+	return counts
+}
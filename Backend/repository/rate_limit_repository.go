@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+	"willowsuite-vault/infra/cache"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitCacheKey is an extension of cachekey that represents the structure of the keys in our cache for the
+// requests a client made to a group of routes. Clients that aren't signed in are told apart by IP, or by the
+// SHA-256 of the email they are signing in as so addresses aren't kept in Redis.
+type RateLimitCacheKey struct {
+	CacheKey cache.CacheKey
+	Group    string
+	IP       string
+	Email    string
+}
+
+// RateLimitSubject is who a rate limit counts requests for. Only one of its fields is set.
+type RateLimitSubject struct {
+	UserID string
+	IP     string
+	Email  string
+}
+
+func rateLimitKey(group string, subject RateLimitSubject) string {
+	email := ""
+	if subject.Email != "" {
+		hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(subject.Email))))
+		email = hex.EncodeToString(hash[:])
+	}
+
+	key, _ := json.Marshal(RateLimitCacheKey{
+		CacheKey: cache.CacheKey{User: subject.UserID, Function: "RateLimit"},
+		Group:    group,
+		IP:       subject.IP,
+		Email:    email,
+	})
+	return string(key)
+}
+
+// slidingWindow keeps the times of the requests made within the window in a sorted set. It drops those that have
+// left the window, then records the new request if there is room for it, and answers whether it did, how many
+// requests are in the window and when the oldest of them was made. Rejected requests aren't recorded, so a client
+// that keeps retrying gets back in as soon as its oldest request leaves the window.
+var slidingWindow = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < tonumber(ARGV[3]) then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {allowed, count, oldest[2] or tostring(now)}
+`)
+
+// RateLimitResult is whether a request fits in its rate limit, and how much of the limit is left.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the oldest request in the window leaves it and makes room for another.
+	Reset time.Duration
+}
+
+// TakeRateLimit records a request to a group of routes by subject, as long as fewer than limit were made within
+// window before it. Every instance shares the count. It goes through the cache's circuit breaker, and returns
+// cache.ErrOpen without asking Redis while that is open.
+func (repo Repository) TakeRateLimit(ctx context.Context, group string, subject RateLimitSubject, limit int, window time.Duration) (RateLimitResult, error) {
+	now := time.Now()
+	member := fmt.Sprintf("%d-%d", now.UnixNano(), rand.Int63())
+
+	var values []interface{}
+	err := repo.guarded(ctx, func(ctx context.Context) (err error) {
+		values, err = slidingWindow.Run(ctx, repo.Redis, []string{rateLimitKey(group, subject)},
+			now.UnixMilli(), window.Milliseconds(), limit, member).Slice()
+		return err
+	})
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	if len(values) != 3 {
+		return RateLimitResult{}, fmt.Errorf("rate limit script answered %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	count, _ := values[1].(int64)
+	oldestText, _ := values[2].(string)
+	oldest, err := strconv.ParseFloat(oldestText, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("parsing the oldest request %q: %w", oldestText, err)
+	}
+
+	reset := time.UnixMilli(int64(oldest)).Add(window).Sub(now)
+	if reset < 0 {
+		reset = 0
+	}
+
+	return RateLimitResult{Allowed: allowed == 1, Remaining: max(limit-int(count), 0), Reset: reset}, nil
+}
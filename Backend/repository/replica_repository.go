@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/logger"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// primaryReadsKey is the context key that sends the reads of a request to the primary.
+type primaryReadsKey struct{}
+
+// WithPrimaryReads returns a copy of ctx whose reads go to the primary rather than a replica that may not have the
+// latest writes yet.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// ReadsFromPrimary reports whether the reads made with ctx go to the primary.
+func ReadsFromPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryReadsKey{}).(bool)
+	return primary
+}
+
+// reader returns the database to read with for ctx.
+func (repo Repository) reader(ctx context.Context) *gorm.DB {
+	db := repo.Database.WithContext(ctx)
+	if ReadsFromPrimary(ctx) {
+		db = db.Clauses(dbresolver.Write)
+	}
+
+	return db
+}
+
+// wroteRecentlyKey is set for a while after a user writes, while the replicas may still be catching up.
+func wroteRecentlyKey(userID string) string {
+	key, _ := json.Marshal(cache.CacheKey{User: userID, Function: "WroteRecently"})
+	return string(key)
+}
+
+// MarkWritten sends a user's reads to the primary for window, on every instance of the API. It reports whether it
+// set a new mark rather than extending one left by an earlier write, which only the former may take back with
+// ClearWritten.
+func (repo Repository) MarkWritten(ctx context.Context, userID string, window time.Duration) bool {
+	key := wroteRecentlyKey(userID)
+	created, err := repo.Redis.SetNX(ctx, key, 1, window).Result()
+	if err != nil {
+		logger.Ctx(ctx).Errorf("error marking a write in Redis: %v", err)
+		return false
+	}
+
+	if !created {
+		if err := repo.Redis.Expire(ctx, key, window).Err(); err != nil {
+			logger.Ctx(ctx).Errorf("error marking a write in Redis: %v", err)
+		}
+	}
+
+	return created
+}
+
+// ClearWritten takes back a mark set by MarkWritten for a write that didn't happen.
+func (repo Repository) ClearWritten(ctx context.Context, userID string) {
+	if err := repo.Redis.Del(ctx, wroteRecentlyKey(userID)).Err(); err != nil {
+		logger.Ctx(ctx).Errorf("error clearing a write mark in Redis: %v", err)
+	}
+}
+
+// WroteRecently reports whether a user wrote recently enough that their reads should go to the primary. When Redis
+// can't say, reads go to the replicas.
+func (repo Repository) WroteRecently(ctx context.Context, userID string) bool {
+	err := repo.Redis.Get(ctx, wroteRecentlyKey(userID)).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.Ctx(ctx).Warnf("error checking for recent writes in Redis: %v", err)
+	}
+
+	return err == nil
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/config"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RevokedTokenCacheKey is an extension of cachekey that represents the structure of the keys in our cache for revoked tokens.
+type RevokedTokenCacheKey struct {
+	CacheKey cache.CacheKey
+	TokenID  string
+}
+
+func sessionsKey(userID string) string {
+	key, _ := json.Marshal(cache.CacheKey{User: userID, Function: "Sessions"})
+	return string(key)
+}
+
+func revokedBeforeKey(userID string) string {
+	key, _ := json.Marshal(cache.CacheKey{User: userID, Function: "RevokedBefore"})
+	return string(key)
+}
+
+func revokedTokenKey(userID string, tokenID string) string {
+	key, _ := json.Marshal(RevokedTokenCacheKey{
+		CacheKey: cache.CacheKey{User: userID, Function: "RevokedToken"},
+		TokenID:  tokenID,
+	})
+	return string(key)
+}
+
+// sessionTouchInterval is how often a session's last use is written. Requests in between only check the
+// instance's own recentlyTouched, so signed in requests don't cost Redis round trips to keep it current.
+const sessionTouchInterval = time.Minute
+
+// recentlyTouched remembers the sessions this instance wrote in the last sessionTouchInterval.
+var recentlyTouched = cache.NewLRUCache(10000)
+
+// TouchSession records that a session was used, creating it on first sight. A session this instance already wrote
+// in the last minute is left as it is. It goes through the cache's circuit breaker and is skipped while that is
+// open, so the session's last use is only behind for as long as Redis is down.
+func (repo Repository) TouchSession(ctx context.Context, userID string, session models.Session) error {
+	key := sessionsKey(userID)
+	touchedKey := key + session.ID
+	if _, err := recentlyTouched.Get(ctx, touchedKey); err == nil {
+		return nil
+	}
+
+	var value string
+	redisErr := repo.guarded(ctx, func(ctx context.Context) (err error) {
+		value, err = repo.Redis.HGet(ctx, key, session.ID).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	})
+	if errors.Is(redisErr, cache.ErrOpen) {
+		return nil
+	}
+
+	if redisErr != nil {
+		logger.Ctx(ctx).Errorf("Error retriving session from Redis: %v", redisErr)
+		return apperrors.Upstream("redis", "Unable to verify session", redisErr)
+	}
+
+	if value != "" {
+		var existing models.Session
+		if jsonErr := json.Unmarshal([]byte(value), &existing); jsonErr == nil {
+			session.CreatedAt = existing.CreatedAt
+		}
+	}
+
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = session.LastSeenAt
+	}
+
+	byteData, jsonErr := json.Marshal(session)
+	if jsonErr != nil {
+		logger.Ctx(ctx).Errorf("error encoding session: %v", jsonErr)
+		return apperrors.Internal("Unable to encode session", jsonErr)
+	}
+
+	err := repo.guarded(ctx, func(ctx context.Context) error {
+		_, err := repo.Redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, session.ID, byteData)
+			pipe.Expire(ctx, key, config.SessionMaxAge())
+			return nil
+		})
+		return err
+	})
+	if errors.Is(err, cache.ErrOpen) {
+		return nil
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Errorf("error saving session: %v", err)
+		return apperrors.Upstream("redis", "Unable to save session", err)
+	}
+
+	recentlyTouched.Set(ctx, touchedKey, "1", sessionTouchInterval)
+	return nil
+}
+
+// GetSessions returns the active sessions for a user, most recently used first.
+func (repo Repository) GetSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	values, err := repo.Redis.HGetAll(ctx, sessionsKey(userID)).Result()
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Error retriving sessions from Redis: %v", err)
+		return nil, apperrors.Upstream("redis", "Issue getting sessions.", err)
+	}
+
+	sessions := []models.Session{}
+	for _, value := range values {
+		var session models.Session
+		if jsonErr := json.Unmarshal([]byte(value), &session); jsonErr != nil {
+			logger.Ctx(ctx).Errorf("error decoding session: %v", jsonErr)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+// localRevocations keeps the revocations made through this instance, so it can still refuse those tokens while
+// Redis is down.
+var localRevocations = cache.NewLRUCache(10000)
+
+// RevokeSession denylists every access token issued for a session and forgets the session.
+func (repo Repository) RevokeSession(ctx context.Context, userID string, sessionID string) error {
+	key := revokedTokenKey(userID, sessionID)
+	localRevocations.Set(ctx, key, "1", config.AccessTokenMaxAge())
+
+	err := repo.Redis.Set(ctx, key, 1, config.AccessTokenMaxAge()).Err()
+	if err != nil {
+		logger.Ctx(ctx).Errorf("error revoking session: %v", err)
+		return apperrors.Upstream("redis", "Couldn't revoke access token", err)
+	}
+
+	if err = repo.Redis.HDel(ctx, sessionsKey(userID), sessionID).Err(); err != nil {
+		logger.Ctx(ctx).Errorf("error removing session: %v", err)
+		return apperrors.Upstream("redis", "Couldn't revoke access token", err)
+	}
+
+	return nil
+}
+
+// RevokeAllSessions denylists every access token issued to a user up to now and forgets all of their sessions.
+func (repo Repository) RevokeAllSessions(ctx context.Context, userID string) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	localRevocations.Set(ctx, revokedBeforeKey(userID), now, config.AccessTokenMaxAge())
+
+	err := repo.Redis.Set(ctx, revokedBeforeKey(userID), now, config.AccessTokenMaxAge()).Err()
+	if err != nil {
+		logger.Ctx(ctx).Errorf("error revoking sessions: %v", err)
+		return apperrors.Upstream("redis", "Couldn't revoke sessions", err)
+	}
+
+	if err = repo.Redis.Del(ctx, sessionsKey(userID)).Err(); err != nil {
+		logger.Ctx(ctx).Errorf("error removing sessions: %v", err)
+		return apperrors.Upstream("redis", "Couldn't revoke sessions", err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether any of the token ids are denylisted or the token was issued before a log out of all devices.
+//
+// The check goes through the same circuit breaker as the cache. While Redis is down only the revocations made
+// through this instance are known, so a token revoked through another one keeps working until Redis is back or the
+// token expires, at most ACCESS_TOKEN_MAX_AGE. That is the price of signed in requests still working during an
+// outage; the token's signature and expiry are checked either way.
+func (repo Repository) IsTokenRevoked(ctx context.Context, userID string, tokenIDs []string, issuedAt int64) (bool, error) {
+	keys := []string{revokedBeforeKey(userID)}
+	for _, tokenID := range tokenIDs {
+		if tokenID != "" {
+			keys = append(keys, revokedTokenKey(userID, tokenID))
+		}
+	}
+
+	var values []interface{}
+	lookup := func(ctx context.Context) (err error) {
+		values, err = repo.Redis.MGet(ctx, keys...).Result()
+		return err
+	}
+
+	if err := repo.guarded(ctx, lookup); err != nil {
+		logger.Ctx(ctx).Warnf("Error retriving revoked tokens from Redis, checking this instance's revocations only: %v", err)
+		values = make([]interface{}, len(keys))
+		for i, key := range keys {
+			if value, localErr := localRevocations.Get(ctx, key); localErr == nil {
+				values[i] = value
+			}
+		}
+	}
+
+	if revokedBefore, ok := values[0].(string); ok {
+		cutoff, typeErr := strconv.ParseInt(revokedBefore, 10, 64)
+		if typeErr == nil && issuedAt < cutoff {
+			return true, nil
+		}
+	}
+
+	for _, value := range values[1:] {
+		if value != nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
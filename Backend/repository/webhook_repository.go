@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
+)
+
+// webhookDeliveriesShown is how many of the latest deliveries of a webhook are listed.
+const webhookDeliveriesShown = 50
+
+// DueWebhookDelivery is a delivery claimed by the dispatcher, with where to send it and what to sign it with.
+type DueWebhookDelivery struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// CreateWebhook saves a new webhook for a user with a freshly generated secret.
+func (repo Repository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return apperrors.Internal("Error generating webhook secret.", err)
+	}
+
+	webhook.Secret = hex.EncodeToString(secret)
+	webhook.CreatedAt = time.Now()
+
+	if dbErr := repo.Database.WithContext(ctx).Create(webhook).Error; dbErr != nil {
+		logger.Ctx(ctx).Errorf("error saving webhook: %v", dbErr)
+		return apperrors.Internal("Error saving webhook.", dbErr)
+	}
+
+	return nil
+}
+
+// GetWebhooks returns the webhooks of a user, oldest first, without their secrets.
+func (repo Repository) GetWebhooks(ctx context.Context, userID string) ([]models.Webhook, error) {
+	webhooks := []models.Webhook{}
+
+	dbErr := repo.reader(ctx).Where("user_id = ?", userID).Order("id").Find(&webhooks).Error
+	if dbErr != nil {
+		logger.Ctx(ctx).Errorf("error getting webhooks: %v", dbErr)
+		return nil, apperrors.Internal("Error getting webhooks.", dbErr)
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook of a user along with its deliveries.
+func (repo Repository) DeleteWebhook(ctx context.Context, userID string, id uint64) error {
+	result := repo.Database.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&models.Webhook{})
+	if result.Error != nil {
+		logger.Ctx(ctx).Errorf("error deleting webhook: %v", result.Error)
+		return apperrors.Internal("Error deleting webhook.", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return apperrors.NotFound("Webhook not found.")
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook of a user, newest first.
+func (repo Repository) GetWebhookDeliveries(ctx context.Context, userID string, webhookID uint64) ([]models.WebhookDelivery, error) {
+	var count int64
+	dbErr := repo.reader(ctx).Model(&models.Webhook{}).Where("user_id = ? AND id = ?", userID, webhookID).Count(&count).Error
+	if dbErr != nil {
+		logger.Ctx(ctx).Errorf("error getting webhook: %v", dbErr)
+		return nil, apperrors.Internal("Error getting webhook.", dbErr)
+	}
+
+	if count == 0 {
+		return nil, apperrors.NotFound("Webhook not found.")
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	dbErr = repo.reader(ctx).Where("webhook_id = ?", webhookID).Order("id DESC").Limit(webhookDeliveriesShown).Find(&deliveries).Error
+	if dbErr != nil {
+		logger.Ctx(ctx).Errorf("error getting webhook deliveries: %v", dbErr)
+		return nil, apperrors.Internal("Error getting webhook deliveries.", dbErr)
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhook queues the event of an earlier delivery to be sent again, as a new delivery with its own attempts.
+func (repo Repository) RedeliverWebhook(ctx context.Context, userID string, webhookID uint64, deliveryID uint64) (*models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	now := time.Now()
+
+	dbErr := repo.Database.WithContext(ctx).Raw(
+		`INSERT INTO webhook_deliveries (webhook_id, event_type, payload, status, next_attempt_at, created_at) `+
+			`SELECT d.webhook_id, d.event_type, d.payload, ?, ?, ? FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id `+
+			`WHERE d.id = ? AND d.webhook_id = ? AND w.user_id = ? RETURNING *`,
+		models.DeliveryPending, now, now, deliveryID, webhookID, userID,
+	).Scan(&deliveries).Error
+	if dbErr != nil {
+		logger.Ctx(ctx).Errorf("error queueing webhook delivery: %v", dbErr)
+		return nil, apperrors.Internal("Error queueing webhook delivery.", dbErr)
+	}
+
+	if len(deliveries) == 0 {
+		return nil, apperrors.NotFound("Webhook delivery not found.")
+	}
+
+	return &deliveries[0], nil
+}
+
+// QueueWebhooks queues a delivery of each event for every webhook of the user whose filters match it. Failures are
+// only logged since the changes themselves are already saved.
+func (repo Repository) QueueWebhooks(ctx context.Context, userID string, events ...models.EntityEvent) {
+	for _, event := range events {
+		payload, err := models.WebhookPayload{EntityEvent: event}.Value()
+		if err != nil {
+			logger.Ctx(ctx).Errorf("error encoding webhook payload: %v", err)
+			continue
+		}
+
+		now := time.Now()
+		dbErr := repo.Database.WithContext(ctx).Exec(
+			`INSERT INTO webhook_deliveries (webhook_id, event_type, payload, status, next_attempt_at, created_at) `+
+				`SELECT id, ?, CAST(? AS jsonb), ?, ?, ? FROM webhooks WHERE user_id = ? `+
+				`AND (jsonb_array_length(events) = 0 OR events @> jsonb_build_array(CAST(? AS text))) `+
+				`AND (jsonb_array_length(categories) = 0 OR categories @> jsonb_build_array(CAST(? AS text)))`,
+			event.Type, payload, models.DeliveryPending, now, now, userID, event.Type, event.Category,
+		).Error
+		if dbErr != nil {
+			logger.Ctx(ctx).Errorf("error queueing webhook deliveries: %v", dbErr)
+		}
+	}
+}
+
+// ClaimWebhookDeliveries takes up to limit pending deliveries that are due and holds them for lease, so no other
+// dispatcher sends them in the meantime. A delivery that isn't recorded before the lease runs out is tried again.
+func (repo Repository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueWebhookDelivery, error) {
+	var deliveries []DueWebhookDelivery
+	now := time.Now()
+
+	dbErr := repo.Database.WithContext(ctx).Raw(
+		`UPDATE webhook_deliveries d SET next_attempt_at = ? FROM webhooks w WHERE w.id = d.webhook_id AND d.id IN `+
+			`(SELECT id FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED) `+
+			`RETURNING d.*, w.url, w.secret`,
+		now.Add(lease), models.DeliveryPending, now, limit,
+	).Scan(&deliveries).Error
+	if dbErr != nil {
+		logger.Ctx(ctx).Errorf("error claiming webhook deliveries: %v", dbErr)
+		return nil, apperrors.Internal("Error claiming webhook deliveries.", dbErr)
+	}
+
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt saves how an attempt at a delivery went. A delivery that failed is tried again at
+// retryAt, or marked failed when retryAt is nil.
+func (repo Repository) RecordWebhookAttempt(ctx context.Context, delivery models.WebhookDelivery, statusCode int, attemptErr error, retryAt *time.Time) error {
+	updates := map[string]interface{}{
+		"attempts":    delivery.Attempts + 1,
+		"last_status": statusCode,
+		"last_error":  "",
+	}
+
+	switch {
+	case attemptErr == nil:
+		updates["status"] = models.DeliverySucceeded
+		updates["delivered_at"] = time.Now()
+		updates["next_attempt_at"] = nil
+	case retryAt != nil:
+		updates["last_error"] = attemptErr.Error()
+		updates["next_attempt_at"] = *retryAt
+	default:
+		updates["status"] = models.DeliveryFailed
+		updates["last_error"] = attemptErr.Error()
+		updates["next_attempt_at"] = nil
+	}
+
+	dbErr := repo.Database.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error
+	if dbErr != nil {
+		logger.Ctx(ctx).Errorf("error recording webhook delivery: %v", dbErr)
+		return apperrors.Internal("Error recording webhook delivery.", dbErr)
+	}
+
+	return nil
+}
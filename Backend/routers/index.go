@@ -2,7 +2,7 @@
 package routers
 
 import (
-	"net/http"
+	"net/netip"
 	"willowsuite-vault/controllers"
 	"willowsuite-vault/helpers"
 	"willowsuite-vault/infra/cache"
@@ -16,47 +16,87 @@ import (
 )
 
 // RegisterRoutes add all routing list here automatically get main router
-func RegisterRoutes(r *chi.Mux) {
+func RegisterRoutes(r *chi.Mux, proxies []netip.Prefix) {
 	handler := controllers.Handler{
 		Repository: &repository.Repository{
 			Database: database.GetDB(),
-			Cache:    cache.GetClient(),
+			Cache:    cache.GetCache(),
+			Redis:    cache.GetClient(),
 		},
 		CognitoClient:   cognito.GetClient(),
 		S3Client:        s3.GetClient(),
 		S3PresignClient: s3.GetPresignClient(),
 		TokenHelper:     &helpers.DefaultTokenHelper{},
+		TrustedProxies:  proxies,
 	}
 
-	r.Get("/", func(w http.ResponseWriter, _ *http.Request) {
-		helpers.SuccessResponse(w, "alive ok")
-	})
+	// Liveness and readiness probes
+	r.Get("/", handler.Healthz)
+	r.Get("/healthz", handler.Healthz)
+	r.Get("/readyz", handler.Readyz)
+
+	// Prometheus metrics, read with METRICS_TOKEN rather than a user's sign in
+	r.Get("/metrics", handler.Metrics)
 
 	// v1 api routes
 	r.Route("/v1", func(r chi.Router) {
 
-		// Users
-		r.Post("/user", handler.SignUp)
-		r.Put("/user", handler.ConfirmSignUp)
-		r.Post("/token", handler.SignIn)
-		r.Put("/token", handler.Refresh)
-		r.Delete("/token", handler.LogOut)
+		// Documentation
+		r.Get("/openapi.json", handler.OpenAPISpec)
+		r.Get("/docs", handler.Docs)
+		r.Get("/docs/{file}", handler.DocsAsset)
+
+		// Users, limited per IP since their callers aren't signed in yet
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RateLimit(handler, "auth", middlewares.ByIP))
+			r.Use(middlewares.Idempotency(handler))
+
+			r.Post("/user", handler.SignUp)
+			r.Put("/user", handler.ConfirmSignUp)
+			r.With(middlewares.RateLimit(handler, "signin", middlewares.ByEmail)).Post("/token", handler.SignIn)
+			r.Put("/token", handler.Refresh)
+			r.Delete("/token", handler.LogOut)
+			r.With(middlewares.RateLimit(handler, "signin", middlewares.ByEmail)).Post("/token/challenge", handler.RespondToChallenge)
+
+			// MFA enrolment accepts either an access token or a sign in session.
+			r.Post("/user/mfa", handler.AssociateSoftwareToken)
+			r.Put("/user/mfa", handler.VerifySoftwareToken)
+		})
 
 		// Protected endpoints
 		r.Group(func(r chi.Router) {
 			r.Use(middlewares.JWTAuth(handler))
+			r.Use(middlewares.RateLimit(handler, "api", middlewares.ByUser))
+			r.Use(middlewares.Idempotency(handler))
+			if database.HasReplicas() {
+				r.Use(middlewares.ReadYourWrites(handler))
+			}
 
 			// Entities
 			r.Post("/entity", handler.CreateEntity)
 			r.Put("/entity", handler.EditEntity)
 			r.Get("/entity/{category}/{id}", handler.GetEntity)
+			r.Patch("/entity/{category}/{id}", handler.PatchEntity)
 			r.Delete("/entity/{category}/{id}", handler.DeleteEntity)
 			r.Get("/entities", handler.GetEntities)
+			r.Post("/entities/batch", handler.BatchEntities)
 			r.Get("/parents/{category}", handler.GetParents)
 			r.Get("/children/{category}/{id}", handler.GetChildren)
+			r.Get("/events", handler.Events)
+
+			// Webhooks
+			r.Post("/webhooks", handler.CreateWebhook)
+			r.Get("/webhooks", handler.GetWebhooks)
+			r.Delete("/webhooks/{id}", handler.DeleteWebhook)
+			r.Get("/webhooks/{id}/deliveries", handler.GetWebhookDeliveries)
+			r.Post("/webhooks/{id}/deliveries/{deliveryID}/redeliver", handler.RedeliverWebhook)
+
+			// Sessions
+			r.Get("/sessions", handler.GetSessions)
+			r.Delete("/sessions", handler.LogOutAllDevices)
 
 			//QR Code
-			r.Post("/qr", handler.Generate)
+			r.With(middlewares.RateLimit(handler, "qr", middlewares.ByUser)).Post("/qr", handler.Generate)
 		})
 
 	})
@@ -0,0 +1,158 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key accepted.
+const maxIdempotencyKeyLength = 255
+
+// rateLimitHeaders are the headers RateLimit sets, which are left out of stored responses.
+var rateLimitHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"}
+
+// recordingWriter passes a response through to the client while keeping a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// Idempotency replays the stored response when a POST, PUT, PATCH or DELETE is retried with the same
+// Idempotency-Key, instead of running it again. Keys belong to the user that sent them, or to the client's IP on
+// routes used before signing in, and reusing one for a different request is refused. Responses with a 5xx or 429
+// status aren't kept so the request can be retried.
+func Idempotency(handler controllers.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" || !isUnsafe(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				helpers.ErrorResponse(w, r, apperrors.BadRequest("Idempotency-Key must be at most "+strconv.Itoa(maxIdempotencyKeyLength)+" characters.", nil))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				helpers.ErrorResponse(w, r, apperrors.BadRequest("Error parsing request", err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := repository.IdempotencyScope{}
+			if claims, ok := r.Context().Value("user_claims").(jwt.MapClaims); ok {
+				scope.UserID, _ = claims["username"].(string)
+			}
+			if scope.UserID == "" {
+				scope.IP = helpers.ClientIP(r, handler.TrustedProxies)
+			}
+			fingerprint := requestFingerprint(r, body)
+
+			stored, err := handler.Repository.ClaimIdempotencyKey(r.Context(), scope, key, fingerprint)
+			if err != nil {
+				helpers.ErrorResponse(w, r, err)
+				return
+			}
+
+			if stored != nil {
+				replay(w, r, stored, fingerprint)
+				return
+			}
+
+			recorder := &recordingWriter{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusInternalServerError || recorder.status == http.StatusTooManyRequests {
+				handler.Repository.ReleaseIdempotencyKey(r.Context(), scope, key)
+				return
+			}
+
+			// Rate limits are counted afresh for every request, the retry gets its own.
+			header := w.Header().Clone()
+			for _, name := range rateLimitHeaders {
+				header.Del(name)
+			}
+
+			response := models.IdempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      recorder.status,
+				Header:      header,
+				Body:        recorder.body.Bytes(),
+			}
+			if err := handler.Repository.SaveIdempotentResponse(r.Context(), scope, key, response); err != nil {
+				logger.Ctx(r.Context()).Warnf("%s %s: %s", r.Method, r.URL.Path, err)
+			}
+		})
+	}
+}
+
+// replay answers a retried request with the response stored for its Idempotency-Key.
+func replay(w http.ResponseWriter, r *http.Request, stored *models.IdempotentResponse, fingerprint string) {
+	if stored.Fingerprint != fingerprint {
+		errs := models.ValidationErrors{}
+		errs.Add("Idempotency-Key", models.ValidationKeyReused, "This Idempotency-Key was already used for a different request.")
+		helpers.ErrorResponse(w, r, apperrors.Validation(errs))
+		return
+	}
+
+	if !stored.Done {
+		helpers.ErrorResponse(w, r, apperrors.Conflict("A request with this Idempotency-Key is still being handled."))
+		return
+	}
+
+	for name, values := range stored.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// requestFingerprint identifies a request by its method, path, the headers that change what it does and its body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write([]byte(r.Header.Get("Content-Type") + "\n" + r.Header.Get("If-Match") + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func isUnsafe(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
@@ -5,8 +5,12 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"willowsuite-vault/apperrors"
 	"willowsuite-vault/controllers"
 	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/infra/tracing"
+	"willowsuite-vault/models"
 )
 
 func JWTAuth(handler controllers.Handler) func(http.Handler) http.Handler {
@@ -15,51 +19,87 @@ func JWTAuth(handler controllers.Handler) func(http.Handler) http.Handler {
 			// Get the JWT from the Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				helpers.BadRequest(w, "Missing Authorization Header")
+				helpers.ErrorResponse(w, r, apperrors.Unauthorized("Missing Authorization Header", nil))
 				return
 			}
 
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 			if tokenString == authHeader {
-				helpers.BadRequest(w, "Invalid Authorization header format")
+				helpers.ErrorResponse(w, r, apperrors.Unauthorized("Invalid Authorization header format", nil))
 				return
 			}
 
+			// Verifying fetches Cognito's signing keys, so it gets a span of its own.
+			_, span := tracing.Tracer().Start(r.Context(), "cognito.VerifyToken")
 			token, err := handler.TokenHelper.VerifyToken(tokenString, true)
+			span.End()
 			if err != nil && strings.Contains(err.Error(), "Failed to get JWKS") {
-				helpers.BadRequest(w, err)
+				helpers.ErrorResponse(w, r, apperrors.Upstream("cognito", "Failed to get JWKS", err))
 				return
 			} else if err != nil {
-				helpers.UnaunthorizedRequest(w, err)
+				helpers.ErrorResponse(w, r, apperrors.Unauthorized("Invalid token", err))
 				return
 			}
 
 			claims, err := handler.TokenHelper.ExtractClaims(token)
 			if err != nil {
-				helpers.UnaunthorizedRequest(w, err)
+				helpers.ErrorResponse(w, r, apperrors.Unauthorized("Invalid token claims", err))
 				return
 			}
 
 			// Verify token_use claim for access tokens
 			if claims["token_use"] != "access" {
-				http.Error(w, "Invalid token use", http.StatusUnauthorized)
+				helpers.ErrorResponse(w, r, apperrors.Unauthorized("Invalid token use", nil))
 				return
 			}
 
 			// Check expiration
 			exp, ok := claims["exp"].(float64)
 			if !ok {
-				http.Error(w, "Invalid exp claim", http.StatusUnauthorized)
+				helpers.ErrorResponse(w, r, apperrors.Unauthorized("Invalid exp claim", nil))
 				return
 			}
 
 			isRefreshRequest := (r.Method == "PUT" && strings.Contains(r.URL.Path, "token"))
 
 			if time.Now().Unix() > int64(exp) && !isRefreshRequest {
-				http.Error(w, "Token has expired", http.StatusUnauthorized)
+				helpers.ErrorResponse(w, r, apperrors.Unauthorized("Token has expired", nil))
 				return
 			}
 
+			// Reject tokens revoked by log out
+			userID, _ := claims["username"].(string)
+			jti, _ := claims["jti"].(string)
+			originJTI, _ := claims["origin_jti"].(string)
+			iat, _ := claims["iat"].(float64)
+			logger.AddFields(r.Context(), logger.Fields{"user_id": userID})
+
+			revoked, err := handler.Repository.IsTokenRevoked(r.Context(), userID, []string{jti, originJTI}, int64(iat))
+			if err != nil {
+				helpers.ErrorResponse(w, r, err)
+				return
+			}
+
+			if revoked {
+				helpers.ErrorResponse(w, r, apperrors.Unauthorized("Token has been revoked", nil))
+				return
+			}
+
+			sessionID := originJTI
+			if sessionID == "" {
+				sessionID = jti
+			}
+
+			err = handler.Repository.TouchSession(r.Context(), userID, models.Session{
+				ID:         sessionID,
+				UserAgent:  r.UserAgent(),
+				IPAddress:  helpers.ClientIP(r, handler.TrustedProxies),
+				LastSeenAt: time.Now(),
+			})
+			if err != nil {
+				logger.Ctx(r.Context()).Errorf("error tracking session: %v", err)
+			}
+
 			// Add claims to request context
 			ctx := r.Context()
 			ctx = context.WithValue(ctx, "user_claims", claims)
@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"willowsuite-vault/apperrors"
+	"willowsuite-vault/config"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RateLimitBy is what the requests a rate limit counts belong to.
+type RateLimitBy int
+
+const (
+	// ByIP counts requests per client IP, for routes used before signing in.
+	ByIP RateLimitBy = iota
+	// ByUser counts requests per signed in user. It has to come after JWTAuth.
+	ByUser
+	// ByEmail counts requests per userEmail in the JSON body, so guessing one account's password or MFA code is
+	// limited however many addresses it comes from. Requests without one aren't counted.
+	ByEmail
+)
+
+// RateLimit refuses requests to a group of routes with 429 once a client has made RATE_LIMIT_<GROUP>_REQUESTS of
+// them within the sliding RATE_LIMIT_<GROUP>_WINDOW. Every response carries the RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers, and refusals also carry Retry-After. Requests
+// are let through when Redis can't be reached, so an outage of the limiter isn't an outage of the API.
+func RateLimit(handler controllers.Handler, group string, by RateLimitBy) func(http.Handler) http.Handler {
+	limit := config.RateLimitRequests(group)
+	window := config.RateLimitWindow(group)
+
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 || window <= 0 {
+			return next
+		}
+
+		policy := fmt.Sprintf("%d;w=%d", limit, int(math.Ceil(window.Seconds())))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject := repository.RateLimitSubject{}
+			switch by {
+			case ByUser:
+				claims := r.Context().Value("user_claims").(jwt.MapClaims)
+				subject.UserID = claims["username"].(string)
+			case ByEmail:
+				subject.Email = bodyEmail(r)
+				if subject.Email == "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+			default:
+				subject.IP = helpers.ClientIP(r, handler.TrustedProxies)
+			}
+
+			result, err := handler.Repository.TakeRateLimit(r.Context(), group, subject, limit, window)
+			if err != nil {
+				// While the breaker is open the outage has been logged already.
+				if !errors.Is(err, cache.ErrOpen) {
+					logger.Ctx(r.Context()).Warnf("Error checking the %s rate limit, letting the request through: %v", group, err)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reset := int(math.Ceil(result.Reset.Seconds()))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(reset))
+			w.Header().Set("RateLimit-Policy", policy)
+
+			if !result.Allowed {
+				retryAfter := max(reset, 1)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				helpers.ErrorResponse(w, r, apperrors.TooManyRequests(fmt.Sprintf("Too many requests, try again in %d seconds.", retryAfter)))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bodyEmail returns the userEmail of a JSON request body, leaving the body to be read again by the handler.
+func bodyEmail(r *http.Request) string {
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	fields := struct {
+		UserEmail string `json:"userEmail"`
+	}{}
+	json.Unmarshal(body, &fields)
+
+	return fields.UserEmail
+}
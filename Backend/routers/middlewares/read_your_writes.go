@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"willowsuite-vault/config"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/repository"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ReadYourWrites sends a user's reads to the primary for config.ReadPrimaryWindow after they write, so they see
+// their changes even while the replicas catch up, and none of their stale reads are cached. A POST, PUT, PATCH or
+// DELETE reads from the primary throughout, as it checks what it is about to change. A write that fails takes its
+// mark back, unless an earlier write left one.
+func ReadYourWrites(handler controllers.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := r.Context().Value("user_claims").(jwt.MapClaims)
+			userID := claims["username"].(string)
+
+			if !isUnsafe(r.Method) {
+				if handler.Repository.WroteRecently(r.Context(), userID) {
+					r = r.WithContext(repository.WithPrimaryReads(r.Context()))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// The mark goes in first: the handler may flush its response, and the client read again, before it
+			// returns.
+			marked := handler.Repository.MarkWritten(r.Context(), userID, config.ReadPrimaryWindow())
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(repository.WithPrimaryReads(r.Context())))
+
+			if marked && ww.Status() >= http.StatusBadRequest {
+				handler.Repository.ClearWritten(r.Context(), userID)
+			}
+		})
+	}
+}
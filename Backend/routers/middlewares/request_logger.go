@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/netip"
+	"time"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/logger"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestLogger gives every request a set of log fields, starting with its request ID, method and path, that
+// the lines logged with logger.Ctx during the request carry. Once the request is handled it logs one line with
+// its route pattern, status, size, latency and client address, which is worked out with proxies.
+func RequestLogger(proxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			ctx := logger.NewContext(r.Context())
+			logger.AddFields(ctx, logger.Fields{
+				"request_id": middleware.GetReqID(ctx),
+				"method":     r.Method,
+				"path":       r.URL.Path,
+			})
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			fields := logger.Fields{
+				"status":     status,
+				"bytes":      ww.BytesWritten(),
+				"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+				"remote_ip":  helpers.ClientIP(r, proxies),
+			}
+			if routeContext := chi.RouteContext(ctx); routeContext != nil && routeContext.RoutePattern() != "" {
+				fields["route"] = routeContext.RoutePattern()
+			}
+
+			entry := logger.Ctx(ctx).WithFields(fields)
+			switch {
+			case status >= http.StatusInternalServerError:
+				entry.Errorf("request completed")
+			case status >= http.StatusBadRequest:
+				entry.Warnf("request completed")
+			default:
+				entry.Infof("request completed")
+			}
+		})
+	}
+}
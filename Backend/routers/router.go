@@ -2,20 +2,26 @@
 package routers
 
 import (
+	"net/netip"
+	"willowsuite-vault/infra/metrics"
+	"willowsuite-vault/infra/tracing"
 	"willowsuite-vault/routers/middlewares"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// SetupRoute configures our Chi Router.
-func SetupRoute() *chi.Mux {
+// SetupRoute configures our Chi Router. proxies are the parsed TRUSTED_PROXIES client addresses are worked out with.
+func SetupRoute(proxies []netip.Prefix) *chi.Mux {
 
 	router := chi.NewRouter()
-	router.Use(middleware.Logger)
+	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middlewares.RequestLogger(proxies))
 	router.Use(middleware.Recoverer)
+	router.Use(metrics.Middleware)
 	router.Use(middlewares.Cors())
-	RegisterRoutes(router) //routes register
+	RegisterRoutes(router, proxies) //routes register
 
 	return router
 }
@@ -0,0 +1,261 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+)
+
+type batchEntitiesResponse struct {
+	Message string               `json:"message"`
+	Data    models.BatchResponse `json:"data"`
+}
+
+var batchEntitiesEndpoint = "/v1/entities/batch"
+
+func setupBatchEntitiesTest(t *testing.T, userName string) (*http.Client, *httptest.Server, sqlmock.Sqlmock, redismock.ClientMock) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	postgres, mockDB := mocks.NewMockDB()
+	redis, mockCache := redismock.NewClientMock()
+	handler := controllers.Handler{
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
+		CognitoClient: mocks.NewMockCognitoClient(ctrl),
+		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+	}
+
+	r := chi.NewRouter()
+	r.Use(mocks.MockJWTMiddleware(userName))
+	r.Post("/v1/entities/batch", handler.BatchEntities)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &http.Client{}, srv, mockDB, mockCache
+}
+
+func expectSavepoint(mockDB sqlmock.Sqlmock) {
+	mockDB.ExpectExec(`^SAVEPOINT sp`).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectRollbackToSavepoint(mockDB sqlmock.Sqlmock) {
+	mockDB.ExpectExec(`^ROLLBACK TO SAVEPOINT sp`).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func sendBatch(t *testing.T, client *http.Client, url string, body string) (*http.Response, []byte) {
+	res, err := client.Post(url+batchEntitiesEndpoint, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	return res, data
+}
+
+// TestBatchEntities runs the unit tests for running several entity operations in one request.
+func TestBatchEntities(t *testing.T) {
+	insertQuery := regexp.QuoteMeta(`INSERT INTO "entities" ("category","name","notes","user_id","created_at","updated_at","deleted_at","version","parent_category","parent_id","attributes") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING "id"`)
+
+	t.Run("BEUT-188: Batch Creates A Parent And Child By Ref", func(t *testing.T) {
+		client, srv, mockDB, mockCache := setupBatchEntitiesTest(t, "testuser")
+
+		address := "1 Main Street"
+		attributes, _ := models.EntityAttributes{Address: &address}.Value()
+
+		mockDB.ExpectBegin()
+		expectSavepoint(mockDB)
+		mockDB.ExpectQuery(insertQuery).
+			WithArgs("building", "Home", nil, "testuser", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, nil, nil, attributes).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(50))
+		expectSavepoint(mockDB)
+		expectParentCheck(mockDB, "building", 50, sqlmock.NewRows([]string{"user_id", "deleted_at"}).AddRow("testuser", nil))
+		mockDB.ExpectQuery(insertQuery).
+			WithArgs("room", "Closet", nil, "testuser", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, "building", 50, "{}").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(51))
+		mockDB.ExpectCommit()
+
+		// The cache is flushed once for the whole batch
+		expectEntitiesFlush(mockCache, "testuser")
+		expectEntityEvents(mockCache, "testuser", models.EventCreate, models.EventCreate)
+		expectWebhooksQueued(mockDB, "testuser", models.EventCreate, models.EventCreate)
+
+		res, data := sendBatch(t, client, srv.URL, `{"operations":[`+
+			`{"op":"create","ref":"home","name":"Home","category":"building","address":"1 Main Street"},`+
+			`{"op":"create","name":"Closet","category":"room","parentRef":"home","parentCategory":"building"}]}`)
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code to be: %d. Got: %d. %s", http.StatusOK, res.StatusCode, data)
+		}
+
+		contents := batchEntitiesResponse{}
+		if err := json.Unmarshal(data, &contents); err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		if contents.Data.Mode != models.BatchAtomic || len(contents.Data.Results) != 2 || contents.Data.Results[1].Status != http.StatusOK {
+			t.Errorf("Expected two successful results in atomic mode. Got: %+v", contents.Data)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-189: Atomic Batch Rolls Back On Failure", func(t *testing.T) {
+		client, srv, mockDB, mockCache := setupBatchEntitiesTest(t, "testuser")
+
+		mockDB.ExpectBegin()
+		expectSavepoint(mockDB)
+		expectParentCheck(mockDB, "container", 10, sqlmock.NewRows([]string{"user_id", "deleted_at"}))
+		expectRollbackToSavepoint(mockDB)
+		mockDB.ExpectRollback()
+
+		res, data := sendBatch(t, client, srv.URL, `{"mode":"atomic","operations":[`+
+			`{"op":"create","name":"Scarf","category":"item","parentID":10,"parentCategory":"container"},`+
+			`{"op":"delete","category":"item","id":11,"version":2}]}`)
+
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusNotFound, res.StatusCode)
+		}
+
+		problem := helpers.Problem{}
+		if err := json.Unmarshal(data, &problem); err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		if expected := "Operation 0: Parent category of container with id 10 not found."; problem.Detail != expected {
+			t.Errorf("Expected detail to be %s. Got: %s", expected, problem.Detail)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-190: Independent Batch Reports Each Operation", func(t *testing.T) {
+		client, srv, mockDB, mockCache := setupBatchEntitiesTest(t, "testuser")
+
+		mockDB.ExpectBegin()
+
+		// The first create fails and is rolled back to its savepoint
+		expectSavepoint(mockDB)
+		expectSavepoint(mockDB)
+		expectParentCheck(mockDB, "shelf", 10, sqlmock.NewRows([]string{"user_id", "deleted_at"}))
+		expectRollbackToSavepoint(mockDB)
+		expectRollbackToSavepoint(mockDB)
+
+		// The child of the failed create fails without touching the database
+		expectSavepoint(mockDB)
+		expectRollbackToSavepoint(mockDB)
+
+		// The delete still goes through
+		expectSavepoint(mockDB)
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "entities" WHERE user_id = $1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name", "user_id", "version", "attributes"}).
+				AddRow("item", 11, "Old Sock", "testuser", 2, "{}"))
+		mockDB.ExpectExec(regexp.QuoteMeta(`UPDATE "entities" SET "deleted_at"=$1 WHERE user_id = $2 AND version = $3`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		expectEntitiesFlush(mockCache, "testuser")
+		expectEntityEvents(mockCache, "testuser", models.EventDelete)
+		expectWebhooksQueued(mockDB, "testuser", models.EventDelete)
+
+		res, data := sendBatch(t, client, srv.URL, `{"mode":"independent","operations":[`+
+			`{"op":"create","ref":"box","name":"Box","category":"container","parentID":10,"parentCategory":"shelf"},`+
+			`{"op":"create","name":"Scarf","category":"item","parentRef":"box","parentCategory":"container"},`+
+			`{"op":"delete","category":"item","id":11,"version":2}]}`)
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code to be: %d. Got: %d. %s", http.StatusOK, res.StatusCode, data)
+		}
+
+		contents := batchEntitiesResponse{}
+		if err := json.Unmarshal(data, &contents); err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		expected := []int{http.StatusNotFound, http.StatusConflict, http.StatusOK}
+		for i, result := range contents.Data.Results {
+			if result.Status != expected[i] {
+				t.Errorf("Expected operation %d to end with %d. Got: %d", i, expected[i], result.Status)
+			}
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-191: Batch Validates Every Operation", func(t *testing.T) {
+		client, srv, mockDB, _ := setupBatchEntitiesTest(t, "testuser")
+
+		res, data := sendBatch(t, client, srv.URL, `{"operations":[`+
+			`{"op":"create","ref":"box","name":"Box","category":"container","parentID":10,"parentCategory":"shelf"},`+
+			`{"op":"create","name":"Scarf","category":"item","parentRef":"bag","parentCategory":"container"},`+
+			`{"op":"update","id":11,"name":"Sock","category":"item","parentID":10,"parentCategory":"container"},`+
+			`{"op":"rename"}]}`)
+
+		if res.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status code to be: %d. Got: %d.", http.StatusUnprocessableEntity, res.StatusCode)
+		}
+
+		contents := validationResponse{}
+		if err := json.Unmarshal(data, &contents); err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		expected := models.ValidationErrors{
+			{Field: "operations[1].parentRef", Code: models.ValidationInvalidChoice},
+			{Field: "operations[2].version", Code: models.ValidationRequired},
+			{Field: "operations[3].op", Code: models.ValidationInvalidChoice},
+		}
+		if len(contents.Data) != len(expected) {
+			t.Fatalf("Expected %d field errors. Got: %v", len(expected), contents.Data)
+		}
+
+		for i, fieldErr := range expected {
+			if contents.Data[i].Field != fieldErr.Field || contents.Data[i].Code != fieldErr.Code {
+				t.Errorf("Expected %s %s. Got: %s %s", fieldErr.Field, fieldErr.Code, contents.Data[i].Field, contents.Data[i].Code)
+			}
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v9"
+)
+
+func lookupStats(function string) cache.LookupStats {
+	for _, stats := range cache.Lookups() {
+		if stats.Function == function {
+			return stats
+		}
+	}
+
+	return cache.LookupStats{Function: function}
+}
+
+// flakyCache is a cache that fails every call while down is set, counting the calls that reach it.
+type flakyCache struct {
+	*cache.LRUCache
+	down  bool
+	calls int
+}
+
+func (c *flakyCache) Get(ctx context.Context, key string) (string, error) {
+	c.calls++
+	if c.down {
+		return "", errors.New("connection refused")
+	}
+	return c.LRUCache.Get(ctx, key)
+}
+
+func (c *flakyCache) Incr(ctx context.Context, key string) (int64, error) {
+	c.calls++
+	if c.down {
+		return 0, errors.New("connection refused")
+	}
+	return c.LRUCache.Incr(ctx, key)
+}
+
+// TestCache runs the unit tests for invalidating cached entities and counting cache hits.
+func TestCache(t *testing.T) {
+	t.Run("BEUT-207: Flushing Moves To A New Generation", func(t *testing.T) {
+		client, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Cache: cache.NewRedisCache(client), Redis: client}
+
+		// One INCR, no matter how many keys the user has cached
+		mockCache.ExpectIncr(`{"User":"cacheuser","Function":"EntitiesGeneration"}`).SetVal(5)
+
+		repo.FlushEntities(context.Background(), "cacheuser")
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-208: Reads Use The Current Generation", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+		client, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Database: postgres, Cache: cache.NewRedisCache(client), Redis: client}
+
+		mockCache.ExpectGet(parentsGenerationKey("cacheuser")).SetVal("5")
+		mockCache.ExpectGet(parentsCacheKey("cacheuser", "shelving_unit", 5)).SetVal(`[{"ID":11,"Name":"Closet","Category":"room"}]`)
+
+		parents, err := repo.GetParents(context.Background(), "shelving_unit", "cacheuser")
+		if err != nil || len(parents) != 1 || parents[0].Name != "Closet" {
+			t.Errorf("Expected the parents cached at generation 5. Got: %v, %v", parents, err)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-209: Hits And Misses Are Counted", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+		client, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Database: postgres, Cache: cache.NewRedisCache(client), Redis: client}
+		before := lookupStats("CountEntities")
+
+		countKey := `{"CacheKey":{"User":"cacheuser","Function":"CountEntities"},"Generation":1,"Search":"","Filters":null}`
+		mockCache.ExpectGet(parentsGenerationKey("cacheuser")).SetVal("1")
+		mockCache.ExpectGet(countKey).RedisNil()
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM entities WHERE user_id = $1 AND deleted_at IS NULL`)).
+			WithArgs("cacheuser").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+		mockCache.ExpectSet(countKey, "4", 5*time.Minute).SetVal("OK")
+		mockCache.ExpectGet(parentsGenerationKey("cacheuser")).SetVal("1")
+		mockCache.ExpectGet(countKey).SetVal("4")
+
+		for i := 0; i < 2; i++ {
+			if count := repo.CountEntities(context.Background(), "cacheuser", "", nil); count != 4 {
+				t.Errorf("Expected 4 entities. Got: %d", count)
+			}
+		}
+
+		after := lookupStats("CountEntities")
+		if after.Hits != before.Hits+1 || after.Misses != before.Misses+1 {
+			t.Errorf("Expected one more hit and miss. Got: %+v then %+v", before, after)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-210: The In-Process Cache Drops The Least Recently Used", func(t *testing.T) {
+		ctx := context.Background()
+		lru := cache.NewLRUCache(2)
+
+		lru.Set(ctx, "a", "1", time.Minute)
+		lru.Set(ctx, "b", "2", time.Minute)
+		lru.Get(ctx, "a")
+		lru.Set(ctx, "c", "3", time.Minute)
+
+		if _, err := lru.Get(ctx, "b"); !errors.Is(err, cache.ErrMiss) {
+			t.Errorf("Expected b to have been dropped. Got: %v", err)
+		}
+
+		if value, err := lru.Get(ctx, "a"); err != nil || value != "1" {
+			t.Errorf("Expected a to still be cached. Got: %q, %v", value, err)
+		}
+
+		lru.Set(ctx, "a", "1", -time.Second)
+		if _, err := lru.Get(ctx, "a"); !errors.Is(err, cache.ErrMiss) {
+			t.Errorf("Expected an expired entry to miss. Got: %v", err)
+		}
+
+		for i := 1; i <= 3; i++ {
+			if count, err := lru.Incr(ctx, "counter"); err != nil || count != int64(i) {
+				t.Errorf("Expected the counter to be %d. Got: %d, %v", i, count, err)
+			}
+		}
+
+		if _, err := (cache.NoopCache{}).Get(ctx, "counter"); !errors.Is(err, cache.ErrMiss) {
+			t.Errorf("Expected the no-op cache to always miss. Got: %v", err)
+		}
+	})
+
+	t.Run("BEUT-211: The Breaker Opens And Closes Again", func(t *testing.T) {
+		ctx := context.Background()
+		flaky := &flakyCache{LRUCache: cache.NewLRUCache(10), down: true}
+		breaker := cache.NewBreaker(flaky)
+		breaker.Cooldown = 20 * time.Millisecond
+
+		for i := 0; i < 10; i++ {
+			breaker.Get(ctx, "key")
+		}
+
+		if flaky.calls != breaker.Threshold || !breaker.Open() {
+			t.Errorf("Expected the breaker to open after %d calls. Got: %d calls, open %v", breaker.Threshold, flaky.calls, breaker.Open())
+		}
+
+		if _, err := breaker.Get(ctx, "key"); !errors.Is(err, cache.ErrOpen) {
+			t.Errorf("Expected an open breaker to turn calls away. Got: %v", err)
+		}
+
+		flaky.down = false
+		time.Sleep(30 * time.Millisecond)
+
+		if _, err := breaker.Get(ctx, "key"); !errors.Is(err, cache.ErrMiss) || breaker.Open() {
+			t.Errorf("Expected the breaker to close once the cache answers. Got: %v, open %v", err, breaker.Open())
+		}
+	})
+
+	t.Run("BEUT-212: Flushes Missed During An Outage Are Made Up", func(t *testing.T) {
+		ctx := context.Background()
+		flaky := &flakyCache{LRUCache: cache.NewLRUCache(10)}
+		repo := repository.Repository{Cache: cache.NewBreaker(flaky)}
+		key := `{"User":"cacheuser","Function":"EntitiesGeneration"}`
+
+		repo.FlushEntities(ctx, "cacheuser")
+
+		flaky.down = true
+		repo.FlushEntities(ctx, "cacheuser")
+
+		flaky.down = false
+		if generation, _ := flaky.LRUCache.Get(ctx, key); generation != "1" {
+			t.Fatalf("Expected the flush during the outage to be missed. Got generation %s", generation)
+		}
+
+		// The next call that works makes up the missed flush
+		repo.Cache.Get(ctx, "anything")
+
+		if generation, _ := flaky.LRUCache.Get(ctx, key); generation != "2" {
+			t.Errorf("Expected the missed flush to be made up. Got generation %s", generation)
+		}
+	})
+
+	t.Run("BEUT-213: Reads Go To The Database When The Cache Is Down", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+		client, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Database: postgres, Cache: cache.NewBreaker(cache.NewRedisCache(client)), Redis: client}
+
+		mockCache.ExpectGet(parentsGenerationKey("cacheuser")).SetErr(errors.New("connection refused"))
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT category, id, name FROM entities WHERE user_id = $1 AND category IN ($2) AND deleted_at IS NULL`)).
+			WithArgs("cacheuser", "building").
+			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name"}).AddRow("building", 1, "Home"))
+
+		parents, err := repo.GetParents(context.Background(), "room", "cacheuser")
+		if err != nil || len(parents) != 1 || parents[0].Name != "Home" {
+			t.Errorf("Expected the parents from the database. Got: %v, %v", parents, err)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+}
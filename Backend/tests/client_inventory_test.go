@@ -0,0 +1,120 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"willowsuite-vault/client"
+	"willowsuite-vault/models"
+)
+
+func inventoryEntities() []client.EntitySummary {
+	none := []client.RelatedEntity{{ID: 0, Name: "-", Category: ""}}
+	home := client.RelatedEntity{ID: 1, Name: "Home", Category: "building"}
+	kitchen := client.RelatedEntity{ID: 2, Name: "Kitchen", Category: "room"}
+
+	return []client.EntitySummary{
+		{ID: 1, Name: "Home", Category: "building", Parent: none},
+		{ID: 2, Name: "Kitchen", Category: "room", Parent: []client.RelatedEntity{home}},
+		{ID: 3, Name: "Garage", Category: "room", Parent: []client.RelatedEntity{home}},
+		{ID: 4, Name: "Box", Category: "container", Parent: []client.RelatedEntity{kitchen, home}},
+		{ID: 5, Name: "Box", Category: "container", Parent: []client.RelatedEntity{kitchen, home}},
+		{ID: 6, Name: "Whisk", Category: "item", Parent: []client.RelatedEntity{{ID: 4, Name: "Box", Category: "container"}, kitchen, home}},
+	}
+}
+
+// TestClientInventory runs the unit tests for building, exporting and importing an inventory with the client.
+func TestClientInventory(t *testing.T) {
+	t.Run("BEUT-160: Client Inventory Resolves Paths", func(t *testing.T) {
+		inventory := client.NewInventory(inventoryEntities())
+
+		node, err := inventory.Resolve("/Home/Kitchen")
+		if err != nil || node == nil || node.Ref() != "room:2" {
+			t.Fatalf("Expected /Home/Kitchen to be room:2. Got: %v, %v", node, err)
+		}
+
+		if len(node.Children) != 2 {
+			t.Errorf("Expected the kitchen to hold 2 boxes. Got: %d", len(node.Children))
+		}
+
+		node, err = inventory.Resolve("item:6")
+		if err != nil || node.Path() != "/Home/Kitchen/Box/Whisk" {
+			t.Errorf("Expected item:6 to be at /Home/Kitchen/Box/Whisk. Got: %v, %v", node, err)
+		}
+
+		if _, err = inventory.Resolve("/Home/Kitchen/Box"); err == nil {
+			t.Errorf("Expected /Home/Kitchen/Box to be ambiguous.")
+		}
+
+		if _, err = inventory.Resolve("/Home/Attic"); err == nil {
+			t.Errorf("Expected /Home/Attic not to exist.")
+		}
+
+		if node, err = inventory.Resolve("/"); err != nil || node != nil {
+			t.Errorf("Expected / to resolve to the top of the inventory. Got: %v, %v", node, err)
+		}
+	})
+
+	t.Run("BEUT-161: Client Inventory Exports Parents First", func(t *testing.T) {
+		inventory := client.NewInventory(inventoryEntities())
+		kitchen, _ := inventory.Resolve("room:2")
+
+		records := inventory.Export(kitchen)
+		if len(records) != 4 || records[0].Ref != "room:2" || records[0].Parent != "building:1" {
+			t.Fatalf("Expected the kitchen and its 3 descendants, kitchen first. Got: %+v", records)
+		}
+
+		seen := map[string]bool{"building:1": true}
+		for _, record := range records {
+			if !seen[record.Parent] {
+				t.Errorf("Expected %s to come after its parent %s.", record.Ref, record.Parent)
+			}
+			seen[record.Ref] = true
+		}
+	})
+
+	t.Run("BEUT-162: Client Import Links New Parents", func(t *testing.T) {
+		var created []models.CreateEntityRequest
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /v1/entity", func(w http.ResponseWriter, r *http.Request) {
+			body := models.CreateEntityRequest{}
+			json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body)
+
+			writeEnvelope(w, map[string]interface{}{"Entity": models.Entity{ID: uint64(100 + len(created)), Name: body.Name}})
+		})
+
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		inventory := client.NewInventory(inventoryEntities())
+		kitchen, _ := inventory.Resolve("room:2")
+		records := inventory.Export(kitchen)
+
+		vault := client.New(srv.URL, client.WithTokens(client.Tokens{AccessToken: "access"}))
+		refs, err := vault.Import(context.Background(), records)
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if refs["room:2"] != "room:101" || len(created) != len(records) {
+			t.Fatalf("Expected every record to be created in order. Got: %v", refs)
+		}
+
+		if created[0].ParentCategory != "building" || created[0].ParentID.Value != 1 {
+			t.Errorf("Expected the kitchen to keep its existing building. Got: %s:%d", created[0].ParentCategory, created[0].ParentID.Value)
+		}
+
+		for i, record := range records[1:] {
+			parentRef := created[i+1].ParentCategory + ":" + strconv.FormatUint(created[i+1].ParentID.Value, 10)
+			if parentRef != refs[record.Parent] {
+				t.Errorf("Expected %s to be created under %s. Got: %s", record.Ref, refs[record.Parent], parentRef)
+			}
+		}
+	})
+}
@@ -0,0 +1,324 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+	"willowsuite-vault/client"
+	"willowsuite-vault/models"
+)
+
+// fakeVault is a minimal stand in for the API that the client tests point at.
+type fakeVault struct {
+	validToken  string
+	refreshes   atomic.Int32
+	entityCalls atomic.Int32
+	failures    int32
+	retryAfter  string
+	entities    []client.EntitySummary
+	keys        []string
+}
+
+func writeEnvelope(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "success", "data": data})
+}
+
+func writeProblem(w http.ResponseWriter, status int, detail string, fieldErrors models.ValidationErrors) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type":      "/problems/test",
+		"title":     http.StatusText(status),
+		"status":    status,
+		"detail":    detail,
+		"requestId": "request-1",
+		"errors":    fieldErrors,
+		"message":   "error",
+		"data":      detail,
+	})
+}
+
+func (vault *fakeVault) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") != "Bearer "+vault.validToken {
+		writeProblem(w, http.StatusUnauthorized, "Token has expired", nil)
+		return false
+	}
+
+	return true
+}
+
+func setupClientTest(t *testing.T, vault *fakeVault) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v1/token", func(w http.ResponseWriter, r *http.Request) {
+		body := models.SignInRequest{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if body.UserEmail == "mfa@test.com" {
+			writeEnvelope(w, map[string]interface{}{"ChallengeName": "SOFTWARE_TOKEN_MFA", "Session": "session"})
+			return
+		}
+
+		writeEnvelope(w, map[string]string{"AccessToken": vault.validToken, "IdToken": "id", "RefreshToken": "refresh", "ExpiresIn": "3600"})
+	})
+
+	mux.HandleFunc("PUT /v1/token", func(w http.ResponseWriter, r *http.Request) {
+		body := models.RefreshRequest{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if body.RefreshToken != "refresh" || body.IDToken != "id" {
+			writeProblem(w, http.StatusUnauthorized, "Invalid refresh token", nil)
+			return
+		}
+
+		vault.refreshes.Add(1)
+		vault.validToken = "access-" + strconv.Itoa(int(vault.refreshes.Load()))
+		writeEnvelope(w, map[string]string{"AccessToken": vault.validToken, "IdToken": "id", "ExpiresIn": "3600"})
+	})
+
+	mux.HandleFunc("GET /v1/entity/{category}/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if vault.entityCalls.Add(1) <= vault.failures {
+			if vault.retryAfter != "" {
+				w.Header().Set("Retry-After", vault.retryAfter)
+				writeProblem(w, http.StatusTooManyRequests, "slow down", nil)
+				return
+			}
+
+			writeProblem(w, http.StatusServiceUnavailable, "unavailable", nil)
+			return
+		}
+
+		if !vault.authorized(w, r) {
+			return
+		}
+
+		id, _ := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		writeEnvelope(w, map[string]interface{}{"Entity": models.Entity{ID: id, Name: "Shelf"}, "Parent": models.Parent{ParentID: 1, ParentCategory: "shelving_unit"}})
+	})
+
+	mux.HandleFunc("POST /v1/entity", func(w http.ResponseWriter, r *http.Request) {
+		vault.keys = append(vault.keys, r.Header.Get("Idempotency-Key"))
+		if vault.entityCalls.Add(1) <= vault.failures {
+			writeProblem(w, http.StatusServiceUnavailable, "unavailable", nil)
+			return
+		}
+
+		fieldErrors := models.ValidationErrors{}
+		fieldErrors.Add("name", models.ValidationRequired, "Missing name")
+		writeProblem(w, http.StatusUnprocessableEntity, "data validation failed", fieldErrors)
+	})
+
+	mux.HandleFunc("GET /v1/entities", func(w http.ResponseWriter, r *http.Request) {
+		if !vault.authorized(w, r) {
+			return
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := min(offset+limit, len(vault.entities))
+		writeEnvelope(w, client.EntitiesPage{TotalCount: len(vault.entities), Entities: vault.entities[offset:end]})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// TestClient runs the unit tests for the Go client SDK.
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("BEUT-152: Client Sign In And Get Entity", func(t *testing.T) {
+		srv := setupClientTest(t, &fakeVault{validToken: "access"})
+		vault := client.New(srv.URL)
+
+		result, err := vault.SignIn(ctx, "test@test.com", "password")
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if result.Challenge != nil || result.Tokens.AccessToken != "access" {
+			t.Fatalf("Expected to be signed in. Got: %+v", result)
+		}
+
+		record, err := vault.GetEntity(ctx, "shelf", 12)
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if record.Entity.ID != 12 || record.Parent.ParentCategory != "shelving_unit" {
+			t.Errorf("Expected shelf 12 in a shelving unit. Got: %+v", record)
+		}
+	})
+
+	t.Run("BEUT-153: Client Sign In Challenge", func(t *testing.T) {
+		srv := setupClientTest(t, &fakeVault{validToken: "access"})
+		vault := client.New(srv.URL)
+
+		result, err := vault.SignIn(ctx, "mfa@test.com", "password")
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if result.Challenge == nil || result.Challenge.Name != "SOFTWARE_TOKEN_MFA" {
+			t.Errorf("Expected a SOFTWARE_TOKEN_MFA challenge. Got: %+v", result)
+		}
+
+		if _, err = vault.GetEntity(ctx, "shelf", 12); !errors.Is(err, client.ErrNotSignedIn) {
+			t.Errorf("Expected ErrNotSignedIn. Got: %v", err)
+		}
+	})
+
+	t.Run("BEUT-154: Client Refreshes Rejected Token", func(t *testing.T) {
+		fake := &fakeVault{validToken: "access-0"}
+		srv := setupClientTest(t, fake)
+
+		var refreshed client.Tokens
+		vault := client.New(srv.URL,
+			client.WithTokens(client.Tokens{AccessToken: "revoked", IDToken: "id", RefreshToken: "refresh"}),
+			client.WithTokenRefreshHandler(func(tokens client.Tokens) { refreshed = tokens }))
+
+		if _, err := vault.GetEntity(ctx, "shelf", 12); err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if fake.refreshes.Load() != 1 {
+			t.Errorf("Expected 1 refresh. Got: %d", fake.refreshes.Load())
+		}
+
+		if refreshed.AccessToken != "access-1" || refreshed.RefreshToken != "refresh" {
+			t.Errorf("Expected the refresh handler to get the new access token and keep the refresh token. Got: %+v", refreshed)
+		}
+	})
+
+	t.Run("BEUT-155: Client Refreshes Expiring Token", func(t *testing.T) {
+		fake := &fakeVault{validToken: "access-0"}
+		srv := setupClientTest(t, fake)
+		vault := client.New(srv.URL, client.WithTokens(client.Tokens{
+			AccessToken:  "access-0",
+			IDToken:      "id",
+			RefreshToken: "refresh",
+			ExpiresAt:    time.Now().Add(5 * time.Second),
+		}))
+
+		if _, err := vault.GetEntity(ctx, "shelf", 12); err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if fake.refreshes.Load() != 1 || vault.Tokens().AccessToken != "access-1" {
+			t.Errorf("Expected the token to be refreshed before the call. Got: %d refreshes", fake.refreshes.Load())
+		}
+	})
+
+	t.Run("BEUT-156: Client Retries Idempotent Calls", func(t *testing.T) {
+		fake := &fakeVault{validToken: "access", failures: 2}
+		srv := setupClientTest(t, fake)
+		vault := client.New(srv.URL,
+			client.WithTokens(client.Tokens{AccessToken: "access"}),
+			client.WithRetries(3, time.Millisecond))
+
+		if _, err := vault.GetEntity(ctx, "shelf", 12); err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if fake.entityCalls.Load() != 3 {
+			t.Errorf("Expected 3 attempts. Got: %d", fake.entityCalls.Load())
+		}
+	})
+
+	t.Run("BEUT-157: Client Retries Creates With The Same Idempotency-Key", func(t *testing.T) {
+		fake := &fakeVault{validToken: "access", failures: 1}
+		srv := setupClientTest(t, fake)
+		vault := client.New(srv.URL,
+			client.WithTokens(client.Tokens{AccessToken: "access"}),
+			client.WithRetries(3, time.Millisecond))
+
+		_, err := vault.CreateEntity(ctx, client.CreateEntityRequest{Name: "Shelf", Category: "shelf"})
+
+		apiErr := &client.Error{}
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("Expected the retry to reach validation. Got: %v", err)
+		}
+
+		if len(fake.keys) != 2 || fake.keys[0] == "" || fake.keys[0] != fake.keys[1] {
+			t.Errorf("Expected 2 attempts with the same Idempotency-Key. Got: %q", fake.keys)
+		}
+
+		vault.CreateEntity(ctx, client.CreateEntityRequest{Name: "Shelf", Category: "shelf"})
+		if len(fake.keys) != 3 || fake.keys[2] == fake.keys[0] {
+			t.Errorf("Expected a new Idempotency-Key for the next create. Got: %q", fake.keys)
+		}
+	})
+
+	t.Run("BEUT-158: Client Decodes Problem Details", func(t *testing.T) {
+		srv := setupClientTest(t, &fakeVault{validToken: "access"})
+		vault := client.New(srv.URL, client.WithTokens(client.Tokens{AccessToken: "access"}))
+
+		_, err := vault.CreateEntity(ctx, client.CreateEntityRequest{Category: "shelf"})
+
+		apiErr := &client.Error{}
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Expected a *client.Error. Got: %v", err)
+		}
+
+		if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.RequestID != "request-1" {
+			t.Errorf("Expected a 422 for request-1. Got: %d for %s", apiErr.StatusCode, apiErr.RequestID)
+		}
+
+		if len(apiErr.FieldErrors) != 1 || apiErr.FieldErrors[0].Field != "name" {
+			t.Errorf("Expected a field error for name. Got: %v", apiErr.FieldErrors)
+		}
+	})
+
+	t.Run("BEUT-159: Client Iterates Every Page", func(t *testing.T) {
+		fake := &fakeVault{validToken: "access"}
+		for i := 1; i <= 5; i++ {
+			fake.entities = append(fake.entities, client.EntitySummary{ID: uint64(i), Name: "Item " + strconv.Itoa(i), Category: "item"})
+		}
+
+		srv := setupClientTest(t, fake)
+		vault := client.New(srv.URL, client.WithTokens(client.Tokens{AccessToken: "access"}))
+
+		var ids []uint64
+		for entity, err := range vault.Entities(ctx, client.ListOptions{Limit: 2}) {
+			if err != nil {
+				t.Fatalf("Expected error to be nil. Got: %v", err)
+			}
+
+			ids = append(ids, entity.ID)
+		}
+
+		if len(ids) != 5 || ids[0] != 1 || ids[4] != 5 {
+			t.Errorf("Expected entities 1 to 5 in order. Got: %v", ids)
+		}
+	})
+
+	t.Run("BEUT-248: Client Does Not Wait Out Long Retry-After", func(t *testing.T) {
+		fake := &fakeVault{validToken: "access", failures: 1, retryAfter: "900"}
+		srv := setupClientTest(t, fake)
+		vault := client.New(srv.URL,
+			client.WithTokens(client.Tokens{AccessToken: "access"}),
+			client.WithRetries(3, time.Millisecond))
+
+		start := time.Now()
+		_, err := vault.GetEntity(ctx, "shelf", 12)
+
+		apiErr := &client.Error{}
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != 15*time.Minute {
+			t.Errorf("Expected a 429 asking to wait 15m. Got: %v", err)
+		}
+
+		if fake.entityCalls.Load() != 1 || time.Since(start) > time.Second {
+			t.Errorf("Expected one attempt without waiting. Got: %d in %v", fake.entityCalls.Load(), time.Since(start))
+		}
+	})
+}
@@ -0,0 +1,135 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestOptimisticConcurrency runs the unit tests for ETags and conditional requests on entities.
+func TestOptimisticConcurrency(t *testing.T) {
+	editBody := `{"id":"10","name":"Test Item","notes":"Notes","category":"item","parentID":"10","parentCategory":"container"}`
+
+	t.Run("BEUT-175: Get Entity Sends Its Version As ETag", func(t *testing.T) {
+		client, srv, mockDB := setupGetEntityTest(t, "testuser")
+
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "entities" WHERE user_id = $1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name", "user_id", "created_at", "version", "attributes"}).
+				AddRow("item", 10, "Test Item", "testuser", time.Now(), 7, "{}"))
+
+		res, err := client.Get(fmt.Sprintf("%s%s/item/10", srv.URL, getEntityEndpoint))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if etag := res.Header.Get("ETag"); etag != `"7"` {
+			t.Errorf(`Expected ETag to be "7". Got: %s`, etag)
+		}
+	})
+
+	t.Run("BEUT-176: Edit Entity Requires If-Match", func(t *testing.T) {
+		client, srv, mockDB, _ := setupEditEntityTest(t, "testuser")
+
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+editEntityEndpoint, bytes.NewBufferString(editBody))
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusPreconditionRequired {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusPreconditionRequired, res.StatusCode)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-177: Edit Entity Stale Version", func(t *testing.T) {
+		client, srv, mockDB, _ := setupEditEntityTest(t, "testuser")
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT version, COALESCE`)).
+			WithArgs("testuser", "item", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+		mockDB.ExpectRollback()
+
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+editEntityEndpoint, bytes.NewBufferString(editBody))
+		req.Header.Set("If-Match", `"2"`)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusPreconditionFailed, res.StatusCode)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-178: Delete Entity Stale Version", func(t *testing.T) {
+		client, srv, mockDB, _ := setupDeleteEntityTest(t, "testuser")
+
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "entities" WHERE user_id = $1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name", "user_id", "version", "attributes"}).
+				AddRow("item", 10, "Test Item", "testuser", 3, "{}"))
+
+		req, _ := http.NewRequest(http.MethodDelete, srv.URL+deleteEntityEndpoint+"/item/10", nil)
+		req.Header.Set("If-Match", `W/"3"`)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusPreconditionFailed {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusPreconditionFailed, res.StatusCode)
+		}
+	})
+
+	t.Run("BEUT-179: Get Entities Not Modified", func(t *testing.T) {
+		client, srv, _, mockCache := setupGetEntitiesTest(t, "testuser")
+
+		setupGetEntitiesCacheHitMockExpectations(mockCache, "testuser", "", "")
+		setupGetEntitiesCacheHitMockExpectations(mockCache, "testuser", "", "")
+
+		res, err := client.Get(srv.URL + getEntitiesEndpoint)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		res.Body.Close()
+
+		etag := res.Header.Get("ETag")
+		if res.StatusCode != http.StatusOK || etag == "" {
+			t.Fatalf("Expected a 200 with an ETag. Got: %d %q", res.StatusCode, etag)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+getEntitiesEndpoint, nil)
+		req.Header.Set("If-None-Match", "W/"+etag)
+		res, err = client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusNotModified, res.StatusCode)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+}
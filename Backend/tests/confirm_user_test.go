@@ -11,6 +11,7 @@ import (
 	"testing"
 	"willowsuite-vault/config"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -48,7 +49,7 @@ func setupConfirmUserTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.
 	redis, _ := redismock.NewClientMock()
 	cognito := mocks.NewMockCognitoClient(ctrl)
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: cognito,
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -106,7 +107,7 @@ func validateConfirmUserResponse(t *testing.T, res *http.Response, expectedHTTP
 		t.Errorf("Expected message to be %s. Got: %s", expectedMessage, contents.Message)
 	}
 
-	if expectedData != "" && contents.Data != expectedData {
+	if expectedData != "" && contents.Data != expectedData && !hasFieldError(contents.Data, expectedData) {
 		t.Errorf("Expected body to be %s. Got: %s", expectedData, contents.Data)
 	}
 }
@@ -137,7 +138,7 @@ func TestConfirmUser(t *testing.T) {
 		{
 			testName:         "BEUT-51: Confirm User Missing Email",
 			validData:        false,
-			expectedHTTP:     http.StatusBadRequest,
+			expectedHTTP:     http.StatusUnprocessableEntity,
 			expectedMessage:  "data validation failed",
 			expectedBody:     "Missing user email",
 			UserEmail:        "",
@@ -146,7 +147,7 @@ func TestConfirmUser(t *testing.T) {
 		{
 			testName:         "BEUT-52: Confirm User Missing Confirmation Code",
 			validData:        false,
-			expectedHTTP:     http.StatusBadRequest,
+			expectedHTTP:     http.StatusUnprocessableEntity,
 			expectedMessage:  "data validation failed",
 			expectedBody:     "Missing confirmation code",
 			UserEmail:        "test@test.test",
@@ -4,7 +4,6 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +11,8 @@ import (
 	"strconv"
 	"testing"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -48,7 +49,7 @@ func setupCreateEntityTest(t *testing.T, userName string) (*http.Client, *httpte
 	postgres, mockDB := mocks.NewMockDB()
 	redis, mockCache := redismock.NewClientMock()
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: mocks.NewMockCognitoClient(ctrl),
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -63,6 +64,12 @@ func setupCreateEntityTest(t *testing.T, userName string) (*http.Client, *httpte
 	return &http.Client{}, srv, mockDB, mockCache
 }
 
+func expectParentCheck(mockDB sqlmock.Sqlmock, parentCategory string, parentID int, rows *sqlmock.Rows) {
+	mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT user_id, deleted_at FROM entities WHERE category = $1 AND id = $2 FOR SHARE`)).
+		WithArgs(parentCategory, parentID).
+		WillReturnRows(rows)
+}
+
 func setupCreateEntityMockExpectations(mockDB *sqlmock.Sqlmock, mockCache redismock.ClientMock, category string, args ...string) {
 	(*mockDB).ExpectBegin()
 
@@ -71,55 +78,31 @@ func setupCreateEntityMockExpectations(mockDB *sqlmock.Sqlmock, mockCache redism
 	testUser := args[2]
 	testID := args[3]
 
-	tableName := category + "s"
-	if category == "shelf" {
-		tableName = "shelves"
+	if category != "building" {
+		testParentID, _ := strconv.Atoi(args[4])
+		expectParentCheck(*mockDB, args[5], testParentID, sqlmock.NewRows([]string{"user_id", "deleted_at"}).AddRow(testUser, nil))
 	}
 
-	query := fmt.Sprintf(`INSERT INTO "%s" ("name","notes","user_id","created_at","updated_at","deleted_at","parent_id","parent_category") VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING "id"`, tableName)
-	if category == "building" {
-		query = `INSERT INTO "buildings" ("name","notes","user_id","created_at","updated_at","deleted_at","address") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
-	}
+	query := `INSERT INTO "entities" ("category","name","notes","user_id","created_at","updated_at","deleted_at","version","parent_category","parent_id","attributes") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING "id"`
 
 	expectation := (*mockDB).ExpectQuery(regexp.QuoteMeta(query))
 	if category == "building" {
 		testAddress := args[4]
-		expectation.WithArgs(testName, testNotes, testUser, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), testAddress)
+		attributes, _ := models.EntityAttributes{Address: &testAddress}.Value()
+		expectation.WithArgs(category, testName, testNotes, testUser, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, nil, nil, attributes)
 	} else {
 		testParentID, _ := strconv.Atoi(args[4])
 		testParentCategory := args[5]
-		expectation.WithArgs(testName, testNotes, testUser, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), testParentID, testParentCategory)
+		expectation.WithArgs(category, testName, testNotes, testUser, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, testParentCategory, testParentID, "{}")
 	}
 	expectation.WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(testID))
 
 	(*mockDB).ExpectCommit()
 
-	keyVals := []string{
-		`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},"Offset":"0","Limit":"15","Search":"","Filter":"[]"}`,
-		`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},"Offset":"15","Limit":"15","Search":"","Filter":"[]"}`,
-	}
-	mockCache.ExpectKeys(`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},*`).SetVal(keyVals)
+	expectEntitiesFlush(mockCache, testUser)
 
-	countKeys := []string{
-		`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},"Search":"","Filter":"[]"}`,
-		`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},"Search":"seachtext","Filter":"[]"}`,
-	}
-	mockCache.ExpectKeys(`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},*`).SetVal(countKeys)
-
-	keyVals = append(keyVals, countKeys...)
-
-	expectedDelKeys := append(
-		keyVals,
-		`{"User":"`+testUser+`","Function":"GetItemParents"}`,
-		`{"User":"`+testUser+`","Function":"GetContainerParents"}`,
-		`{"User":"`+testUser+`","Function":"GetShelfParents"}`,
-		`{"User":"`+testUser+`","Function":"GetShelving_unitParents"}`,
-		`{"User":"`+testUser+`","Function":"GetRoomParents"}`,
-	)
-
-	for _, key := range expectedDelKeys {
-		mockCache.ExpectDel(key).SetVal(1)
-	}
+	expectEntityEvents(mockCache, testUser, models.EventCreate)
+	expectWebhooksQueued(*mockDB, testUser, models.EventCreate)
 }
 
 func validateCreateEntitySuccessResponse(t *testing.T, res *http.Response, mockDB sqlmock.Sqlmock, mockCache redismock.ClientMock, testID uint) {
@@ -446,8 +429,8 @@ func TestCreateEntityValid(t *testing.T) {
 			if tc.validData {
 				validateCreateEntitySuccessResponse(t, res, mockDB, mockCache, tc.EntityID)
 			} else {
-				if res.StatusCode != http.StatusBadRequest {
-					t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusBadRequest, res.StatusCode)
+				if res.StatusCode != http.StatusUnprocessableEntity {
+					t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusUnprocessableEntity, res.StatusCode)
 				}
 			}
 
@@ -3,6 +3,7 @@ package tests
 
 import (
 	"bytes"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +13,8 @@ import (
 	"testing"
 	"time"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -33,6 +36,10 @@ type deleteEntityTestCase struct {
 	numberOfChildren int
 	category         string
 	id               int64
+	// anyVersion sends If-Match: * rather than the version that was read.
+	anyVersion bool
+	// meanwhile is what happened to the entity between reading and deleting it: "deleted", "changed" or nothing.
+	meanwhile string
 }
 
 var deleteEntityEndpoint = "/v1/entity"
@@ -45,7 +52,7 @@ func setupDeleteEntityTest(t *testing.T, userName string) (*http.Client, *httpte
 	postgres, mockDB := mocks.NewMockDB()
 	redis, mockCache := redismock.NewClientMock()
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: mocks.NewMockCognitoClient(ctrl),
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -60,54 +67,21 @@ func setupDeleteEntityTest(t *testing.T, userName string) (*http.Client, *httpte
 	return &http.Client{}, srv, mockDB, mockCache
 }
 
-func setupDeleteEntityMockExpectations(mockDB *sqlmock.Sqlmock, mockCache redismock.ClientMock, testID int64, category string, testUser string, numberOfChildren int) {
-	tableName := category + "s"
-	if category == "shelf" {
-		tableName = "shelves"
-	}
+func setupDeleteEntityMockExpectations(mockDB *sqlmock.Sqlmock, mockCache redismock.ClientMock, tc deleteEntityTestCase) {
+	testID, category, testUser, numberOfChildren := tc.id, tc.category, tc.testUser, tc.numberOfChildren
 
 	// Expect the SELECT operation to check if the entity exists
-	selectQuery := fmt.Sprintf(`SELECT * FROM "%s" WHERE user_id = $1 AND "%s"."deleted_at" IS NULL AND "%s"."id" = $2 ORDER BY "%s"."id" LIMIT 1`, tableName, tableName, tableName, tableName)
+	selectQuery := `SELECT * FROM "entities" WHERE user_id = $1 AND "entities"."deleted_at" IS NULL AND "entities"."category" = $2 AND "entities"."id" = $3 ORDER BY "entities"."id" LIMIT 1`
 	(*mockDB).ExpectQuery(regexp.QuoteMeta(selectQuery)).
-		WithArgs(testUser, testID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "notes", "user_id", "created_at", "updated_at", "deleted_at", "parent_id", "parent_category"}).
-			AddRow(testID, "Test Item", "Test Notes", testUser, time.Now(), time.Now(), nil, 0, ""))
+		WithArgs(testUser, category, testID).
+		WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name", "notes", "user_id", "created_at", "updated_at", "deleted_at", "version", "parent_category", "parent_id", "attributes"}).
+			AddRow(category, testID, "Test Item", "Test Notes", testUser, time.Now(), time.Now(), nil, 3, nil, nil, "{}"))
 
-	if category == "container" {
-		// Expect the query to check for container children
-		childrenQuery := `SELECT count(id) AS childrenCount FROM items WHERE user_id = $1 AND parent_id = $2 AND parent_category = $3 AND deleted_at IS NULL`
-		(*mockDB).ExpectQuery(regexp.QuoteMeta(childrenQuery)).
-			WithArgs(testUser, testID, category).
-			WillReturnRows(sqlmock.NewRows([]string{"childrenCount"}).AddRow(numberOfChildren))
-	} else if category == "shelf" {
-		// Expect the query to check for shelf children
-		childrenQuery := `
-			SELECT
-				(SELECT count(id) FROM containers WHERE user_id = $1 AND parent_id = $2 AND parent_category = $3 AND deleted_at IS NULL) +
-				(SELECT count(id) FROM items WHERE user_id = $4 AND parent_id = $5 AND parent_category = $6 AND deleted_at IS NULL)
-			AS childrenCount`
-		(*mockDB).ExpectQuery(regexp.QuoteMeta(childrenQuery)).
-			WithArgs(testUser, testID, category, testUser, testID, category).
-			WillReturnRows(sqlmock.NewRows([]string{"childrenCount"}).AddRow(numberOfChildren))
-	} else if category == "shelving_unit" {
-		childrenQuery := `SELECT count(id) AS childrenCount FROM shelves WHERE user_id = $1 AND parent_id = $2 AND parent_category = $3 AND deleted_at IS NULL`
-		(*mockDB).ExpectQuery(regexp.QuoteMeta(childrenQuery)).
-			WithArgs(testUser, testID, category).
-			WillReturnRows(sqlmock.NewRows([]string{"childrenCount"}).AddRow(numberOfChildren))
-	} else if category == "room" {
-		childrenQuery := `
-		SELECT
-			(SELECT count(id) FROM shelving_units WHERE user_id = $1 AND parent_id = $2 AND parent_category = $3 AND deleted_at IS NULL) +
-			(SELECT count(id) FROM containers WHERE user_id = $4 AND parent_id = $5 AND parent_category = $6 AND deleted_at IS NULL) +
-			(SELECT count(id) FROM items WHERE user_id = $7 AND parent_id = $8 AND parent_category = $9 AND deleted_at IS NULL)
-		AS childrenCount`
-		(*mockDB).ExpectQuery(regexp.QuoteMeta(childrenQuery)).
-			WithArgs(testUser, testID, category, testUser, testID, category, testUser, testID, category).
-			WillReturnRows(sqlmock.NewRows([]string{"childrenCount"}).AddRow(numberOfChildren))
-	} else if category == "building" {
-		childrenQuery := `SELECT count(id) AS childrenCount FROM rooms WHERE user_id = $1 AND parent_id = $2 AND parent_category = $3 AND deleted_at IS NULL`
+	if category != "item" {
+		// Expect the query to check for children
+		childrenQuery := `SELECT count(id) AS childrenCount FROM entities WHERE user_id = $1 AND parent_category = $2 AND parent_id = $3 AND deleted_at IS NULL`
 		(*mockDB).ExpectQuery(regexp.QuoteMeta(childrenQuery)).
-			WithArgs(testUser, testID, category).
+			WithArgs(testUser, category, testID).
 			WillReturnRows(sqlmock.NewRows([]string{"childrenCount"}).AddRow(numberOfChildren))
 	}
 
@@ -116,41 +90,42 @@ func setupDeleteEntityMockExpectations(mockDB *sqlmock.Sqlmock, mockCache redism
 		(*mockDB).ExpectBegin()
 
 		// Expect the UPDATE operation
-		query := fmt.Sprintf(`UPDATE "%s" SET "deleted_at"=$1 WHERE user_id = $2 AND "%s"."id" = $3 AND "%s"."deleted_at" IS NULL`, tableName, tableName, tableName)
+		query := `UPDATE "entities" SET "deleted_at"=$1 WHERE user_id = $2 AND version = $3 AND ("entities"."category","entities"."id") IN (($4,$5)) AND "entities"."deleted_at" IS NULL`
+		args := []driver.Value{sqlmock.AnyArg(), testUser, 3, category, testID}
+		if tc.anyVersion {
+			query = `UPDATE "entities" SET "deleted_at"=$1 WHERE user_id = $2 AND ("entities"."category","entities"."id") IN (($3,$4)) AND "entities"."deleted_at" IS NULL`
+			args = []driver.Value{sqlmock.AnyArg(), testUser, category, testID}
+		}
+
+		deleted := int64(1)
+		if tc.meanwhile != "" {
+			deleted = 0
+		}
 
 		expectation := (*mockDB).ExpectExec(regexp.QuoteMeta(query))
-		expectation.WithArgs(sqlmock.AnyArg(), testUser, testID)
-		expectation.WillReturnResult(sqlmock.NewResult(0, 1))
+		expectation.WithArgs(args...)
+		expectation.WillReturnResult(sqlmock.NewResult(0, deleted))
 
 		// Expect transaction to be committed
 		(*mockDB).ExpectCommit()
 
-		keyVals := []string{
-			`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},"Offset":"0","Limit":"15","Search":"","Filter":"[]"}`,
-			`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},"Offset":"15","Limit":"15","Search":"","Filter":"[]"}`,
-		}
-		mockCache.ExpectKeys(`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},*`).SetVal(keyVals)
+		if tc.meanwhile != "" {
+			// Nothing was deleted, so whether the entity is still there tells a delete from a change
+			remaining := 1
+			if tc.meanwhile == "deleted" {
+				remaining = 0
+			}
 
-		countKeys := []string{
-			`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},"Search":"","Filter":"[]"}`,
-			`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},"Search":"seachtext","Filter":"[]"}`,
+			(*mockDB).ExpectQuery(regexp.QuoteMeta(`SELECT count(id) FROM entities WHERE user_id = $1 AND category = $2 AND id = $3 AND deleted_at IS NULL`)).
+				WithArgs(testUser, category, testID).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(remaining))
+			return
 		}
-		mockCache.ExpectKeys(`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},*`).SetVal(countKeys)
-
-		keyVals = append(keyVals, countKeys...)
 
-		expectedDelKeys := append(
-			keyVals,
-			`{"User":"`+testUser+`","Function":"GetItemParents"}`,
-			`{"User":"`+testUser+`","Function":"GetContainerParents"}`,
-			`{"User":"`+testUser+`","Function":"GetShelfParents"}`,
-			`{"User":"`+testUser+`","Function":"GetShelving_unitParents"}`,
-			`{"User":"`+testUser+`","Function":"GetRoomParents"}`,
-		)
+		expectEntitiesFlush(mockCache, testUser)
 
-		for _, key := range expectedDelKeys {
-			mockCache.ExpectDel(key).SetVal(1)
-		}
+		expectEntityEvents(mockCache, testUser, models.EventDelete)
+		expectWebhooksQueued(*mockDB, testUser, models.EventDelete)
 	}
 }
 
@@ -280,6 +255,31 @@ func TestDeleteEntityValid(t *testing.T) {
 			testUser:         "testUser12",
 			numberOfChildren: 5,
 		},
+		{
+			testName:  "BEUT-249: Delete Entity Deleted Meanwhile",
+			validData: true,
+			id:        70,
+			category:  "item",
+			testUser:  "testUser13",
+			meanwhile: "deleted",
+		},
+		{
+			testName:   "BEUT-250: Delete Any Version Of Entity Deleted Meanwhile",
+			validData:  true,
+			id:         75,
+			category:   "item",
+			testUser:   "testUser14",
+			anyVersion: true,
+			meanwhile:  "deleted",
+		},
+		{
+			testName:  "BEUT-251: Delete Entity Changed Meanwhile",
+			validData: true,
+			id:        80,
+			category:  "item",
+			testUser:  "testUser15",
+			meanwhile: "changed",
+		},
 	}
 
 	for _, tc := range cases {
@@ -287,7 +287,7 @@ func TestDeleteEntityValid(t *testing.T) {
 		t.Run(tc.testName, func(t *testing.T) {
 
 			if tc.validData {
-				setupDeleteEntityMockExpectations(&mockDB, mockCache, tc.id, tc.category, tc.testUser, tc.numberOfChildren)
+				setupDeleteEntityMockExpectations(&mockDB, mockCache, tc)
 			}
 
 			url := fmt.Sprintf("%s%s/%s/%d", srv.URL, deleteEntityEndpoint, tc.category, tc.id)
@@ -301,6 +301,10 @@ func TestDeleteEntityValid(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to build request: %v", err)
 			}
+			req.Header.Set("If-Match", `"3"`)
+			if tc.anyVersion {
+				req.Header.Set("If-Match", "*")
+			}
 
 			res, err := client.Do(req)
 			if err != nil {
@@ -308,8 +312,25 @@ func TestDeleteEntityValid(t *testing.T) {
 			}
 			defer res.Body.Close()
 
-			if tc.validData && tc.numberOfChildren == 0 {
+			if tc.meanwhile != "" {
+				expected := http.StatusPreconditionFailed
+				if tc.meanwhile == "deleted" {
+					expected = http.StatusNotFound
+				}
+
+				if res.StatusCode != expected {
+					t.Errorf("Expected status code to be: %d. Got: %d.", expected, res.StatusCode)
+				}
+
+				if err := mockDB.ExpectationsWereMet(); err != nil {
+					t.Errorf("PostGres expectations were not met: %v", err)
+				}
+			} else if tc.validData && tc.numberOfChildren == 0 {
 				validateDeleteEntityResponse(t, res, mockDB, mockCache)
+			} else if tc.validData {
+				if res.StatusCode != http.StatusConflict {
+					t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusConflict, res.StatusCode)
+				}
 			} else {
 				if res.StatusCode != http.StatusBadRequest {
 					t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusBadRequest, res.StatusCode)
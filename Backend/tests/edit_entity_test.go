@@ -12,6 +12,8 @@ import (
 	"strconv"
 	"testing"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -48,7 +50,7 @@ func setupEditEntityTest(t *testing.T, userName string) (*http.Client, *httptest
 	postgres, mockDB := mocks.NewMockDB()
 	redis, mockCache := redismock.NewClientMock()
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: mocks.NewMockCognitoClient(ctrl),
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -69,60 +71,47 @@ func setupEditEntityMockExpectations(mockDB *sqlmock.Sqlmock, mockCache redismoc
 	testUser := args[2]
 	testID, _ := strconv.Atoi(args[3])
 
-	tableName := category + "s"
-	if category == "shelf" {
-		tableName = "shelves"
-	}
-
 	// Expect transaction to begin
 	(*mockDB).ExpectBegin()
 
-	// Expect the UPDATE operation
-	query := fmt.Sprintf(`UPDATE "%s" SET "name"=$1,"notes"=$2,"user_id"=$3,"created_at"=$4,"updated_at"=$5,"deleted_at"=$6,"parent_id"=$7,"parent_category"=$8 WHERE "%s"."deleted_at" IS NULL AND "id" = $9`, tableName, tableName)
-	if category == "building" {
-		query = `UPDATE "buildings" SET "name"=$1,"notes"=$2,"user_id"=$3,"created_at"=$4,"updated_at"=$5,"deleted_at"=$6,"address"=$7 WHERE "buildings"."deleted_at" IS NULL AND "id" = $8`
+	// Expect the current version to be locked
+	current := sqlmock.NewRows([]string{"version", "parent_category", "parent_id"}).AddRow(3, "", 0)
+	if category != "building" {
+		testParentID, _ := strconv.Atoi(args[4])
+		current = sqlmock.NewRows([]string{"version", "parent_category", "parent_id"}).AddRow(3, args[5], testParentID)
+	}
+	(*mockDB).ExpectQuery(regexp.QuoteMeta(`SELECT version, COALESCE(parent_category, '') AS parent_category, COALESCE(parent_id, 0) AS parent_id FROM entities WHERE user_id = $1 AND category = $2 AND id = $3 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(testUser, category, testID).
+		WillReturnRows(current)
+
+	// Expect the parent to be checked and locked
+	if category != "building" {
+		testParentID, _ := strconv.Atoi(args[4])
+		expectParentCheck(*mockDB, args[5], testParentID, sqlmock.NewRows([]string{"user_id", "deleted_at"}).AddRow(testUser, nil))
 	}
 
+	// Expect the UPDATE operation
+	query := `UPDATE "entities" SET "name"=$1,"notes"=$2,"updated_at"=$3,"version"=$4,"parent_category"=$5,"parent_id"=$6,"attributes"=$7 WHERE "entities"."deleted_at" IS NULL AND "category" = $8 AND "id" = $9`
+
 	expectation := (*mockDB).ExpectExec(regexp.QuoteMeta(query))
 	if category == "building" {
 		testAddress := args[4]
-		expectation.WithArgs(testName, testNotes, testUser, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), testAddress, testID)
+		attributes, _ := models.EntityAttributes{Address: &testAddress}.Value()
+		expectation.WithArgs(testName, testNotes, sqlmock.AnyArg(), 4, nil, nil, attributes, category, testID)
 	} else {
 		testParentID, _ := strconv.Atoi(args[4])
 		testParentCategory := args[5]
-		expectation.WithArgs(testName, testNotes, testUser, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), testParentID, testParentCategory, testID)
+		expectation.WithArgs(testName, testNotes, sqlmock.AnyArg(), 4, testParentCategory, testParentID, "{}", category, testID)
 	}
 	expectation.WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Expect transaction to be committed
 	(*mockDB).ExpectCommit()
 
-	keyVals := []string{
-		`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},"Offset":"0","Limit":"15","Search":"","Filter":"[]"}`,
-		`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},"Offset":"15","Limit":"15","Search":"","Filter":"[]"}`,
-	}
-	mockCache.ExpectKeys(`{"CacheKey":{"User":"` + testUser + `","Function":"GetAllEntities"},*`).SetVal(keyVals)
-
-	countKeys := []string{
-		`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},"Search":"","Filter":"[]"}`,
-		`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},"Search":"seachtext","Filter":"[]"}`,
-	}
-	mockCache.ExpectKeys(`{"CacheKey":{"User":"` + testUser + `","Function":"CountEntities"},*`).SetVal(countKeys)
-
-	keyVals = append(keyVals, countKeys...)
+	expectEntitiesFlush(mockCache, testUser)
 
-	expectedDelKeys := append(
-		keyVals,
-		`{"User":"`+testUser+`","Function":"GetItemParents"}`,
-		`{"User":"`+testUser+`","Function":"GetContainerParents"}`,
-		`{"User":"`+testUser+`","Function":"GetShelfParents"}`,
-		`{"User":"`+testUser+`","Function":"GetShelving_unitParents"}`,
-		`{"User":"`+testUser+`","Function":"GetRoomParents"}`,
-	)
-
-	for _, key := range expectedDelKeys {
-		mockCache.ExpectDel(key).SetVal(1)
-	}
+	expectEntityEvents(mockCache, testUser, models.EventUpdate)
+	expectWebhooksQueued(*mockDB, testUser, models.EventUpdate)
 }
 
 func validateEditEntityResponse(t *testing.T, res *http.Response, mockDB sqlmock.Sqlmock, mockCache redismock.ClientMock) {
@@ -454,6 +443,7 @@ func TestEditEntityValid(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to build request: %v", err)
 			}
+			req.Header.Set("If-Match", `"3"`)
 
 			res, err := client.Do(req)
 			if err != nil {
@@ -464,8 +454,8 @@ func TestEditEntityValid(t *testing.T) {
 			if tc.validData {
 				validateEditEntityResponse(t, res, mockDB, mockCache)
 			} else {
-				if res.StatusCode != http.StatusBadRequest {
-					t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusBadRequest, res.StatusCode)
+				if res.StatusCode != http.StatusUnprocessableEntity {
+					t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusUnprocessableEntity, res.StatusCode)
 				}
 			}
 
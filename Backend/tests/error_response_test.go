@@ -0,0 +1,168 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+)
+
+type errorResponseTestCase struct {
+	testName        string
+	testUser        string
+	category        string
+	id              string
+	dbError         error
+	expectedHTTP    int
+	expectedType    string
+	expectedMessage string
+	expectedDetail  string
+}
+
+func setupErrorResponseTest(t *testing.T, userName string) (*http.Client, *httptest.Server, sqlmock.Sqlmock) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	postgres, mockDB := mocks.NewMockDB()
+	redis, _ := redismock.NewClientMock()
+	handler := controllers.Handler{
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
+		CognitoClient: mocks.NewMockCognitoClient(ctrl),
+		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(mocks.MockJWTMiddleware(userName))
+	r.Get(getEntityEndpoint+getEntityParameters, handler.GetEntity)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &http.Client{}, srv, mockDB
+}
+
+func setupErrorResponseMockExpectations(mockDB sqlmock.Sqlmock, tc errorResponseTestCase) {
+	query := mockDB.ExpectQuery(`SELECT \* FROM "entities" WHERE user_id = \$1`)
+	if tc.dbError != nil {
+		query.WillReturnError(tc.dbError)
+		return
+	}
+
+	query.WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func validateErrorResponse(t *testing.T, res *http.Response, tc errorResponseTestCase) {
+	if res.StatusCode != tc.expectedHTTP {
+		t.Errorf("Expected status code to be: %d. Got: %d.", tc.expectedHTTP, res.StatusCode)
+	}
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("Expected content type to be application/problem+json. Got: %s", contentType)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	problem := helpers.Problem{}
+	if err = json.Unmarshal(data, &problem); err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	if problem.Status != tc.expectedHTTP {
+		t.Errorf("Expected problem status to be: %d. Got: %d.", tc.expectedHTTP, problem.Status)
+	}
+
+	if problem.Type != tc.expectedType {
+		t.Errorf("Expected problem type to be %s. Got: %s", tc.expectedType, problem.Type)
+	}
+
+	if problem.Message != tc.expectedMessage {
+		t.Errorf("Expected message to be %s. Got: %s", tc.expectedMessage, problem.Message)
+	}
+
+	if problem.Detail != tc.expectedDetail || problem.Data != tc.expectedDetail {
+		t.Errorf("Expected detail to be %s. Got: %s", tc.expectedDetail, problem.Detail)
+	}
+
+	if problem.RequestID == "" {
+		t.Errorf("Expected a request ID in the problem body.")
+	}
+}
+
+// TestErrorResponse runs the unit tests for mapping application errors to problem+json responses.
+func TestErrorResponse(t *testing.T) {
+	cases := []errorResponseTestCase{
+		{
+			testName:        "BEUT-145: Error Response Not Found",
+			testUser:        "testuser",
+			category:        "item",
+			id:              "1",
+			expectedHTTP:    http.StatusNotFound,
+			expectedType:    "/problems/not_found",
+			expectedMessage: "not found",
+			expectedDetail:  "Entity category of item with id 1 not found.",
+		},
+		{
+			testName:        "BEUT-146: Error Response Database Failure",
+			testUser:        "testuser",
+			category:        "item",
+			id:              "2",
+			dbError:         errors.New("connection reset"),
+			expectedHTTP:    http.StatusInternalServerError,
+			expectedType:    "/problems/internal",
+			expectedMessage: "internal server error",
+			expectedDetail:  "Error getting entity.",
+		},
+		{
+			testName:        "BEUT-147: Error Response Bad Request",
+			testUser:        "testuser",
+			category:        "item",
+			id:              "abc",
+			expectedHTTP:    http.StatusBadRequest,
+			expectedType:    "/problems/bad_request",
+			expectedMessage: "data validation failed",
+			expectedDetail:  "ID must be type integer: abc",
+		},
+	}
+
+	for _, tc := range cases {
+		client, srv, mockDB := setupErrorResponseTest(t, tc.testUser)
+		t.Run(tc.testName, func(t *testing.T) {
+			if tc.expectedHTTP != http.StatusBadRequest {
+				setupErrorResponseMockExpectations(mockDB, tc)
+			}
+
+			url := fmt.Sprintf("%s%s/%s/%s", srv.URL, getEntityEndpoint, tc.category, tc.id)
+
+			res, err := client.Get(url)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			validateErrorResponse(t, res, tc)
+
+			if err := mockDB.ExpectationsWereMet(); err != nil {
+				t.Errorf("PostGres expectations were not met: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// eventArg reads the event sent in the last argument of an XADD or PUBLISH.
+func eventArg(args []interface{}) (models.EntityEvent, error) {
+	var event models.EntityEvent
+
+	var data []byte
+	switch value := args[len(args)-1].(type) {
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return event, fmt.Errorf("unexpected event argument %T", value)
+	}
+
+	err := json.Unmarshal(data, &event)
+	return event, err
+}
+
+// expectEntityEvents expects an event of each of the given types to be stored and published for a user, in order.
+// The events get the IDs 1700000000000-0, 1700000000000-1 and so on.
+func expectEntityEvents(mockCache redismock.ClientMock, testUser string, eventTypes ...string) {
+	key := `{"User":"` + testUser + `","Function":"Events"}`
+
+	for i, eventType := range eventTypes {
+		id := fmt.Sprintf("1700000000000-%d", i)
+
+		mockCache.CustomMatch(func(_, actual []interface{}) error {
+			event, err := eventArg(actual)
+			if err != nil {
+				return err
+			}
+
+			if actual[1] != key || event.Type != eventType || event.ID != "" {
+				return fmt.Errorf("expected a %s event to be stored in %s. Got: %v", eventType, key, actual)
+			}
+
+			return nil
+		}).ExpectXAdd(&redis.XAddArgs{Stream: key, MaxLen: 1000, Approx: true, Values: map[string]interface{}{"event": ""}}).SetVal(id)
+
+		mockCache.CustomMatch(func(_, actual []interface{}) error {
+			event, err := eventArg(actual)
+			if err != nil {
+				return err
+			}
+
+			if actual[1] != key || event.Type != eventType || event.ID != id {
+				return fmt.Errorf("expected %s event %s to be published on %s. Got: %v", eventType, id, key, actual)
+			}
+
+			return nil
+		}).ExpectPublish(key, "").SetVal(1)
+	}
+}
+
+func storedEvent(id string, eventType string, entityID uint64) redis.XMessage {
+	data, _ := json.Marshal(models.EntityEvent{Type: eventType, Category: "item", EntityID: entityID, Version: 1})
+	return redis.XMessage{ID: id, Values: map[string]interface{}{"event": string(data)}}
+}
+
+// TestEvents runs the unit tests for publishing entity changes and resuming the event stream.
+func TestEvents(t *testing.T) {
+	key := `{"User":"testuser","Function":"Events"}`
+
+	t.Run("BEUT-197: Published Events Carry Their Stream ID", func(t *testing.T) {
+		client, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Cache: cache.NewRedisCache(client), Redis: client}
+
+		expectEntityEvents(mockCache, "testuser", models.EventMove, models.EventDelete)
+
+		repo.PublishEvents(context.Background(), "testuser",
+			models.EntityEvent{Type: models.EventMove, Category: "item", EntityID: 10},
+			models.EntityEvent{Type: models.EventDelete, Category: "item", EntityID: 11},
+		)
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-198: Reconnecting Replays Missed Events", func(t *testing.T) {
+		client, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Cache: cache.NewRedisCache(client), Redis: client}
+
+		mockCache.ExpectXRangeN(key, "-", "+", 1).SetVal([]redis.XMessage{storedEvent("1700000000000-0", models.EventCreate, 9)})
+		mockCache.ExpectXRange(key, "(1700000000000-1", "+").SetVal([]redis.XMessage{
+			storedEvent("1700000000000-2", models.EventUpdate, 10),
+			storedEvent("1700000000001-0", models.EventDelete, 11),
+		})
+
+		events, complete, err := repo.EventsSince(context.Background(), "testuser", "1700000000000-1")
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if !complete {
+			t.Errorf("Expected the missed events to be complete")
+		}
+
+		if len(events) != 2 || events[0].ID != "1700000000000-2" || events[1].Type != models.EventDelete {
+			t.Errorf("Expected the update and delete after the last event. Got: %+v", events)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-199: Reconnecting After Events Were Trimmed", func(t *testing.T) {
+		client, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Cache: cache.NewRedisCache(client), Redis: client}
+
+		mockCache.ExpectXRangeN(key, "-", "+", 1).SetVal([]redis.XMessage{storedEvent("1700000000500-0", models.EventCreate, 9)})
+		mockCache.ExpectXRange(key, "(1700000000000-1", "+").SetVal([]redis.XMessage{storedEvent("1700000000500-0", models.EventCreate, 9)})
+
+		_, complete, err := repo.EventsSince(context.Background(), "testuser", "1700000000000-1")
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if complete {
+			t.Errorf("Expected the missed events to be incomplete")
+		}
+
+		// An ID that isn't from the stream can't be resumed from at all
+		_, complete, err = repo.EventsSince(context.Background(), "testuser", "yesterday")
+		if err != nil || complete {
+			t.Errorf("Expected an unknown ID to need a reset. Got: %v %v", complete, err)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-200: Event IDs Are Ordered", func(t *testing.T) {
+		testCases := []struct {
+			a, b     string
+			expected bool
+		}{
+			{"1700000000000-1", "1700000000000-2", true},
+			{"1700000000000-10", "1700000000000-9", false},
+			{"999-5", "1000-0", true},
+			{"1000-0", "1000-0", false},
+			{"garbage", "1000-0", true},
+			{"1000-0", "garbage", false},
+		}
+
+		for _, tc := range testCases {
+			if got := models.EventIDBefore(tc.a, tc.b); got != tc.expected {
+				t.Errorf("Expected EventIDBefore(%s, %s) to be %v. Got: %v", tc.a, tc.b, tc.expected, got)
+			}
+		}
+	})
+}
@@ -9,9 +9,11 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"regexp"
+	"strconv"
 	"testing"
 	"time"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/models"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
@@ -45,7 +47,7 @@ func setupGetEntitiesTest(t *testing.T, userName string) (*http.Client, *httptes
 	postgres, mockDB := mocks.NewMockDB()
 	redis, mockCache := redismock.NewClientMock()
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: mocks.NewMockCognitoClient(ctrl),
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -69,42 +71,34 @@ func setupGetEntitiesCacheMissMockExpectations(mockDB *sqlmock.Sqlmock, mockCach
 		limit = "20"
 	}
 
-	cacheKey := fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"GetAllEntities"},"Offset":"%s","Limit":"%s","Search":"","Filters":[]}`, userName, offset, limit)
-	countCacheKey := fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"CountEntities"},"Search":"","Filters":[]}`, userName)
-
-	expectedMainSQL := fmt.Sprintf(`
-		(SELECT 1 AS tableWeight, created_at, 'building' AS category, id, name, notes, address, 0 AS parent_id, ' ' AS parent_category FROM buildings WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at ASC LIMIT $2)
-    	UNION ALL
-    	(SELECT 2 AS tableWeight, created_at, 'room' AS category, id, name, notes, '' AS address, parent_id, parent_category FROM rooms WHERE user_id = $3 AND deleted_at IS NULL ORDER BY created_at ASC LIMIT $4)
-    	UNION ALL
-    	(SELECT 3 AS tableWeight, created_at, 'shelving_unit' AS category, id, name, notes, '' AS address, parent_id, parent_category FROM shelving_units WHERE user_id = $5 AND deleted_at IS NULL ORDER BY created_at ASC LIMIT $6)
-    	UNION ALL
-    	(SELECT 4 AS tableWeight, created_at, 'shelf' AS category, id, name, notes, '' AS address, parent_id, parent_category FROM shelves WHERE user_id = $7 AND deleted_at IS NULL ORDER BY created_at ASC LIMIT $8)
-    	UNION ALL
-    	(SELECT 5 AS tableWeight, created_at, 'container' AS category, id, name, notes, '' AS address, parent_id, parent_category FROM containers WHERE user_id = $9 AND deleted_at IS NULL ORDER BY created_at ASC LIMIT $10)
-    	UNION ALL
-    	(SELECT 6 AS tableWeight, created_at, 'item' AS category, id, name, notes, '' AS address, parent_id, parent_category FROM items WHERE user_id = $11 AND deleted_at IS NULL ORDER BY created_at ASC LIMIT $12)
-    	ORDER BY tableWeight OFFSET $13 LIMIT $14`)
-
-	expectedCountSQL := `SELECT (SELECT COUNT(*) FROM buildings WHERE user_id = $1 AND deleted_at IS NULL ) +
-                            (SELECT COUNT(*) FROM rooms WHERE user_id = $2 AND deleted_at IS NULL ) +
-                            (SELECT COUNT(*) FROM shelving_units WHERE user_id = $3 AND deleted_at IS NULL ) +
-                            (SELECT COUNT(*) FROM shelves WHERE user_id = $4 AND deleted_at IS NULL ) +
-                            (SELECT COUNT(*) FROM containers WHERE user_id = $5 AND deleted_at IS NULL ) +
-                            (SELECT COUNT(*) FROM items WHERE user_id = $6 AND deleted_at IS NULL ) AS EntityCount`
+	cacheKey := fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"GetAllEntities"},"Generation":0,"Offset":"%s","Limit":"%s","Search":"","Filters":[]}`, userName, offset, limit)
+	countCacheKey := fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"CountEntities"},"Generation":0,"Search":"","Filters":[]}`, userName)
+	generationKey := fmt.Sprintf(`{"User":"%s","Function":"EntitiesGeneration"}`, userName)
+	offsetInt, _ := strconv.Atoi(offset)
+	limitInt, _ := strconv.Atoi(limit)
 
+	expectedMainSQL := `SELECT category, id, name, notes, attributes->>'address' AS address, COALESCE(parent_id, 0) AS parent_id, COALESCE(parent_category, '') AS parent_category, version
+		FROM entities WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY CASE category WHEN 'building' THEN 1 WHEN 'room' THEN 2 WHEN 'shelving_unit' THEN 3 WHEN 'shelf' THEN 4 WHEN 'container' THEN 5 WHEN 'item' THEN 6 END, created_at, id
+		OFFSET $2 LIMIT $3`
+
+	expectedCountSQL := `SELECT COUNT(*) FROM entities WHERE user_id = $1 AND deleted_at IS NULL`
+
+	// Nothing has changed yet, so the entities are still on generation 0
+	mockCache.ExpectGet(generationKey).RedisNil()
 	mockCache.ExpectGet(cacheKey).RedisNil()
+	mockCache.ExpectGet(generationKey).RedisNil()
 	mockCache.ExpectGet(countCacheKey).RedisNil()
 
 	(*mockDB).ExpectQuery(regexp.QuoteMeta(expectedMainSQL)).
-		WithArgs(userName, limit, userName, limit, userName, limit, userName, limit, userName, limit, userName, limit, offset, limit).
-		WillReturnRows(sqlmock.NewRows([]string{"tableWeight", "created_at", "category", "id", "name", "notes", "address", "parent_id", "parent_category"}).
-			AddRow(1, time.Now(), "building", 1, "Building 1", " ", " ", 0, " ").
-			AddRow(2, time.Now(), "room", 1, "Room 1", " ", " ", 1, "building").
-			AddRow(3, time.Now(), "shelving_unit", 1, "Shelving Unit 1", " ", " ", 1, "room").
-			AddRow(4, time.Now(), "shelf", 1, "Shelf 1", " ", " ", 1, "shelving_unit").
-			AddRow(5, time.Now(), "container", 1, "Container 1", " ", " ", 1, "shelf").
-			AddRow(6, time.Now(), "item", 2, "Item 2", " ", " ", 1, "container"))
+		WithArgs(userName, offsetInt, limitInt).
+		WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name", "notes", "address", "parent_id", "parent_category"}).
+			AddRow("building", 1, "Building 1", " ", "123 address", 0, "").
+			AddRow("room", 1, "Room 1", " ", nil, 1, "building").
+			AddRow("shelving_unit", 1, "Shelving Unit 1", " ", nil, 1, "room").
+			AddRow("shelf", 1, "Shelf 1", " ", nil, 1, "shelving_unit").
+			AddRow("container", 1, "Container 1", " ", nil, 1, "shelf").
+			AddRow("item", 2, "Item 2", " ", nil, 1, "container"))
 
 	// Recursive parent expectations - room
 	expectBuilding(mockDB, userName)
@@ -132,8 +126,8 @@ func setupGetEntitiesCacheMissMockExpectations(mockDB *sqlmock.Sqlmock, mockCach
 	expectBuilding(mockDB, userName)
 
 	(*mockDB).ExpectQuery(regexp.QuoteMeta(expectedCountSQL)).
-		WithArgs(userName, userName, userName, userName, userName, userName).
-		WillReturnRows(sqlmock.NewRows([]string{"EntityCount"}).AddRow(12))
+		WithArgs(userName).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
 
 	// Expect set for main entities cache key
 	// mockCache.Regexp().ExpectSet(regexp.QuoteMeta(cacheKey), ".*", 5*time.Minute).SetVal("OK")
@@ -151,9 +145,11 @@ func setupGetEntitiesCacheHitMockExpectations(mockCache redismock.ClientMock, us
 		limit = "20"
 	}
 
-	cacheKey := fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"GetAllEntities"},"Offset":"%s","Limit":"%s","Search":"","Filters":[]}`, userName, offset, limit)
-	countCacheKey := fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"CountEntities"},"Search":"","Filters":[]}`, userName)
+	cacheKey := fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"GetAllEntities"},"Generation":3,"Offset":"%s","Limit":"%s","Search":"","Filters":[]}`, userName, offset, limit)
+	countCacheKey := fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"CountEntities"},"Generation":3,"Search":"","Filters":[]}`, userName)
+	generationKey := fmt.Sprintf(`{"User":"%s","Function":"EntitiesGeneration"}`, userName)
 
+	mockCache.ExpectGet(generationKey).SetVal("3")
 	mockCache.ExpectGet(cacheKey).SetVal(`[
 											{"ID":36,"Name":"Home","Category":"building","Location":" ","Notes":"Some test notes for the building."},
 											{"ID":11,"Name":"Another Test Room","Category":"room","Location":" ","Notes":""},
@@ -163,42 +159,41 @@ func setupGetEntitiesCacheHitMockExpectations(mockCache redismock.ClientMock, us
 											{"ID":85,"Name":"Test Entity","Category":"item","Location":" ","Notes":"Maybe."}
 										]`)
 
+	mockCache.ExpectGet(generationKey).SetVal("3")
 	mockCache.ExpectGet(countCacheKey).SetVal("6")
 }
 
 func expectContainer(mockDB *sqlmock.Sqlmock, userName string) {
-	(*mockDB).ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "containers" WHERE user_id = $1 AND "containers"."deleted_at" IS NULL AND "containers"."id" = $2 ORDER BY "containers"."id" LIMIT 1`)).
-		WithArgs(userName, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "notes", "created_at", "updated_at", "user_id", "parent_id", "parent_category"}).
-			AddRow(1, "Container 1", "test notes", time.Now(), time.Now(), userName, 1, "shelf"))
+	expectParentEntity(mockDB, userName, "container", "Container 1", "shelf")
 }
 
 func expectShelf(mockDB *sqlmock.Sqlmock, userName string) {
-	(*mockDB).ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "shelves" WHERE user_id = $1 AND "shelves"."deleted_at" IS NULL AND "shelves"."id" = $2 ORDER BY "shelves"."id" LIMIT 1`)).
-		WithArgs(userName, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "notes", "created_at", "updated_at", "user_id", "parent_id", "parent_category"}).
-			AddRow(1, "Shelf 1", "test notes", time.Now(), time.Now(), userName, 1, "shelving_unit"))
+	expectParentEntity(mockDB, userName, "shelf", "Shelf 1", "shelving_unit")
 }
 
 func expectUnit(mockDB *sqlmock.Sqlmock, userName string) {
-	(*mockDB).ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "shelving_units" WHERE user_id = $1 AND "shelving_units"."deleted_at" IS NULL AND "shelving_units"."id" = $2 ORDER BY "shelving_units"."id" LIMIT 1`)).
-		WithArgs(userName, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "notes", "created_at", "updated_at", "user_id", "parent_id", "parent_category"}).
-			AddRow(1, "Shelving Unit 1", "test notes", time.Now(), time.Now(), userName, 1, "room"))
+	expectParentEntity(mockDB, userName, "shelving_unit", "Shelving Unit 1", "room")
 }
 
 func expectRoom(mockDB *sqlmock.Sqlmock, userName string) {
-	(*mockDB).ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "rooms" WHERE user_id = $1 AND "rooms"."deleted_at" IS NULL AND "rooms"."id" = $2 ORDER BY "rooms"."id" LIMIT 1`)).
-		WithArgs(userName, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "notes", "created_at", "updated_at", "user_id", "parent_id", "parent_category"}).
-			AddRow(1, "Room 1", "test notes", time.Now(), time.Now(), userName, 1, "building"))
+	expectParentEntity(mockDB, userName, "room", "Room 1", "building")
 }
 
 func expectBuilding(mockDB *sqlmock.Sqlmock, userName string) {
-	(*mockDB).ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "buildings" WHERE user_id = $1 AND "buildings"."deleted_at" IS NULL AND "buildings"."id" = $2 ORDER BY "buildings"."id" LIMIT 1`)).
-		WithArgs(userName, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "address", "notes", "created_at", "updated_at", "user_id"}).
-			AddRow(1, "Building 1", "123 address", "test notes", time.Now(), time.Now(), userName))
+	expectParentEntity(mockDB, userName, "building", "Building 1", "")
+}
+
+func expectParentEntity(mockDB *sqlmock.Sqlmock, userName string, category string, name string, parentCategory string) {
+	rows := sqlmock.NewRows([]string{"category", "id", "name", "notes", "created_at", "updated_at", "user_id", "parent_category", "parent_id", "attributes"})
+	if parentCategory == "" {
+		rows.AddRow(category, 1, name, "test notes", time.Now(), time.Now(), userName, nil, nil, `{"address":"123 address"}`)
+	} else {
+		rows.AddRow(category, 1, name, "test notes", time.Now(), time.Now(), userName, parentCategory, 1, "{}")
+	}
+
+	(*mockDB).ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "entities" WHERE user_id = $1 AND "entities"."deleted_at" IS NULL AND "entities"."category" = $2 AND "entities"."id" = $3 ORDER BY "entities"."id" LIMIT 1`)).
+		WithArgs(userName, category, 1).
+		WillReturnRows(rows)
 }
 
 func validateGetEntitiesSuccessResponse(t *testing.T, res *http.Response, mockDB sqlmock.Sqlmock, mockCache redismock.ClientMock) {
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -44,7 +45,7 @@ func setupGetEntityTest(t *testing.T, userName string) (*http.Client, *httptest.
 	postgres, mockDB := mocks.NewMockDB()
 	redis, _ := redismock.NewClientMock()
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: mocks.NewMockCognitoClient(ctrl),
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -60,24 +61,19 @@ func setupGetEntityTest(t *testing.T, userName string) (*http.Client, *httptest.
 }
 
 func setupGetEntityMockExpectations(mockDB *sqlmock.Sqlmock, category string, userName string, entityID string) {
-	tableName := category + "s"
-	if category == "shelf" {
-		tableName = "shelves"
-	}
-
 	entityIDInt, _ := strconv.ParseInt(entityID, 10, 64)
 
-	expectedMainSQL := fmt.Sprintf(`SELECT \* FROM "%s" WHERE user_id = \$1 AND "%s"."deleted_at" IS NULL AND "%s"."id" = \$2 ORDER BY "%s"."id" LIMIT 1`, tableName, tableName, tableName, tableName)
+	expectedMainSQL := `SELECT \* FROM "entities" WHERE user_id = \$1 AND "entities"."deleted_at" IS NULL AND "entities"."category" = \$2 AND "entities"."id" = \$3 ORDER BY "entities"."id" LIMIT 1`
 
-	rows := sqlmock.NewRows([]string{"id", "name", "notes", "user_id", "created_at", "updated_at", "deleted_at", "parent_id", "parent_category"}).
-		AddRow(entityID, "Entity 1", "Notes", userName, time.Now(), time.Now(), nil, 0, "")
+	rows := sqlmock.NewRows([]string{"category", "id", "name", "notes", "user_id", "created_at", "updated_at", "deleted_at", "parent_category", "parent_id", "attributes"}).
+		AddRow(category, entityID, "Entity 1", "Notes", userName, time.Now(), time.Now(), nil, nil, nil, "{}")
 
 	(*mockDB).ExpectQuery(expectedMainSQL).
-		WithArgs(userName, entityIDInt).
+		WithArgs(userName, category, entityIDInt).
 		WillReturnRows(rows)
 }
 
-func validateGetEntitySuccessResponse(t *testing.T, res *http.Response, mockDB sqlmock.Sqlmock) {
+func validateGetEntitySuccessResponse(t *testing.T, res *http.Response, mockDB sqlmock.Sqlmock, category string) {
 	if res.StatusCode != http.StatusOK {
 		t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusOK, res.StatusCode)
 	}
@@ -102,6 +98,18 @@ func validateGetEntitySuccessResponse(t *testing.T, res *http.Response, mockDB s
 		t.Errorf("Expected data to be type models.GetEntityResponse. Got: %v", dataType)
 	}
 
+	// Every category keeps the shape it had when it had its own table.
+	shapeField := "Parent"
+	if category == "building" {
+		shapeField = "Address"
+	}
+
+	if entity, ok := contents.Data.(map[string]interface{}); ok {
+		if _, hasField := entity[shapeField]; !hasField || entity["Entity"] == nil {
+			t.Errorf("Expected a %s to have Entity and %s fields. Got: %v", category, shapeField, entity)
+		}
+	}
+
 	if err := mockDB.ExpectationsWereMet(); err != nil {
 		t.Errorf("PostGres expectations were not met: %v", err)
 	}
@@ -183,7 +191,7 @@ func TestGetEntity(t *testing.T) {
 			defer res.Body.Close()
 
 			if tc.testValidInput {
-				validateGetEntitySuccessResponse(t, res, mockDB)
+				validateGetEntitySuccessResponse(t, res, mockDB, tc.category)
 			} else if (!tc.testValidInput) && (res.StatusCode != http.StatusBadRequest) {
 				t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusBadRequest, res.StatusCode)
 			}
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -44,7 +45,7 @@ func setupGetParentsTest(t *testing.T, userName string) (*http.Client, *httptest
 	postgres, mockDB := mocks.NewMockDB()
 	redis, mockCache := redismock.NewClientMock()
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: mocks.NewMockCognitoClient(ctrl),
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -59,79 +60,86 @@ func setupGetParentsTest(t *testing.T, userName string) (*http.Client, *httptest
 	return &http.Client{}, srv, mockDB, mockCache
 }
 
+func parentsGenerationKey(userName string) string {
+	return fmt.Sprintf(`{"User":"%s","Function":"EntitiesGeneration"}`, userName)
+}
+
+func parentsCacheKey(userName string, category string, generation int) string {
+	return fmt.Sprintf(`{"CacheKey":{"User":"%s","Function":"GetParents"},"Generation":%d,"Category":"%s"}`, userName, generation, category)
+}
+
 func setupGetParentsCacheMissMockExpectations(mockDB *sqlmock.Sqlmock, mockCache redismock.ClientMock, category string, userName string) {
 	switch category {
 	case "item":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetItemParents"}`, userName)
-		expectedSQL := `
-        (SELECT 'room' AS category, id, name FROM rooms WHERE user_id = $1 AND deleted_at IS NULL)
-        UNION ALL
-        (SELECT 'shelf' AS category, id, name FROM shelves WHERE user_id = $2 AND deleted_at IS NULL)
-        UNION ALL
-        (SELECT 'container' AS category, id, name FROM containers WHERE user_id = $3 AND deleted_at IS NULL)`
+		cacheKey := parentsCacheKey(userName, "item", 0)
+		expectedSQL := `SELECT category, id, name FROM entities WHERE user_id = $1 AND category IN ($2,$3,$4) AND deleted_at IS NULL
+        ORDER BY CASE category WHEN 'building' THEN 1 WHEN 'room' THEN 2 WHEN 'shelving_unit' THEN 3 WHEN 'shelf' THEN 4 WHEN 'container' THEN 5 WHEN 'item' THEN 6 END, id`
 
 		(*mockDB).ExpectQuery(regexp.QuoteMeta(expectedSQL)).
-			WithArgs(userName, userName, userName).
+			WithArgs(userName, "container", "shelf", "room").
 			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name"}).
 				AddRow("room", 1, "Room 1").
 				AddRow("shelf", 2, "Shelf 1").
 				AddRow("container", 3, "Container 1"))
 
+		mockCache.ExpectGet(parentsGenerationKey(userName)).RedisNil()
 		mockCache.ExpectGet(cacheKey).RedisNil()
 		mockCache.Regexp().ExpectSet(cacheKey, ".*", 5*time.Minute).SetVal("OK")
 		break
 	case "container":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetContainerParents"}`, userName)
-		expectedSQL := `
-        (SELECT 'room' AS category, id, name FROM rooms WHERE user_id = $1 AND deleted_at IS NULL)
-        UNION ALL
-        (SELECT 'shelf' AS category, id, name FROM shelves WHERE user_id = $2 AND deleted_at IS NULL)`
+		cacheKey := parentsCacheKey(userName, "container", 0)
+		expectedSQL := `SELECT category, id, name FROM entities WHERE user_id = $1 AND category IN ($2,$3) AND deleted_at IS NULL
+        ORDER BY CASE category WHEN 'building' THEN 1 WHEN 'room' THEN 2 WHEN 'shelving_unit' THEN 3 WHEN 'shelf' THEN 4 WHEN 'container' THEN 5 WHEN 'item' THEN 6 END, id`
 
 		(*mockDB).ExpectQuery(regexp.QuoteMeta(expectedSQL)).
-			WithArgs(userName, userName).
+			WithArgs(userName, "shelf", "room").
 			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name"}).
 				AddRow("room", 1, "Room 1").
 				AddRow("shelf", 2, "Shelf 1"))
 
+		mockCache.ExpectGet(parentsGenerationKey(userName)).RedisNil()
 		mockCache.ExpectGet(cacheKey).RedisNil()
 		mockCache.Regexp().ExpectSet(cacheKey, ".*", 5*time.Minute).SetVal("OK")
 		break
 	case "shelf":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetShelfParents"}`, userName)
-		expectedSQL := `
-        (SELECT 'shelving_unit' AS category, id, name FROM shelving_units WHERE user_id = $1 AND deleted_at IS NULL)`
+		cacheKey := parentsCacheKey(userName, "shelf", 0)
+		expectedSQL := `SELECT category, id, name FROM entities WHERE user_id = $1 AND category IN ($2) AND deleted_at IS NULL
+        ORDER BY CASE category WHEN 'building' THEN 1 WHEN 'room' THEN 2 WHEN 'shelving_unit' THEN 3 WHEN 'shelf' THEN 4 WHEN 'container' THEN 5 WHEN 'item' THEN 6 END, id`
 
 		(*mockDB).ExpectQuery(regexp.QuoteMeta(expectedSQL)).
-			WithArgs(userName).
+			WithArgs(userName, "shelving_unit").
 			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name"}).
 				AddRow("shelving_unit", 1, "Shelving Unit 1"))
 
+		mockCache.ExpectGet(parentsGenerationKey(userName)).RedisNil()
 		mockCache.ExpectGet(cacheKey).RedisNil()
 		mockCache.Regexp().ExpectSet(cacheKey, ".*", 5*time.Minute).SetVal("OK")
 		break
 	case "shelving_unit":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetShelving_unitParents"}`, userName)
-		expectedSQL := `
-        (SELECT 'room' AS category, id, name FROM rooms WHERE user_id = $1 AND deleted_at IS NULL)`
+		cacheKey := parentsCacheKey(userName, "shelving_unit", 0)
+		expectedSQL := `SELECT category, id, name FROM entities WHERE user_id = $1 AND category IN ($2) AND deleted_at IS NULL
+        ORDER BY CASE category WHEN 'building' THEN 1 WHEN 'room' THEN 2 WHEN 'shelving_unit' THEN 3 WHEN 'shelf' THEN 4 WHEN 'container' THEN 5 WHEN 'item' THEN 6 END, id`
 
 		(*mockDB).ExpectQuery(regexp.QuoteMeta(expectedSQL)).
-			WithArgs(userName).
+			WithArgs(userName, "room").
 			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name"}).
 				AddRow("room", 1, "Room 1"))
 
+		mockCache.ExpectGet(parentsGenerationKey(userName)).RedisNil()
 		mockCache.ExpectGet(cacheKey).RedisNil()
 		mockCache.Regexp().ExpectSet(cacheKey, ".*", 5*time.Minute).SetVal("OK")
 		break
 	case "room":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetRoomParents"}`, userName)
-		expectedSQL := `
-        (SELECT 'building' AS category, id, name FROM buildings WHERE user_id = $1 AND deleted_at IS NULL)`
+		cacheKey := parentsCacheKey(userName, "room", 0)
+		expectedSQL := `SELECT category, id, name FROM entities WHERE user_id = $1 AND category IN ($2) AND deleted_at IS NULL
+        ORDER BY CASE category WHEN 'building' THEN 1 WHEN 'room' THEN 2 WHEN 'shelving_unit' THEN 3 WHEN 'shelf' THEN 4 WHEN 'container' THEN 5 WHEN 'item' THEN 6 END, id`
 
 		(*mockDB).ExpectQuery(regexp.QuoteMeta(expectedSQL)).
-			WithArgs(userName).
+			WithArgs(userName, "building").
 			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name"}).
 				AddRow("building", 1, "Building 1"))
 
+		mockCache.ExpectGet(parentsGenerationKey(userName)).RedisNil()
 		mockCache.ExpectGet(cacheKey).RedisNil()
 		mockCache.Regexp().ExpectSet(cacheKey, ".*", 5*time.Minute).SetVal("OK")
 		break
@@ -142,7 +150,8 @@ func setupGetParentsCacheMissMockExpectations(mockDB *sqlmock.Sqlmock, mockCache
 func setupGetParentsCacheHitMockExpectations(mockCache redismock.ClientMock, category string, userName string) {
 	switch category {
 	case "item":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetItemParents"}`, userName)
+		cacheKey := parentsCacheKey(userName, "item", 2)
+		mockCache.ExpectGet(parentsGenerationKey(userName)).SetVal("2")
 		mockCache.ExpectGet(cacheKey).SetVal(`[
 			{"ID":13,"Name":"Cat Room","Category":"room"},
 			{"ID":11,"Name":"Shelf 1","Category":"shelf"},
@@ -150,26 +159,30 @@ func setupGetParentsCacheHitMockExpectations(mockCache redismock.ClientMock, cat
 		]`)
 		break
 	case "container":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetContainerParents"}`, userName)
+		cacheKey := parentsCacheKey(userName, "container", 2)
+		mockCache.ExpectGet(parentsGenerationKey(userName)).SetVal("2")
 		mockCache.ExpectGet(cacheKey).SetVal(`[
 			{"ID":13,"Name":"Cat Room","Category":"room"},
 			{"ID":11,"Name":"Shelf 1","Category":"shelf"}
 		]`)
 		break
 	case "shelf":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetShelfParents"}`, userName)
+		cacheKey := parentsCacheKey(userName, "shelf", 2)
+		mockCache.ExpectGet(parentsGenerationKey(userName)).SetVal("2")
 		mockCache.ExpectGet(cacheKey).SetVal(`[
 			{"ID":11,"Name":"Shelving Unit 1","Category":"shelving_unit"}
 		]`)
 		break
 	case "shelving_unit":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetShelving_unitParents"}`, userName)
+		cacheKey := parentsCacheKey(userName, "shelving_unit", 2)
+		mockCache.ExpectGet(parentsGenerationKey(userName)).SetVal("2")
 		mockCache.ExpectGet(cacheKey).SetVal(`[
 			{"ID":11,"Name":"Room 1","Category":"room"}
 		]`)
 		break
 	case "room":
-		cacheKey := fmt.Sprintf(`{"User":"%s","Function":"GetRoomParents"}`, userName)
+		cacheKey := parentsCacheKey(userName, "room", 2)
+		mockCache.ExpectGet(parentsGenerationKey(userName)).SetVal("2")
 		mockCache.ExpectGet(cacheKey).SetVal(`[
 			{"ID":11,"Name":"Building 1","Category":"building"}
 		]`)
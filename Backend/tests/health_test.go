@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/go-redis/redismock/v9"
+	"github.com/spf13/viper"
+)
+
+// fakeS3 is an S3 client whose bucket answers with headBucketErr.
+type fakeS3 struct {
+	headBucketErr error
+}
+
+func (f fakeS3) PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f fakeS3) HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func (f fakeS3) HeadBucket(context.Context, *s3.HeadBucketInput, ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, f.headBucketErr
+}
+
+// readinessHandler returns a handler whose Redis answers the ping with redisErr and whose bucket answers with s3Err.
+// Postgres and Cognito's signing keys are up. Every /readyz checks the dependencies again.
+func readinessHandler(t *testing.T, redisErr error, s3Err error) (controllers.Handler, redismock.ClientMock) {
+	postgres, _ := mocks.NewMockDB()
+	redis, mockCache := redismock.NewClientMock()
+	if redisErr != nil {
+		mockCache.ExpectPing().SetErr(redisErr)
+	} else {
+		mockCache.ExpectPing().SetVal("PONG")
+	}
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"keys":[]}`))
+	}))
+	t.Cleanup(jwks.Close)
+	viper.Set("AWS_JWKS_URL", jwks.URL)
+	viper.Set("METRICS_TOKEN", metricsToken)
+	viper.Set("READINESS_CACHE_TTL", "0s")
+	t.Cleanup(func() {
+		viper.Set("AWS_JWKS_URL", nil)
+		viper.Set("METRICS_TOKEN", "")
+		viper.Set("READINESS_CACHE_TTL", nil)
+	})
+
+	return controllers.Handler{
+		Repository: &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
+		S3Client:   fakeS3{headBucketErr: s3Err},
+	}, mockCache
+}
+
+// askReadiness asks /readyz, with the metrics token when token isn't empty.
+func askReadiness(t *testing.T, handler controllers.Handler, token string) (int, models.Readiness) {
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	handler.Readyz(w, req)
+
+	var body struct {
+		Data models.Readiness `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode the readiness: %v", err)
+	}
+
+	return w.Code, body.Data
+}
+
+// checkReadiness asks /readyz with the metrics token, with Redis answering the ping with redisErr and the bucket
+// with s3Err. Postgres and Cognito's signing keys are up.
+func checkReadiness(t *testing.T, redisErr error, s3Err error) (int, models.Readiness) {
+	handler, mockCache := readinessHandler(t, redisErr, s3Err)
+	status, readiness := askReadiness(t, handler, metricsToken)
+
+	if err := mockCache.ExpectationsWereMet(); err != nil {
+		t.Errorf("Redis expectations were not met: %v", err)
+	}
+
+	return status, readiness
+}
+
+// TestHealth runs the unit tests for the liveness and readiness probes.
+func TestHealth(t *testing.T) {
+	t.Run("BEUT-225: Liveness Checks No Dependencies", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		controllers.Handler{}.Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected 200 without any dependencies. Got: %d", w.Code)
+		}
+	})
+
+	t.Run("BEUT-226: Ready When Every Dependency Is Up", func(t *testing.T) {
+		status, readiness := checkReadiness(t, nil, nil)
+		if status != http.StatusOK || readiness.Status != models.HealthUp {
+			t.Errorf("Expected 200 and up. Got: %d %v", status, readiness)
+		}
+
+		for _, name := range []string{"postgres", "redis", "s3", "cognito"} {
+			if readiness.Dependencies[name].Status != models.HealthUp {
+				t.Errorf("Expected %s to be up. Got: %v", name, readiness.Dependencies[name])
+			}
+		}
+	})
+
+	t.Run("BEUT-227: Only Required Dependencies Make The API Unready", func(t *testing.T) {
+		status, readiness := checkReadiness(t, errors.New("connection refused"), nil)
+		if status != http.StatusOK || readiness.Status != models.HealthDegraded {
+			t.Errorf("Expected 200 and degraded while Redis is down. Got: %d %v", status, readiness)
+		}
+
+		if redis := readiness.Dependencies["redis"]; redis.Status != models.HealthDown || redis.Required || redis.Error == "" {
+			t.Errorf("Expected Redis to be reported down with its error but not required. Got: %v", redis)
+		}
+
+		status, readiness = checkReadiness(t, nil, errors.New("access denied"))
+		if status != http.StatusOK || readiness.Status != models.HealthDegraded {
+			t.Errorf("Expected 200 and degraded while S3 is down. Got: %d %v", status, readiness)
+		}
+
+		if bucket := readiness.Dependencies["s3"]; bucket.Status != models.HealthDown || bucket.Required {
+			t.Errorf("Expected S3 to be reported down but not required. Got: %v", bucket)
+		}
+	})
+	t.Run("BEUT-243: Only The Metrics Token Sees How Each Dependency Answered", func(t *testing.T) {
+		handler, _ := readinessHandler(t, nil, errors.New("bucket vault-prod: access denied"))
+
+		status, readiness := askReadiness(t, handler, "")
+		if status != http.StatusOK || readiness.Status != models.HealthDegraded {
+			t.Errorf("Expected 200 and degraded. Got: %d %v", status, readiness)
+		}
+
+		if readiness.Dependencies != nil {
+			t.Errorf("Expected no dependencies without the metrics token. Got: %v", readiness.Dependencies)
+		}
+
+		_, readiness = askReadiness(t, handler, "wrong-token")
+		if readiness.Dependencies != nil {
+			t.Errorf("Expected no dependencies with the wrong token. Got: %v", readiness.Dependencies)
+		}
+	})
+
+	t.Run("BEUT-244: Dependency Checks Are Reused For A While", func(t *testing.T) {
+		checkReadiness(t, nil, nil)
+
+		// Redis expects no ping and the bucket is down now, but the last check is still fresh.
+		viper.Set("READINESS_CACHE_TTL", "1m")
+		redis, mockCache := redismock.NewClientMock()
+		handler := controllers.Handler{
+			Repository: &repository.Repository{Cache: cache.NewRedisCache(redis), Redis: redis},
+			S3Client:   fakeS3{headBucketErr: errors.New("access denied")},
+		}
+
+		for i := 0; i < 3; i++ {
+			status, readiness := askReadiness(t, handler, metricsToken)
+			if status != http.StatusOK || readiness.Status != models.HealthUp {
+				t.Errorf("Expected the last check to be reused. Got: %d %v", status, readiness)
+			}
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+}
@@ -0,0 +1,271 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/routers/middlewares"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+)
+
+const idempotencyBody = `{"name":"Scarf","category":"item","parentID":10,"parentCategory":"container"}`
+
+var idempotencyCacheKey = `{"CacheKey":{"User":"testuser","Function":"Idempotency"},"IP":"","Key":"retry-1"}`
+
+var anonymousIdempotencyCacheKey = `{"CacheKey":{"User":"","Function":"Idempotency"},"IP":"127.0.0.1","Key":"retry-1"}`
+
+// setupIdempotencyTest serves a route behind the idempotency middleware that answers with the given status and
+// counts how many times it really ran.
+func setupIdempotencyTest(t *testing.T, status int) (*http.Client, *httptest.Server, redismock.ClientMock, *int) {
+	return setupIdempotencyTestAs(t, "testuser", status)
+}
+
+// setupIdempotencyTestAs is setupIdempotencyTest with the requests sent by the given user, or by no one when it's
+// empty.
+func setupIdempotencyTestAs(t *testing.T, userID string, status int) (*http.Client, *httptest.Server, redismock.ClientMock, *int) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	redis, mockCache := redismock.NewClientMock()
+	handler := controllers.Handler{
+		Repository:    &repository.Repository{Cache: cache.NewRedisCache(redis), Redis: redis},
+		CognitoClient: mocks.NewMockCognitoClient(ctrl),
+		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+	}
+
+	calls := 0
+	r := chi.NewRouter()
+	if userID != "" {
+		r.Use(mocks.MockJWTMiddleware(userID))
+	}
+	r.Use(middlewares.Idempotency(handler))
+	r.HandleFunc("/v1/entity", func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("RateLimit-Limit", "10")
+		w.Header().Set("RateLimit-Remaining", "9")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"message":"success","data":{"ID":1}}`))
+	})
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &http.Client{}, srv, mockCache, &calls
+}
+
+func idempotencyFingerprint(method string, body string) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " /v1/entity\napplication/json\n\n" + body))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func idempotencyRecord(response models.IdempotentResponse) []byte {
+	data, _ := json.Marshal(response)
+	return data
+}
+
+func sendIdempotent(t *testing.T, client *http.Client, method string, url string, key string, body string) *http.Response {
+	req, _ := http.NewRequest(method, url+"/v1/entity", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	return res
+}
+
+// TestIdempotency runs the unit tests for replaying retried requests sent with an Idempotency-Key.
+func TestIdempotency(t *testing.T) {
+	fingerprint := idempotencyFingerprint(http.MethodPost, idempotencyBody)
+	claim := idempotencyRecord(models.IdempotentResponse{Fingerprint: fingerprint})
+	stored := models.IdempotentResponse{
+		Fingerprint: fingerprint,
+		Done:        true,
+		Status:      http.StatusOK,
+		Header:      http.Header{"Content-Type": {"application/json"}},
+		Body:        []byte(`{"message":"success","data":{"ID":1}}`),
+	}
+
+	t.Run("BEUT-192: Retried Request Is Replayed", func(t *testing.T) {
+		client, srv, mockCache, calls := setupIdempotencyTest(t, http.StatusOK)
+
+		mockCache.ExpectSetNX(idempotencyCacheKey, claim, time.Minute).SetVal(true)
+		mockCache.ExpectSet(idempotencyCacheKey, idempotencyRecord(stored), repository.IdempotencyTTL).SetVal("OK")
+		mockCache.ExpectSetNX(idempotencyCacheKey, claim, time.Minute).SetVal(false)
+		mockCache.ExpectGet(idempotencyCacheKey).SetVal(string(idempotencyRecord(stored)))
+
+		first := sendIdempotent(t, client, http.MethodPost, srv.URL, "retry-1", idempotencyBody)
+		first.Body.Close()
+
+		retry := sendIdempotent(t, client, http.MethodPost, srv.URL, "retry-1", idempotencyBody)
+		defer retry.Body.Close()
+
+		data, _ := io.ReadAll(retry.Body)
+		if retry.StatusCode != http.StatusOK || string(data) != string(stored.Body) {
+			t.Errorf("Expected the stored response to be replayed. Got: %d %s", retry.StatusCode, data)
+		}
+
+		if retry.Header.Get("Idempotent-Replayed") != "true" {
+			t.Errorf("Expected Idempotent-Replayed to be true. Got: %q", retry.Header.Get("Idempotent-Replayed"))
+		}
+
+		if *calls != 1 {
+			t.Errorf("Expected the handler to run once. Got: %d", *calls)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-193: Reused Key With A Different Body", func(t *testing.T) {
+		client, srv, mockCache, calls := setupIdempotencyTest(t, http.StatusOK)
+
+		otherBody := `{"name":"Hat","category":"item","parentID":10,"parentCategory":"container"}`
+		otherClaim := idempotencyRecord(models.IdempotentResponse{Fingerprint: idempotencyFingerprint(http.MethodPost, otherBody)})
+		mockCache.ExpectSetNX(idempotencyCacheKey, otherClaim, time.Minute).SetVal(false)
+		mockCache.ExpectGet(idempotencyCacheKey).SetVal(string(idempotencyRecord(stored)))
+
+		res := sendIdempotent(t, client, http.MethodPost, srv.URL, "retry-1", otherBody)
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusUnprocessableEntity, res.StatusCode)
+		}
+
+		contents := validationResponse{}
+		data, _ := io.ReadAll(res.Body)
+		if err := json.Unmarshal(data, &contents); err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		if len(contents.Data) != 1 || contents.Data[0].Code != models.ValidationKeyReused {
+			t.Errorf("Expected a %s field error. Got: %+v", models.ValidationKeyReused, contents.Data)
+		}
+
+		if *calls != 0 {
+			t.Errorf("Expected the handler not to run. Got: %d", *calls)
+		}
+	})
+
+	t.Run("BEUT-194: Retry While The First Request Is Running", func(t *testing.T) {
+		client, srv, mockCache, calls := setupIdempotencyTest(t, http.StatusOK)
+
+		mockCache.ExpectSetNX(idempotencyCacheKey, claim, time.Minute).SetVal(false)
+		mockCache.ExpectGet(idempotencyCacheKey).SetVal(string(claim))
+
+		res := sendIdempotent(t, client, http.MethodPost, srv.URL, "retry-1", idempotencyBody)
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusConflict {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusConflict, res.StatusCode)
+		}
+
+		if *calls != 0 {
+			t.Errorf("Expected the handler not to run. Got: %d", *calls)
+		}
+	})
+
+	t.Run("BEUT-195: Server Errors Are Not Kept", func(t *testing.T) {
+		client, srv, mockCache, _ := setupIdempotencyTest(t, http.StatusInternalServerError)
+
+		mockCache.ExpectSetNX(idempotencyCacheKey, claim, time.Minute).SetVal(true)
+		mockCache.ExpectDel(idempotencyCacheKey).SetVal(1)
+
+		res := sendIdempotent(t, client, http.MethodPost, srv.URL, "retry-1", idempotencyBody)
+		res.Body.Close()
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-196: Safe Methods Ignore The Key", func(t *testing.T) {
+		client, srv, mockCache, calls := setupIdempotencyTest(t, http.StatusOK)
+
+		res := sendIdempotent(t, client, http.MethodGet, srv.URL, "retry-1", "")
+		res.Body.Close()
+
+		if *calls != 1 {
+			t.Errorf("Expected the handler to run once. Got: %d", *calls)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+	t.Run("BEUT-235: Keys Sent Before Signing In Belong To The IP", func(t *testing.T) {
+		client, srv, mockCache, calls := setupIdempotencyTestAs(t, "", http.StatusOK)
+
+		mockCache.ExpectSetNX(anonymousIdempotencyCacheKey, claim, time.Minute).SetVal(true)
+		mockCache.ExpectSet(anonymousIdempotencyCacheKey, idempotencyRecord(stored), repository.AnonymousIdempotencyTTL).SetVal("OK")
+
+		res := sendIdempotent(t, client, http.MethodPost, srv.URL, "retry-1", idempotencyBody)
+		res.Body.Close()
+
+		if *calls != 1 {
+			t.Errorf("Expected the handler to run once. Got: %d", *calls)
+		}
+
+		// The stored response leaves out the RateLimit headers, so they aren't replayed stale.
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-236: Rate Limited Responses Are Not Kept", func(t *testing.T) {
+		client, srv, mockCache, _ := setupIdempotencyTest(t, http.StatusTooManyRequests)
+
+		mockCache.ExpectSetNX(idempotencyCacheKey, claim, time.Minute).SetVal(true)
+		mockCache.ExpectDel(idempotencyCacheKey).SetVal(1)
+
+		res := sendIdempotent(t, client, http.MethodPost, srv.URL, "retry-1", idempotencyBody)
+		res.Body.Close()
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-237: Claim Gives Up When It Keeps Expiring", func(t *testing.T) {
+		client, srv, mockCache, calls := setupIdempotencyTest(t, http.StatusOK)
+
+		for i := 0; i < 3; i++ {
+			mockCache.ExpectSetNX(idempotencyCacheKey, claim, time.Minute).SetVal(false)
+			mockCache.ExpectGet(idempotencyCacheKey).RedisNil()
+		}
+
+		res := sendIdempotent(t, client, http.MethodPost, srv.URL, "retry-1", idempotencyBody)
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusConflict {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusConflict, res.StatusCode)
+		}
+
+		if *calls != 0 {
+			t.Errorf("Expected the handler not to run. Got: %d", *calls)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/routers/middlewares"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// captureLogs sends the log lines of the test to a buffer, in format at level.
+func captureLogs(t *testing.T, format string, level string) *bytes.Buffer {
+	var buf bytes.Buffer
+	if err := logger.Init(format, level); err != nil {
+		t.Fatalf("Expected error to be nil. Got: %v", err)
+	}
+	logger.SetOutput(&buf)
+	t.Cleanup(func() {
+		logger.Init("json", "info")
+		logger.SetOutput(os.Stderr)
+	})
+
+	return &buf
+}
+
+// logLines decodes every JSON line in buf.
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("Expected every line to be JSON. Got %q: %v", raw, err)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// TestLogger runs the unit tests for the structured logs.
+func TestLogger(t *testing.T) {
+	t.Run("BEUT-223: Request Lines Carry The Request's Fields", func(t *testing.T) {
+		buf := captureLogs(t, "json", "info")
+
+		r := chi.NewRouter()
+		r.Use(middleware.RequestID)
+		r.Use(middlewares.RequestLogger(nil))
+		r.Get("/beut-223/{id}", func(w http.ResponseWriter, request *http.Request) {
+			logger.AddFields(request.Context(), logger.Fields{"user_id": "Testing"})
+			logger.Ctx(request.Context()).Errorf("something went wrong")
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/beut-223/7", nil)
+		req.Header.Set("X-Request-Id", "beut-223")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		lines := logLines(t, buf)
+		if len(lines) != 2 {
+			t.Fatalf("Expected the handler's line and the request line. Got: %v", lines)
+		}
+
+		handlerLine, requestLine := lines[0], lines[1]
+		if handlerLine["msg"] != "something went wrong" || handlerLine["request_id"] != "beut-223" || handlerLine["user_id"] != "Testing" {
+			t.Errorf("Expected the handler's line to carry the request ID and user. Got: %v", handlerLine)
+		}
+
+		if !strings.HasPrefix(handlerLine["caller"].(string), "tests/logger_test.go:") {
+			t.Errorf("Expected the caller to be the handler. Got: %v", handlerLine["caller"])
+		}
+
+		if requestLine["route"] != "/beut-223/{id}" || requestLine["status"] != float64(http.StatusTeapot) || requestLine["user_id"] != "Testing" {
+			t.Errorf("Expected the request line to carry the route, status and user. Got: %v", requestLine)
+		}
+
+		if _, ok := requestLine["latency_ms"].(float64); !ok {
+			t.Errorf("Expected the request line to carry the latency. Got: %v", requestLine)
+		}
+
+		if requestLine["level"] != "warning" {
+			t.Errorf("Expected a 4xx to be logged as a warning. Got: %v", requestLine["level"])
+		}
+	})
+
+	t.Run("BEUT-224: Log Format And Level Are Configurable", func(t *testing.T) {
+		if err := logger.Init("xml", "info"); err == nil {
+			t.Errorf("Expected an unknown format to be refused")
+		}
+
+		if err := logger.Init("json", "loud"); err == nil {
+			t.Errorf("Expected an unknown level to be refused")
+		}
+
+		buf := captureLogs(t, "logfmt", "warn")
+
+		logger.Infof("not written")
+		logger.WithFields(logger.Fields{"user_id": "Testing"}).Warnf("written")
+
+		output := buf.String()
+		if strings.Contains(output, "not written") {
+			t.Errorf("Expected lines below the level to be dropped. Got: %s", output)
+		}
+
+		if !strings.Contains(output, `level=warning msg=written`) || !strings.Contains(output, "user_id=Testing") {
+			t.Errorf("Expected a logfmt line with its fields. Got: %s", output)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v9"
+)
+
+// TestMaintenance runs the unit tests for the repository functions behind vault-admin.
+func TestMaintenance(t *testing.T) {
+	t.Run("BEUT-163: Find Orphans Checks Parent And Category", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+		repo := repository.Repository{Database: postgres}
+
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT c.category, c.id, c.name, c.user_id, COALESCE(c.parent_id, 0) AS parent_id, COALESCE(c.parent_category, '') AS parent_category FROM entities c ` +
+			`LEFT JOIN entities p ON p.category = c.parent_category AND p.id = c.parent_id AND p.user_id = c.user_id AND p.deleted_at IS NULL ` +
+			`WHERE c.deleted_at IS NULL AND c.category <> 'building' AND c.user_id = $1 AND (p.id IS NULL OR (c.category, c.parent_category) NOT IN (` +
+			`('room', 'building'), ('shelving_unit', 'room'), ('shelf', 'shelving_unit'), ('container', 'shelf'), ('container', 'room'), ` +
+			`('item', 'container'), ('item', 'shelf'), ('item', 'room'))) ORDER BY c.user_id, c.category, c.id`)).
+			WithArgs("user1").
+			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name", "user_id", "parent_id", "parent_category"}).
+				AddRow("item", 7, "Whisk", "user1", 4, "container").
+				AddRow("room", 2, "Kitchen", "user1", 0, ""))
+
+		orphans, err := repo.FindOrphans("user1")
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if len(orphans) != 2 || orphans[0].ID != 7 || orphans[0].ParentCategory != "container" || orphans[1].Name != "Kitchen" {
+			t.Errorf("Expected the whisk and the kitchen to be orphans. Got: %+v", orphans)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("BEUT-164: Purge Deleted Counts Every Category", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+		repo := repository.Repository{Database: postgres}
+		before := time.Now().Add(-time.Hour)
+
+		mockDB.ExpectQuery(regexp.QuoteMeta(`DELETE FROM entities WHERE deleted_at IS NOT NULL AND deleted_at < $1 AND NOT EXISTS `+
+			`(SELECT 1 FROM entities c WHERE c.parent_category = entities.category AND c.parent_id = entities.id AND (c.deleted_at IS NULL OR c.deleted_at >= $2)) RETURNING category`)).
+			WithArgs(before, before).
+			WillReturnRows(sqlmock.NewRows([]string{"category"}).AddRow("item").AddRow("room").AddRow("item"))
+
+		counts, err := repo.PurgeDeleted(before)
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		expected := map[string]int64{"room": 1, "item": 2}
+		if len(counts) != 6 || counts[0].Category != "building" {
+			t.Fatalf("Expected a count for every category, buildings first. Got: %+v", counts)
+		}
+
+		for _, count := range counts {
+			if count.Rows != expected[count.Category] {
+				t.Errorf("Expected %d %s rows to be purged. Got: %d", expected[count.Category], count.Category, count.Rows)
+			}
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("BEUT-165: Flush All Entities Flushes Every Owner", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+		redis, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis}
+
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT user_id FROM entities ORDER BY user_id`)).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user1").AddRow("user2"))
+
+		for _, user := range []string{"user1", "user2"} {
+			expectEntitiesFlush(mockCache, user)
+		}
+
+		users, err := repo.FlushAllEntities(context.Background())
+		if err != nil || users != 2 {
+			t.Fatalf("Expected 2 users to be flushed. Got: %d, %v", users, err)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled cache expectations: %s", err)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/metrics"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/spf13/viper"
+)
+
+const metricsToken = "scraper-token"
+
+// scrapeMetrics reads /metrics the way Prometheus would, with token as the bearer token.
+func scrapeMetrics(t *testing.T, token string) (int, string) {
+	viper.Set("METRICS_TOKEN", metricsToken)
+	t.Cleanup(func() { viper.Set("METRICS_TOKEN", "") })
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	controllers.Handler{}.Metrics(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	return w.Code, string(body)
+}
+
+// TestMetrics runs the unit tests for the Prometheus metrics endpoint.
+func TestMetrics(t *testing.T) {
+	t.Run("BEUT-217: Metrics Need The Metrics Token", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		controllers.Handler{}.Metrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected metrics to be off without a token. Got: %d", w.Code)
+		}
+
+		if status, _ := scrapeMetrics(t, ""); status != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without a token. Got: %d", status)
+		}
+
+		if status, _ := scrapeMetrics(t, "a user's access token"); status != http.StatusUnauthorized {
+			t.Errorf("Expected 401 with the wrong token. Got: %d", status)
+		}
+
+		status, body := scrapeMetrics(t, metricsToken)
+		if status != http.StatusOK {
+			t.Fatalf("Expected 200 with the metrics token. Got: %d", status)
+		}
+
+		for _, name := range []string{"go_goroutines", "go_memstats_alloc_bytes", "process_start_time_seconds"} {
+			if !strings.Contains(body, name) {
+				t.Errorf("Expected the metrics to include %s", name)
+			}
+		}
+	})
+
+	t.Run("BEUT-218: Requests Are Counted By Route Pattern", func(t *testing.T) {
+		r := chi.NewRouter()
+		r.Use(metrics.Middleware)
+		r.Get("/beut-218/{category}/{id}", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+		for _, path := range []string{"/beut-218/item/1", "/beut-218/item/2", "/beut-218/nowhere"} {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		_, body := scrapeMetrics(t, metricsToken)
+		if !strings.Contains(body, `vault_http_requests_total{method="GET",route="/beut-218/{category}/{id}",status="418"} 2`) {
+			t.Errorf("Expected both requests to be counted under their route pattern")
+		}
+
+		if !regexp.MustCompile(`vault_http_request_duration_seconds_count\{method="GET",route="/beut-218/\{category\}/\{id\}"\} 2`).MatchString(body) {
+			t.Errorf("Expected both requests to be timed under their route pattern")
+		}
+
+		if !regexp.MustCompile(`vault_http_requests_total\{method="GET",route="unmatched",status="404"\} [1-9]`).MatchString(body) {
+			t.Errorf("Expected the unmatched request to be counted as unmatched")
+		}
+	})
+
+	t.Run("BEUT-219: Database Queries Are Timed", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+		if err := metrics.InstrumentDB(postgres); err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		_, before := scrapeMetrics(t, metricsToken)
+
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT 1`)).WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+		var one int
+		postgres.Raw(`SELECT 1`).Scan(&one)
+
+		_, after := scrapeMetrics(t, metricsToken)
+
+		count := regexp.MustCompile(`vault_db_query_duration_seconds_count\{operation="row"\} (\d+)`)
+		beforeCount, afterCount := "0", ""
+		if match := count.FindStringSubmatch(before); match != nil {
+			beforeCount = match[1]
+		}
+		if match := count.FindStringSubmatch(after); match != nil {
+			afterCount = match[1]
+		}
+
+		if afterCount == "" || afterCount == beforeCount {
+			t.Errorf("Expected the query to be timed. Got %s then %s", beforeCount, afterCount)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+}
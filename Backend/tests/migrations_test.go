@@ -0,0 +1,121 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"willowsuite-vault/migrations"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectMigrationLock(mockDB sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mockDB.ExpectBegin()
+	mockDB.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mockDB.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT version, name, applied_at FROM schema_migrations`)).WillReturnRows(applied)
+}
+
+// TestMigrations runs the unit tests for the versioned migrations.
+func TestMigrations(t *testing.T) {
+	all, err := migrations.Load()
+	if err != nil {
+		t.Fatalf("Expected the embedded migrations to load. Got: %v", err)
+	}
+
+	t.Run("BEUT-166: Migrations Are Numbered In Order", func(t *testing.T) {
+		if len(all) < 2 {
+			t.Fatalf("Expected at least the schema and index migrations. Got: %d", len(all))
+		}
+
+		for i, migration := range all {
+			if migration.Version != int64(i+1) {
+				t.Errorf("Expected migration %s to be version %d. Got: %d", migration.Name, i+1, migration.Version)
+			}
+
+			if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+				t.Errorf("Expected migration %s to have up and down SQL.", migration.Name)
+			}
+		}
+	})
+
+	t.Run("BEUT-167: Migrate Up Applies Pending Migrations", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+
+		expectMigrationLock(mockDB, sqlmock.NewRows([]string{"version", "name", "applied_at"}).AddRow(1, all[0].Name, time.Now()))
+		for _, migration := range all[1:] {
+			mockDB.ExpectExec(regexp.QuoteMeta(migration.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`)).
+				WithArgs(migration.Version, migration.Name).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mockDB.ExpectCommit()
+
+		ran, err := migrations.Up(postgres)
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		if len(ran) != len(all)-1 || ran[0].Version != 2 {
+			t.Errorf("Expected every migration after the first to run. Got: %+v", ran)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("BEUT-168: Failed Migration Rolls Everything Back", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+
+		expectMigrationLock(mockDB, sqlmock.NewRows([]string{"version", "name", "applied_at"}))
+		mockDB.ExpectExec(regexp.QuoteMeta(all[0].Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mockDB.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectExec(regexp.QuoteMeta(all[1].Up)).WillReturnError(errors.New("relation does not exist"))
+		mockDB.ExpectRollback()
+
+		ran, err := migrations.Up(postgres)
+		if err == nil || !strings.Contains(err.Error(), all[1].Name) {
+			t.Fatalf("Expected the error to name the failed migration. Got: %v", err)
+		}
+
+		if len(ran) != 0 {
+			t.Errorf("Expected no migrations to be reported as applied. Got: %+v", ran)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("BEUT-169: Migrate Down Reverts The Latest Migration", func(t *testing.T) {
+		postgres, mockDB := mocks.NewMockDB()
+		latest := all[len(all)-1]
+
+		rows := sqlmock.NewRows([]string{"version", "name", "applied_at"})
+		for _, migration := range all {
+			rows.AddRow(migration.Version, migration.Name, time.Now())
+		}
+
+		expectMigrationLock(mockDB, rows)
+		mockDB.ExpectExec(regexp.QuoteMeta(latest.Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mockDB.ExpectExec(regexp.QuoteMeta(`DELETE FROM schema_migrations WHERE version = $1`)).
+			WithArgs(latest.Version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		reverted, err := migrations.Down(postgres, 1)
+		if err != nil || len(reverted) != 1 || reverted[0].Version != latest.Version {
+			t.Fatalf("Expected only %d_%s to be reverted. Got: %+v, %v", latest.Version, latest.Name, reverted, err)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+}
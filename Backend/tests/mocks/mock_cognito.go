@@ -35,6 +35,26 @@ func (m *MockCognitoClient) EXPECT() *MockCognitoClientMockRecorder {
 	return m.recorder
 }
 
+// AssociateSoftwareToken mocks base method.
+func (m *MockCognitoClient) AssociateSoftwareToken(ctx context.Context, params *cognitoidentityprovider.AssociateSoftwareTokenInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.AssociateSoftwareTokenOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "AssociateSoftwareToken", varargs...)
+	ret0, _ := ret[0].(*cognitoidentityprovider.AssociateSoftwareTokenOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssociateSoftwareToken indicates an expected call of AssociateSoftwareToken.
+func (mr *MockCognitoClientMockRecorder) AssociateSoftwareToken(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssociateSoftwareToken", reflect.TypeOf((*MockCognitoClient)(nil).AssociateSoftwareToken), varargs...)
+}
+
 // ConfirmSignUp mocks base method.
 func (m *MockCognitoClient) ConfirmSignUp(ctx context.Context, params *cognitoidentityprovider.ConfirmSignUpInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.ConfirmSignUpOutput, error) {
 	m.ctrl.T.Helper()
@@ -55,6 +75,26 @@ func (mr *MockCognitoClientMockRecorder) ConfirmSignUp(ctx, params interface{},
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmSignUp", reflect.TypeOf((*MockCognitoClient)(nil).ConfirmSignUp), varargs...)
 }
 
+// GlobalSignOut mocks base method.
+func (m *MockCognitoClient) GlobalSignOut(ctx context.Context, params *cognitoidentityprovider.GlobalSignOutInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.GlobalSignOutOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GlobalSignOut", varargs...)
+	ret0, _ := ret[0].(*cognitoidentityprovider.GlobalSignOutOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GlobalSignOut indicates an expected call of GlobalSignOut.
+func (mr *MockCognitoClientMockRecorder) GlobalSignOut(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GlobalSignOut", reflect.TypeOf((*MockCognitoClient)(nil).GlobalSignOut), varargs...)
+}
+
 // InitiateAuth mocks base method.
 func (m *MockCognitoClient) InitiateAuth(ctx context.Context, params *cognitoidentityprovider.InitiateAuthInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.InitiateAuthOutput, error) {
 	m.ctrl.T.Helper()
@@ -75,6 +115,26 @@ func (mr *MockCognitoClientMockRecorder) InitiateAuth(ctx, params interface{}, o
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateAuth", reflect.TypeOf((*MockCognitoClient)(nil).InitiateAuth), varargs...)
 }
 
+// RespondToAuthChallenge mocks base method.
+func (m *MockCognitoClient) RespondToAuthChallenge(ctx context.Context, params *cognitoidentityprovider.RespondToAuthChallengeInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RespondToAuthChallenge", varargs...)
+	ret0, _ := ret[0].(*cognitoidentityprovider.RespondToAuthChallengeOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RespondToAuthChallenge indicates an expected call of RespondToAuthChallenge.
+func (mr *MockCognitoClientMockRecorder) RespondToAuthChallenge(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RespondToAuthChallenge", reflect.TypeOf((*MockCognitoClient)(nil).RespondToAuthChallenge), varargs...)
+}
+
 // RevokeToken mocks base method.
 func (m *MockCognitoClient) RevokeToken(ctx context.Context, params *cognitoidentityprovider.RevokeTokenInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.RevokeTokenOutput, error) {
 	m.ctrl.T.Helper()
@@ -95,6 +155,26 @@ func (mr *MockCognitoClientMockRecorder) RevokeToken(ctx, params interface{}, op
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockCognitoClient)(nil).RevokeToken), varargs...)
 }
 
+// SetUserMFAPreference mocks base method.
+func (m *MockCognitoClient) SetUserMFAPreference(ctx context.Context, params *cognitoidentityprovider.SetUserMFAPreferenceInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.SetUserMFAPreferenceOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SetUserMFAPreference", varargs...)
+	ret0, _ := ret[0].(*cognitoidentityprovider.SetUserMFAPreferenceOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetUserMFAPreference indicates an expected call of SetUserMFAPreference.
+func (mr *MockCognitoClientMockRecorder) SetUserMFAPreference(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserMFAPreference", reflect.TypeOf((*MockCognitoClient)(nil).SetUserMFAPreference), varargs...)
+}
+
 // SignUp mocks base method.
 func (m *MockCognitoClient) SignUp(ctx context.Context, params *cognitoidentityprovider.SignUpInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.SignUpOutput, error) {
 	m.ctrl.T.Helper()
@@ -114,3 +194,23 @@ func (mr *MockCognitoClientMockRecorder) SignUp(ctx, params interface{}, optFns
 	varargs := append([]interface{}{ctx, params}, optFns...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockCognitoClient)(nil).SignUp), varargs...)
 }
+
+// VerifySoftwareToken mocks base method.
+func (m *MockCognitoClient) VerifySoftwareToken(ctx context.Context, params *cognitoidentityprovider.VerifySoftwareTokenInput, optFns ...func(*cognitoidentityprovider.Options)) (*cognitoidentityprovider.VerifySoftwareTokenOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "VerifySoftwareToken", varargs...)
+	ret0, _ := ret[0].(*cognitoidentityprovider.VerifySoftwareTokenOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifySoftwareToken indicates an expected call of VerifySoftwareToken.
+func (mr *MockCognitoClientMockRecorder) VerifySoftwareToken(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifySoftwareToken", reflect.TypeOf((*MockCognitoClient)(nil).VerifySoftwareToken), varargs...)
+}
@@ -22,3 +22,15 @@ func MockJWTMiddleware(userName string) func(next http.Handler) http.Handler {
 		})
 	}
 }
+
+func MockJWTMiddlewareWithClaims(claims jwt.MapClaims) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, "user_claims", claims)
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
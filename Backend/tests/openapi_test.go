@@ -0,0 +1,148 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"willowsuite-vault/docs"
+	"willowsuite-vault/routers"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components map[string]map[string]json.RawMessage `json:"components"`
+}
+
+func loadOpenAPIDocument(t *testing.T, data []byte) openAPIDocument {
+	spec := openAPIDocument{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("Expected the OpenAPI document to be valid JSON. Got: %v", err)
+	}
+
+	return spec
+}
+
+func registeredRoutes(t *testing.T) map[string][]string {
+	r := chi.NewRouter()
+	routers.RegisterRoutes(r, nil)
+
+	routes := map[string][]string{}
+	err := chi.Walk(r, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/")
+		if route == "" {
+			route = "/"
+		}
+
+		routes[route] = append(routes[route], strings.ToLower(method))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk routes: %v", err)
+	}
+
+	return routes
+}
+
+// TestOpenAPI runs the unit tests for the OpenAPI document.
+func TestOpenAPI(t *testing.T) {
+	spec := loadOpenAPIDocument(t, docs.Spec)
+	routes := registeredRoutes(t)
+
+	t.Run("BEUT-148: OpenAPI Documents Every Route", func(t *testing.T) {
+		for route, methods := range routes {
+			for _, method := range methods {
+				if _, ok := spec.Paths[route][method]; !ok {
+					t.Errorf("Expected %s %s to be in the OpenAPI document.", strings.ToUpper(method), route)
+				}
+			}
+		}
+	})
+
+	t.Run("BEUT-149: OpenAPI Has No Stale Routes", func(t *testing.T) {
+		for route, operations := range spec.Paths {
+			for method := range operations {
+				registered := false
+				for _, registeredMethod := range routes[route] {
+					registered = registered || registeredMethod == method
+				}
+
+				if !registered {
+					t.Errorf("Expected %s %s in the OpenAPI document to be a registered route.", strings.ToUpper(method), route)
+				}
+			}
+		}
+	})
+
+	t.Run("BEUT-150: OpenAPI References Resolve", func(t *testing.T) {
+		refs := regexp.MustCompile(`"\$ref":\s*"#/components/([^/]+)/([^"]+)"`).FindAllStringSubmatch(string(docs.Spec), -1)
+		for _, ref := range refs {
+			if _, ok := spec.Components[ref[1]][ref[2]]; !ok {
+				t.Errorf("Expected #/components/%s/%s to be defined.", ref[1], ref[2])
+			}
+		}
+	})
+
+	t.Run("BEUT-151: OpenAPI Served At Runtime", func(t *testing.T) {
+		r := chi.NewRouter()
+		routers.RegisterRoutes(r, nil)
+
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		res, err := http.Get(srv.URL + "/v1/openapi.json")
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusOK, res.StatusCode)
+		}
+
+		data, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		served := loadOpenAPIDocument(t, data)
+		if len(served.Paths) != len(spec.Paths) {
+			t.Errorf("Expected %d paths to be served. Got: %d", len(spec.Paths), len(served.Paths))
+		}
+	})
+
+	t.Run("BEUT-252: Docs Load Nothing From Another Origin", func(t *testing.T) {
+		sources := regexp.MustCompile(`(?:src|href)="([^"]*)"`).FindAllStringSubmatch(string(docs.UI), -1)
+		for _, source := range sources {
+			if strings.Contains(source[1], "//") {
+				t.Errorf("Expected %s to be served by the API.", source[1])
+			}
+		}
+	})
+
+	t.Run("BEUT-253: Docs Assets Served At Runtime", func(t *testing.T) {
+		r := chi.NewRouter()
+		routers.RegisterRoutes(r, nil)
+
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		for file, expected := range map[string]int{"VERSION": http.StatusOK, "missing.js": http.StatusNotFound} {
+			res, err := http.Get(srv.URL + "/v1/docs/" + file)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != expected {
+				t.Errorf("Expected status code for %s to be: %d. Got: %d.", file, expected, res.StatusCode)
+			}
+		}
+	})
+}
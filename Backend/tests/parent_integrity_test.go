@@ -0,0 +1,134 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+	"willowsuite-vault/helpers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgconn"
+)
+
+type parentIntegrityTestCase struct {
+	testName       string
+	testUser       string
+	parentRows     *sqlmock.Rows
+	insertError    error
+	expectedHTTP   int
+	expectedDetail string
+}
+
+func validateParentIntegrityResponse(t *testing.T, res *http.Response, expectedHTTP int, expectedDetail string) {
+	if res.StatusCode != expectedHTTP {
+		t.Errorf("Expected status code to be: %d. Got: %d.", expectedHTTP, res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	problem := helpers.Problem{}
+	if err = json.Unmarshal(data, &problem); err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	if problem.Detail != expectedDetail {
+		t.Errorf("Expected detail to be %s. Got: %s", expectedDetail, problem.Detail)
+	}
+}
+
+// TestParentIntegrity runs the unit tests for refusing parents that are missing, deleted or someone else's.
+func TestParentIntegrity(t *testing.T) {
+	body := `{"name":"Test Item","notes":"Notes","category":"item","parentID":"10","parentCategory":"container"}`
+	columns := []string{"user_id", "deleted_at"}
+
+	cases := []parentIntegrityTestCase{
+		{
+			testName:       "BEUT-170: Create Entity Parent Not Found",
+			testUser:       "testuser",
+			parentRows:     sqlmock.NewRows(columns),
+			expectedHTTP:   http.StatusNotFound,
+			expectedDetail: "Parent category of container with id 10 not found.",
+		},
+		{
+			testName:       "BEUT-171: Create Entity Parent Owned By Another User",
+			testUser:       "testuser",
+			parentRows:     sqlmock.NewRows(columns).AddRow("otheruser", nil),
+			expectedHTTP:   http.StatusNotFound,
+			expectedDetail: "Parent category of container with id 10 not found.",
+		},
+		{
+			testName:       "BEUT-172: Create Entity Parent Deleted",
+			testUser:       "testuser",
+			parentRows:     sqlmock.NewRows(columns).AddRow("testuser", time.Now()),
+			expectedHTTP:   http.StatusConflict,
+			expectedDetail: "Parent category of container with id 10 has been deleted.",
+		},
+		{
+			testName:       "BEUT-173: Create Entity Parent Deleted Concurrently",
+			testUser:       "testuser",
+			parentRows:     sqlmock.NewRows(columns).AddRow("testuser", nil),
+			insertError:    &pgconn.PgError{Code: "23001", Message: "parent container 10 of item 0 has been deleted"},
+			expectedHTTP:   http.StatusConflict,
+			expectedDetail: "parent container 10 of item 0 has been deleted",
+		},
+	}
+
+	for _, tc := range cases {
+		client, srv, mockDB, _ := setupCreateEntityTest(t, tc.testUser)
+		t.Run(tc.testName, func(t *testing.T) {
+			mockDB.ExpectBegin()
+			expectParentCheck(mockDB, "container", 10, tc.parentRows)
+			if tc.insertError != nil {
+				mockDB.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "entities"`)).WillReturnError(tc.insertError)
+			}
+			mockDB.ExpectRollback()
+
+			res, err := client.Post(srv.URL+endpoint, "application/json", bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			validateParentIntegrityResponse(t, res, tc.expectedHTTP, tc.expectedDetail)
+
+			if err := mockDB.ExpectationsWereMet(); err != nil {
+				t.Errorf("PostGres expectations were not met: %v", err)
+			}
+		})
+	}
+
+	t.Run("BEUT-174: Delete Entity Children Added Concurrently", func(t *testing.T) {
+		client, srv, mockDB, _ := setupDeleteEntityTest(t, "testuser")
+
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "entities" WHERE user_id = $1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name", "user_id", "attributes"}).AddRow("container", 10, "Box", "testuser", "{}"))
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT count(id) AS childrenCount FROM entities`)).
+			WillReturnRows(sqlmock.NewRows([]string{"childrenCount"}).AddRow(0))
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(regexp.QuoteMeta(`UPDATE "entities" SET "deleted_at"=$1`)).
+			WillReturnError(&pgconn.PgError{Code: "23001", Message: "container 10 still has children"})
+		mockDB.ExpectRollback()
+
+		request, _ := http.NewRequest(http.MethodDelete, srv.URL+"/v1/entity/container/10", nil)
+		request.Header.Set("If-Match", "*")
+		res, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		validateParentIntegrityResponse(t, res, http.StatusConflict, "container 10 still has children")
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+)
+
+type patchEntityTestCase struct {
+	testName     string
+	contentType  string
+	ifMatch      string
+	patch        string
+	updateArgs   []interface{}
+	expectedHTTP int
+	expectedCode string
+}
+
+var patchEntityEndpoint = "/v1/entity/item/10"
+
+func setupPatchEntityTest(t *testing.T, userName string) (*http.Client, *httptest.Server, sqlmock.Sqlmock, redismock.ClientMock) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	postgres, mockDB := mocks.NewMockDB()
+	redis, mockCache := redismock.NewClientMock()
+	handler := controllers.Handler{
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
+		CognitoClient: mocks.NewMockCognitoClient(ctrl),
+		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+	}
+
+	r := chi.NewRouter()
+	r.Use(mocks.MockJWTMiddleware(userName))
+	r.Patch("/v1/entity/{category}/{id}", handler.PatchEntity)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &http.Client{}, srv, mockDB, mockCache
+}
+
+func setupPatchEntityMockExpectations(mockDB sqlmock.Sqlmock, mockCache redismock.ClientMock, testUser string, updateArgs []interface{}) {
+	// Expect the entity to be read before the patch is applied
+	mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "entities" WHERE user_id = $1`)).
+		WillReturnRows(sqlmock.NewRows([]string{"category", "id", "name", "notes", "user_id", "version", "parent_category", "parent_id", "attributes"}).
+			AddRow("item", 10, "Test Item", "Notes", testUser, 3, "container", 10, "{}"))
+
+	if updateArgs == nil {
+		return
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT version, COALESCE(parent_category, '') AS parent_category, COALESCE(parent_id, 0) AS parent_id FROM entities WHERE user_id = $1 AND category = $2 AND id = $3 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(testUser, "item", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "parent_category", "parent_id"}).AddRow(3, "container", 10))
+	expectParentCheck(mockDB, updateArgs[3].(string), updateArgs[4].(int), sqlmock.NewRows([]string{"user_id", "deleted_at"}).AddRow(testUser, nil))
+	mockDB.ExpectExec(regexp.QuoteMeta(`UPDATE "entities" SET "name"=$1,"notes"=$2,"updated_at"=$3,"version"=$4,"parent_category"=$5,"parent_id"=$6,"attributes"=$7 WHERE "entities"."deleted_at" IS NULL AND "category" = $8 AND "id" = $9`)).
+		WithArgs(updateArgs[0], updateArgs[1], sqlmock.AnyArg(), updateArgs[2], updateArgs[3], updateArgs[4], "{}", "item", 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mockDB.ExpectCommit()
+
+	// The entity is under container 10 until the patch moves it
+	eventType := models.EventUpdate
+	if updateArgs[3] != "container" || updateArgs[4] != 10 {
+		eventType = models.EventMove
+	}
+
+	expectEntitiesFlush(mockCache, testUser)
+	expectEntityEvents(mockCache, testUser, eventType)
+	expectWebhooksQueued(mockDB, testUser, eventType)
+}
+
+// expectEntitiesFlush expects FlushEntities to run once for a user, moving their cached entities on a generation.
+func expectEntitiesFlush(mockCache redismock.ClientMock, testUser string) {
+	mockCache.ExpectIncr(`{"User":"` + testUser + `","Function":"EntitiesGeneration"}`).SetVal(1)
+}
+
+func validatePatchEntityResponse(t *testing.T, res *http.Response, tc patchEntityTestCase) {
+	if res.StatusCode != tc.expectedHTTP {
+		t.Errorf("Expected status code to be: %d. Got: %d.", tc.expectedHTTP, res.StatusCode)
+	}
+
+	if tc.expectedHTTP == http.StatusOK {
+		if etag := res.Header.Get("ETag"); etag != `"4"` {
+			t.Errorf(`Expected ETag to be "4". Got: %s`, etag)
+		}
+	}
+
+	if tc.expectedCode == "" {
+		return
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	contents := validationResponse{}
+	if err = json.Unmarshal(data, &contents); err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	if len(contents.Data) == 0 || contents.Data[0].Code != tc.expectedCode {
+		t.Errorf("Expected a %s field error. Got: %+v", tc.expectedCode, contents.Data)
+	}
+}
+
+// TestPatchEntity runs the unit tests for partial updates of entities.
+func TestPatchEntity(t *testing.T) {
+	cases := []patchEntityTestCase{
+		{
+			testName:     "BEUT-180: Patch Entity Merge Patch",
+			contentType:  helpers.MergePatchType,
+			ifMatch:      `"3"`,
+			patch:        `{"name":"Renamed Item","notes":null}`,
+			updateArgs:   []interface{}{"Renamed Item", nil, 4, "container", 10},
+			expectedHTTP: http.StatusOK,
+		},
+		{
+			testName:     "BEUT-181: Patch Entity JSON Patch",
+			contentType:  helpers.JSONPatchType,
+			ifMatch:      "*",
+			patch:        `[{"op":"test","path":"/name","value":"Test Item"},{"op":"replace","path":"/parentID","value":"12"},{"op":"replace","path":"/parentCategory","value":"shelf"}]`,
+			updateArgs:   []interface{}{"Test Item", "Notes", 4, "shelf", 12},
+			expectedHTTP: http.StatusOK,
+		},
+		{
+			testName:     "BEUT-182: Patch Entity Failed Test Operation",
+			contentType:  helpers.JSONPatchType,
+			ifMatch:      "*",
+			patch:        `[{"op":"test","path":"/name","value":"Other Item"},{"op":"remove","path":"/notes"}]`,
+			expectedHTTP: http.StatusConflict,
+		},
+		{
+			testName:     "BEUT-183: Patch Entity Category Is Read Only",
+			contentType:  helpers.MergePatchType,
+			ifMatch:      "*",
+			patch:        `{"category":"container"}`,
+			expectedHTTP: http.StatusUnprocessableEntity,
+			expectedCode: "read_only",
+		},
+		{
+			testName:     "BEUT-184: Patch Entity Unknown Field",
+			contentType:  helpers.JSONPatchType,
+			ifMatch:      "*",
+			patch:        `[{"op":"add","path":"/colour","value":"red"}]`,
+			expectedHTTP: http.StatusUnprocessableEntity,
+			expectedCode: "unknown_field",
+		},
+		{
+			testName:     "BEUT-185: Patch Entity Clearing A Required Field",
+			contentType:  helpers.MergePatchType,
+			ifMatch:      "*",
+			patch:        `{"name":null}`,
+			expectedHTTP: http.StatusUnprocessableEntity,
+			expectedCode: "required",
+		},
+		{
+			testName:     "BEUT-186: Patch Entity Stale Version",
+			contentType:  helpers.MergePatchType,
+			ifMatch:      `"2"`,
+			patch:        `{"name":"Renamed Item"}`,
+			expectedHTTP: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		client, srv, mockDB, mockCache := setupPatchEntityTest(t, "testuser")
+		t.Run(tc.testName, func(t *testing.T) {
+			setupPatchEntityMockExpectations(mockDB, mockCache, "testuser", tc.updateArgs)
+
+			req, _ := http.NewRequest(http.MethodPatch, srv.URL+patchEntityEndpoint, bytes.NewBufferString(tc.patch))
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("If-Match", tc.ifMatch)
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			validatePatchEntityResponse(t, res, tc)
+
+			if err := mockDB.ExpectationsWereMet(); err != nil {
+				t.Errorf("PostGres expectations were not met: %v", err)
+			}
+
+			if err := mockCache.ExpectationsWereMet(); err != nil {
+				t.Errorf("Redis expectations were not met: %v", err)
+			}
+		})
+	}
+
+	t.Run("BEUT-187: Patch Entity Unsupported Media Type", func(t *testing.T) {
+		client, srv, mockDB, _ := setupPatchEntityTest(t, "testuser")
+		setupPatchEntityMockExpectations(mockDB, nil, "testuser", nil)
+
+		req, _ := http.NewRequest(http.MethodPatch, srv.URL+patchEntityEndpoint, bytes.NewBufferString(`name=Renamed`))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("If-Match", "*")
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusUnsupportedMediaType, res.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,278 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"willowsuite-vault/config"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/routers/middlewares"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redismock/v9"
+	"github.com/spf13/viper"
+)
+
+// setupRateLimitTest serves a route behind a rate limit of 3 requests a minute, counted by IP, user or email, and
+// keeps the bodies it really got. The test server is a trusted proxy.
+func setupRateLimitTest(t *testing.T, by middlewares.RateLimitBy) (*httptest.Server, redismock.ClientMock, *[]string) {
+	srv, mockCache, bodies, _ := setupRateLimitTestWith(t, by, func(c cache.Cache) cache.Cache { return c })
+	return srv, mockCache, bodies
+}
+
+// setupRateLimitTestWith is setupRateLimitTest with the repository's cache made by wrap, and returns that cache.
+func setupRateLimitTestWith(t *testing.T, by middlewares.RateLimitBy, wrap func(cache.Cache) cache.Cache) (*httptest.Server, redismock.ClientMock, *[]string, cache.Cache) {
+	viper.Set("RATE_LIMIT_TEST_REQUESTS", 3)
+	viper.Set("RATE_LIMIT_TEST_WINDOW", "1m")
+	t.Cleanup(func() {
+		viper.Set("RATE_LIMIT_TEST_REQUESTS", nil)
+		viper.Set("RATE_LIMIT_TEST_WINDOW", nil)
+	})
+
+	redis, mockCache := redismock.NewClientMock()
+	repoCache := wrap(cache.NewRedisCache(redis))
+	handler := controllers.Handler{
+		Repository:     &repository.Repository{Cache: repoCache, Redis: redis},
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+	}
+
+	bodies := []string{}
+	r := chi.NewRouter()
+	if by == middlewares.ByUser {
+		r.Use(mocks.MockJWTMiddleware("testuser"))
+	}
+	r.Use(middlewares.RateLimit(handler, "test", by))
+	r.Post("/v1/token", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return srv, mockCache, &bodies, repoCache
+}
+
+// expectRateLimit expects the sliding window of key to be checked against a limit of 3 a minute, and answers it
+// with whether the request was allowed, how many are in the window and when the oldest of them was made.
+func expectRateLimit(mockCache redismock.ClientMock, key string, allowed int64, count int64, oldest time.Time) *redismock.ExpectedCmd {
+	expected := mockCache.CustomMatch(func(_, actual []interface{}) error {
+		if len(actual) != 8 || actual[0] != "evalsha" || actual[3] != key || fmt.Sprint(actual[5:7]) != "[60000 3]" {
+			return fmt.Errorf("expected the rate limit of %s to be checked. Got: %v", key, actual)
+		}
+
+		return nil
+	}).ExpectEvalSha("", []string{key}, "now", "window", "limit", "member")
+	expected.SetVal([]interface{}{allowed, count, strconv.FormatInt(oldest.UnixMilli(), 10)})
+
+	return expected
+}
+
+// sendRateLimited sends body through a proxy that says the client is 203.0.113.7, after the client claimed to be
+// 198.51.100.1.
+func sendRateLimited(t *testing.T, url string, body string) *http.Response {
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/token", strings.NewReader(body))
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	return res
+}
+
+// TestRateLimit runs the unit tests for limiting how often a client may call a group of routes.
+func TestRateLimit(t *testing.T) {
+	t.Run("BEUT-228: Requests Within The Limit Are Counted By IP", func(t *testing.T) {
+		srv, mockCache, bodies := setupRateLimitTest(t, middlewares.ByIP)
+
+		key := `{"CacheKey":{"User":"","Function":"RateLimit"},"Group":"test","IP":"203.0.113.7","Email":""}`
+		expectRateLimit(mockCache, key, 1, 1, time.Now())
+
+		res := sendRateLimited(t, srv.URL, "")
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || len(*bodies) != 1 {
+			t.Errorf("Expected the request to go through. Got: %d after %d calls", res.StatusCode, len(*bodies))
+		}
+
+		headers := map[string]string{"RateLimit-Limit": "3", "RateLimit-Remaining": "2", "RateLimit-Reset": "60", "RateLimit-Policy": "3;w=60"}
+		for name, expected := range headers {
+			if res.Header.Get(name) != expected {
+				t.Errorf("Expected %s to be %q. Got: %q", name, expected, res.Header.Get(name))
+			}
+		}
+
+		if res.Header.Get("Retry-After") != "" {
+			t.Errorf("Expected no Retry-After. Got: %q", res.Header.Get("Retry-After"))
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-229: Requests Over The Limit Are Refused Per User", func(t *testing.T) {
+		srv, mockCache, bodies := setupRateLimitTest(t, middlewares.ByUser)
+
+		key := `{"CacheKey":{"User":"testuser","Function":"RateLimit"},"Group":"test","IP":"","Email":""}`
+		expectRateLimit(mockCache, key, 0, 3, time.Now().Add(-30*time.Second))
+
+		res := sendRateLimited(t, srv.URL, "")
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusTooManyRequests, res.StatusCode)
+		}
+
+		if len(*bodies) != 0 {
+			t.Errorf("Expected the handler not to run. Got: %d", len(*bodies))
+		}
+
+		if res.Header.Get("Retry-After") != "30" || res.Header.Get("RateLimit-Reset") != "30" {
+			t.Errorf("Expected to be told to retry in 30 seconds. Got: Retry-After %q, RateLimit-Reset %q", res.Header.Get("Retry-After"), res.Header.Get("RateLimit-Reset"))
+		}
+
+		if res.Header.Get("RateLimit-Remaining") != "0" {
+			t.Errorf("Expected no requests to remain. Got: %q", res.Header.Get("RateLimit-Remaining"))
+		}
+
+		if res.Header.Get("Content-Type") != "application/problem+json" {
+			t.Errorf("Expected a problem response. Got: %q", res.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("BEUT-230: Requests Go Through When Redis Is Down", func(t *testing.T) {
+		srv, mockCache, bodies := setupRateLimitTest(t, middlewares.ByIP)
+
+		key := `{"CacheKey":{"User":"","Function":"RateLimit"},"Group":"test","IP":"203.0.113.7","Email":""}`
+		expectRateLimit(mockCache, key, 0, 0, time.Now()).SetErr(errors.New("connection refused"))
+
+		res := sendRateLimited(t, srv.URL, "")
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || len(*bodies) != 1 {
+			t.Errorf("Expected the request to go through. Got: %d after %d calls", res.StatusCode, len(*bodies))
+		}
+
+		if res.Header.Get("RateLimit-Limit") != "" {
+			t.Errorf("Expected no RateLimit headers. Got: %q", res.Header.Get("RateLimit-Limit"))
+		}
+	})
+
+	t.Run("BEUT-233: Forwarded Headers Are Only Believed From Trusted Proxies", func(t *testing.T) {
+		viper.Set("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1")
+		t.Cleanup(func() { viper.Set("TRUSTED_PROXIES", nil) })
+
+		proxies, err := config.TrustedProxies()
+		if err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		cases := []struct {
+			remote    string
+			forwarded string
+			realIP    string
+			expected  string
+		}{
+			{remote: "203.0.113.7:4000", forwarded: "198.51.100.1", realIP: "198.51.100.2", expected: "203.0.113.7"},
+			{remote: "10.0.0.5:4000", forwarded: "198.51.100.1, 203.0.113.7", expected: "203.0.113.7"},
+			{remote: "10.0.0.5:4000", forwarded: "198.51.100.1, 203.0.113.7, 192.0.2.1", expected: "203.0.113.7"},
+			{remote: "10.0.0.5:4000", forwarded: "203.0.113.7, not-an-ip", expected: "10.0.0.5"},
+			{remote: "10.0.0.5:4000", realIP: "203.0.113.7", expected: "203.0.113.7"},
+			{remote: "10.0.0.5:4000", expected: "10.0.0.5"},
+		}
+
+		for _, tc := range cases {
+			req := httptest.NewRequest(http.MethodPost, "/v1/token", nil)
+			req.RemoteAddr = tc.remote
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			if ip := helpers.ClientIP(req, proxies); ip != tc.expected {
+				t.Errorf("Expected %s from %s forwarding %q. Got: %s", tc.expected, tc.remote, tc.forwarded, ip)
+			}
+		}
+	})
+
+	t.Run("BEUT-234: Sign In Is Limited Per Email", func(t *testing.T) {
+		srv, mockCache, bodies := setupRateLimitTest(t, middlewares.ByEmail)
+
+		hash := sha256.Sum256([]byte("victim@test.com"))
+		key := `{"CacheKey":{"User":"","Function":"RateLimit"},"Group":"test","IP":"","Email":"` + hex.EncodeToString(hash[:]) + `"}`
+		expectRateLimit(mockCache, key, 1, 1, time.Now())
+
+		body := `{"userEmail":" Victim@test.com","password":"guess"}`
+		res := sendRateLimited(t, srv.URL, body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || len(*bodies) != 1 || (*bodies)[0] != body {
+			t.Errorf("Expected the handler to get the whole body. Got: %d %q", res.StatusCode, *bodies)
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+	t.Run("BEUT-247: Requests Go Straight Through While The Breaker Is Open", func(t *testing.T) {
+		srv, mockCache, bodies, repoCache := setupRateLimitTestWith(t, middlewares.ByIP, func(c cache.Cache) cache.Cache {
+			return cache.NewBreaker(c)
+		})
+		breaker := repoCache.(*cache.Breaker)
+
+		key := `{"CacheKey":{"User":"","Function":"RateLimit"},"Group":"test","IP":"203.0.113.7","Email":""}`
+		for i := 0; i < breaker.Threshold; i++ {
+			expectRateLimit(mockCache, key, 0, 0, time.Now()).SetErr(errors.New("connection refused"))
+		}
+
+		// The limiter fails open while Redis fails, and stops asking it once the breaker opens.
+		for i := 0; i < breaker.Threshold+3; i++ {
+			res := sendRateLimited(t, srv.URL, "")
+			res.Body.Close()
+
+			if res.StatusCode != http.StatusOK || res.Header.Get("RateLimit-Limit") != "" {
+				t.Errorf("Expected the request to go through without rate limit headers. Got: %d %v", res.StatusCode, res.Header)
+			}
+		}
+
+		if len(*bodies) != breaker.Threshold+3 {
+			t.Errorf("Expected every request to reach the handler. Got: %d", len(*bodies))
+		}
+
+		if !breaker.Open() {
+			t.Errorf("Expected the breaker to be open")
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-254: Trusted Proxies That Don't Parse Are An Error", func(t *testing.T) {
+		viper.Set("TRUSTED_PROXIES", "10.0.0.0/8, 10.0.0.0/33")
+		t.Cleanup(func() { viper.Set("TRUSTED_PROXIES", nil) })
+
+		if _, err := config.TrustedProxies(); err == nil {
+			t.Errorf("Expected an error for 10.0.0.0/33. Got: nil")
+		}
+	})
+}
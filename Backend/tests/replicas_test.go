@@ -0,0 +1,189 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"willowsuite-vault/config"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/infra/database"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/routers/middlewares"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redismock/v9"
+	"gorm.io/gorm"
+)
+
+const replicaLagQuery = `pg_last_xact_replay_timestamp`
+
+var wroteRecentlyKey = `{"User":"testuser","Function":"WroteRecently"}`
+
+// setupReplicaPolicyTest returns a policy over a mocked primary and replica, and the mock of the replica.
+func setupReplicaPolicyTest(t *testing.T) (*database.ReplicaPolicy, gorm.ConnPool, gorm.ConnPool, sqlmock.Sqlmock) {
+	primary, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to mock the primary: %v", err)
+	}
+	t.Cleanup(func() { primary.Close() })
+
+	replica, mockReplica, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to mock the replica: %v", err)
+	}
+	t.Cleanup(func() { replica.Close() })
+
+	policy := database.NewReplicaPolicy(primary, []gorm.ConnPool{replica}, []string{"replica"})
+	return policy, primary, replica, mockReplica
+}
+
+// TestReplicas runs the unit tests for sending reads to replicas that are keeping up, and to the primary after writes.
+func TestReplicas(t *testing.T) {
+	t.Run("BEUT-214: Lagging Replicas Are Dropped Until They Catch Up", func(t *testing.T) {
+		policy, primary, replica, mockReplica := setupReplicaPolicyTest(t)
+		pools := []gorm.ConnPool{replica, primary}
+
+		if policy.Resolve(pools) != replica {
+			t.Errorf("Expected reads to go to the replica before it is checked")
+		}
+
+		mockReplica.ExpectQuery(replicaLagQuery).WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(10.5))
+		policy.Check(context.Background(), 2*time.Second)
+
+		health := policy.Health()
+		if health[0].Healthy || health[0].Lag != 10500*time.Millisecond {
+			t.Errorf("Expected the replica to be 10.5s behind and dropped. Got: %+v", health[0])
+		}
+
+		if policy.Resolve(pools) != primary {
+			t.Errorf("Expected reads to go to the primary while the replica lags")
+		}
+
+		mockReplica.ExpectQuery(replicaLagQuery).WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0.2))
+		policy.Check(context.Background(), 2*time.Second)
+
+		if !policy.Health()[0].Healthy || policy.Resolve(pools) != replica {
+			t.Errorf("Expected reads to go back to the replica once it caught up. Got: %+v", policy.Health()[0])
+		}
+
+		if err := mockReplica.ExpectationsWereMet(); err != nil {
+			t.Errorf("Replica expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-215: Unreachable Replicas Are Dropped", func(t *testing.T) {
+		policy, primary, replica, mockReplica := setupReplicaPolicyTest(t)
+
+		mockReplica.ExpectQuery(replicaLagQuery).WillReturnError(errors.New("connection refused"))
+		policy.Check(context.Background(), 2*time.Second)
+
+		health := policy.Health()
+		if health[0].Healthy || health[0].Error != "connection refused" {
+			t.Errorf("Expected the replica to be dropped with its error. Got: %+v", health[0])
+		}
+
+		if policy.Resolve([]gorm.ConnPool{replica, primary}) != primary {
+			t.Errorf("Expected reads to go to the primary")
+		}
+	})
+
+	t.Run("BEUT-216: Reads Go To The Primary After A Write", func(t *testing.T) {
+		redis, mockCache := redismock.NewClientMock()
+		handler := controllers.Handler{
+			Repository: &repository.Repository{Cache: cache.NewRedisCache(redis), Redis: redis},
+		}
+
+		var primaryReads []bool
+		r := chi.NewRouter()
+		r.Use(mocks.MockJWTMiddleware("testuser"))
+		r.Use(middlewares.ReadYourWrites(handler))
+		r.HandleFunc("/v1/entity", func(w http.ResponseWriter, request *http.Request) {
+			primaryReads = append(primaryReads, repository.ReadsFromPrimary(request.Context()))
+		})
+
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		mockCache.ExpectGet(wroteRecentlyKey).RedisNil()
+		mockCache.ExpectSetNX(wroteRecentlyKey, 1, config.ReadPrimaryWindow()).SetVal(true)
+		mockCache.ExpectGet(wroteRecentlyKey).SetVal("1")
+		mockCache.ExpectGet(wroteRecentlyKey).SetErr(errors.New("connection refused"))
+
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodGet, http.MethodGet} {
+			req, _ := http.NewRequest(method, srv.URL+"/v1/entity", nil)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			res.Body.Close()
+		}
+
+		// Before the write, the write itself, after it and when Redis can't tell
+		expected := []bool{false, true, true, false}
+		for i := range expected {
+			if i >= len(primaryReads) || primaryReads[i] != expected[i] {
+				t.Fatalf("Expected reads from the primary to be %v. Got: %v", expected, primaryReads)
+			}
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+	t.Run("BEUT-242: Writes Are Marked Before They Run And Failed Ones Take It Back", func(t *testing.T) {
+		redis, mockCache := redismock.NewClientMock()
+		handler := controllers.Handler{
+			Repository: &repository.Repository{Cache: cache.NewRedisCache(redis), Redis: redis},
+		}
+
+		status, pendingAfter := http.StatusOK, false
+		r := chi.NewRouter()
+		r.Use(mocks.MockJWTMiddleware("testuser"))
+		r.Use(middlewares.ReadYourWrites(handler))
+		r.HandleFunc("/v1/entity", func(w http.ResponseWriter, _ *http.Request) {
+			// The mark has to be in place before the response can reach the client.
+			if err := mockCache.ExpectationsWereMet(); err != nil && !pendingAfter {
+				t.Errorf("Expected the write to be marked before it ran: %v", err)
+			}
+			w.WriteHeader(status)
+		})
+
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		send := func() {
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/entity", nil)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			res.Body.Close()
+		}
+
+		mockCache.ExpectSetNX(wroteRecentlyKey, 1, config.ReadPrimaryWindow()).SetVal(true)
+		send()
+
+		// A failed write extends the mark of the one before it and leaves it in place.
+		status = http.StatusConflict
+		mockCache.ExpectSetNX(wroteRecentlyKey, 1, config.ReadPrimaryWindow()).SetVal(false)
+		mockCache.ExpectExpire(wroteRecentlyKey, config.ReadPrimaryWindow()).SetVal(true)
+		send()
+
+		// A failed write with no write before it takes its mark back.
+		pendingAfter = true
+		mockCache.ExpectSetNX(wroteRecentlyKey, 1, config.ReadPrimaryWindow()).SetVal(true)
+		mockCache.ExpectDel(wroteRecentlyKey).SetVal(1)
+		send()
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"willowsuite-vault/config"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+)
+
+type sessionsResponse struct {
+	Message string           `json:"message"`
+	Data    []models.Session `json:"data"`
+}
+
+type sessionsTestCase struct {
+	testName        string
+	method          string
+	testUser        string
+	accessToken     string
+	sessions        []models.Session
+	expectedHTTP    int
+	expectedMessage string
+}
+
+var sessionsEndpoint = "/v1/sessions"
+
+func setupSessionsTest(t *testing.T, userName string) (*http.Client, *httptest.Server, *mocks.MockCognitoClient, redismock.ClientMock) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	postgres, _ := mocks.NewMockDB()
+	redis, mockCache := redismock.NewClientMock()
+	cognito := mocks.NewMockCognitoClient(ctrl)
+	handler := controllers.Handler{
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
+		CognitoClient: cognito,
+		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+	}
+
+	r := chi.NewRouter()
+	r.Use(mocks.MockJWTMiddlewareWithClaims(jwt.MapClaims{
+		"username":   userName,
+		"origin_jti": "current-session",
+	}))
+	r.Get(sessionsEndpoint, handler.GetSessions)
+	r.Delete(sessionsEndpoint, handler.LogOutAllDevices)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &http.Client{}, srv, cognito, mockCache
+}
+
+func setupSessionsMockExpectations(cognito *mocks.MockCognitoClient, mockCache redismock.ClientMock, tc sessionsTestCase) {
+	sessionsKey := `{"User":"` + tc.testUser + `","Function":"Sessions"}`
+
+	if tc.method == http.MethodGet {
+		values := map[string]string{}
+		for _, session := range tc.sessions {
+			byteData, _ := json.Marshal(session)
+			values[session.ID] = string(byteData)
+		}
+
+		mockCache.ExpectHGetAll(sessionsKey).SetVal(values)
+		return
+	}
+
+	if tc.accessToken == "" {
+		return
+	}
+
+	cognito.EXPECT().GlobalSignOut(gomock.All(), &cognitoidentityprovider.GlobalSignOutInput{
+		AccessToken: aws.String(tc.accessToken),
+	}).Return(&cognitoidentityprovider.GlobalSignOutOutput{}, nil)
+
+	mockCache.Regexp().ExpectSet(`{"User":"`+tc.testUser+`","Function":"RevokedBefore"}`, `[0-9]+`, config.AccessTokenMaxAge()).SetVal("OK")
+	mockCache.ExpectDel(sessionsKey).SetVal(1)
+}
+
+func validateSessionsResponse(t *testing.T, res *http.Response, mockCache redismock.ClientMock, tc sessionsTestCase) {
+	if res.StatusCode != tc.expectedHTTP {
+		t.Errorf("Expected status code to be: %d. Got: %d.", tc.expectedHTTP, res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	contents := map[string]interface{}{}
+	if err = json.Unmarshal(data, &contents); err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	if contents["message"] != tc.expectedMessage {
+		t.Errorf("Expected message to be %s. Got: %s", tc.expectedMessage, contents["message"])
+	}
+
+	if tc.method == http.MethodGet {
+		sessions := sessionsResponse{}
+		if err = json.Unmarshal(data, &sessions); err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		if len(sessions.Data) != len(tc.sessions) {
+			t.Fatalf("Expected %d sessions. Got: %d", len(tc.sessions), len(sessions.Data))
+		}
+
+		for i := 1; i < len(sessions.Data); i++ {
+			if sessions.Data[i].LastSeenAt.After(sessions.Data[i-1].LastSeenAt) {
+				t.Errorf("Expected sessions to be ordered by most recently used.")
+			}
+		}
+
+		for _, session := range sessions.Data {
+			if session.Current != (session.ID == "current-session") {
+				t.Errorf("Expected only the current session to be flagged. Got: %s flagged %v", session.ID, session.Current)
+			}
+		}
+	}
+
+	if err := mockCache.ExpectationsWereMet(); err != nil {
+		t.Errorf("Redis expectations were not met: %v", err)
+	}
+}
+
+// TestSessions runs the unit tests for the GetSessions and LogOutAllDevices functions.
+func TestSessions(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	cases := []sessionsTestCase{
+		{
+			testName: "BEUT-135: Get Sessions",
+			method:   http.MethodGet,
+			testUser: "testuser",
+			sessions: []models.Session{
+				{ID: "other-session", UserAgent: "Firefox", IPAddress: "10.0.0.2", CreatedAt: now.Add(-time.Hour), LastSeenAt: now.Add(-time.Minute)},
+				{ID: "current-session", UserAgent: "curl", IPAddress: "10.0.0.1", CreatedAt: now.Add(-time.Hour), LastSeenAt: now},
+			},
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+		},
+		{
+			testName:        "BEUT-136: Get Sessions None",
+			method:          http.MethodGet,
+			testUser:        "testuser2",
+			sessions:        []models.Session{},
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+		},
+		{
+			testName:        "BEUT-137: Log Out All Devices",
+			method:          http.MethodDelete,
+			testUser:        "testuser",
+			accessToken:     "access-token",
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+		},
+		{
+			testName:        "BEUT-138: Log Out All Devices Missing Access Token",
+			method:          http.MethodDelete,
+			testUser:        "testuser",
+			expectedHTTP:    http.StatusBadRequest,
+			expectedMessage: "data validation failed",
+		},
+	}
+
+	for _, tc := range cases {
+		client, srv, cognito, mockCache := setupSessionsTest(t, tc.testUser)
+		t.Run(tc.testName, func(t *testing.T) {
+			setupSessionsMockExpectations(cognito, mockCache, tc)
+
+			url := fmt.Sprintf("%s%s", srv.URL, sessionsEndpoint)
+
+			req, err := http.NewRequest(tc.method, url, nil)
+			if err != nil {
+				t.Fatalf("Failed to build request: %v", err)
+			}
+
+			if tc.accessToken != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.accessToken)
+			}
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			validateSessionsResponse(t, res, mockCache, tc)
+		})
+	}
+}
+
+// TestTouchSession runs the unit tests for recording that a session was used.
+func TestTouchSession(t *testing.T) {
+	t.Run("BEUT-231: Session Is Written At Most Once A Minute", func(t *testing.T) {
+		redis, mockCache := redismock.NewClientMock()
+		repo := repository.Repository{Cache: cache.NewRedisCache(redis), Redis: redis}
+		sessionsKey := `{"User":"touchuser","Function":"Sessions"}`
+
+		mockCache.ExpectHGet(sessionsKey, "touched-session").RedisNil()
+		mockCache.CustomMatch(func(_, actual []interface{}) error {
+			if actual[0] != "hset" || actual[1] != sessionsKey || actual[2] != "touched-session" {
+				return fmt.Errorf("expected the session to be saved. Got: %v", actual)
+			}
+			return nil
+		}).ExpectHSet(sessionsKey, "touched-session", "").SetVal(1)
+		mockCache.ExpectExpire(sessionsKey, config.SessionMaxAge()).SetVal(true)
+
+		for i := 0; i < 3; i++ {
+			session := models.Session{ID: "touched-session", UserAgent: "curl", LastSeenAt: time.Now()}
+			if err := repo.TouchSession(context.Background(), "touchuser", session); err != nil {
+				t.Fatalf("Expected error to be nil. Got: %v", err)
+			}
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Expected one write. Got: %v", err)
+		}
+	})
+
+	t.Run("BEUT-246: Sessions Are Not Touched While Redis Is Down", func(t *testing.T) {
+		redis, mockCache := redismock.NewClientMock()
+		breaker := cache.NewBreaker(cache.NewRedisCache(redis))
+		repo := repository.Repository{Cache: breaker, Redis: redis}
+		sessionsKey := `{"User":"outageuser","Function":"Sessions"}`
+
+		for i := 0; i < breaker.Threshold; i++ {
+			mockCache.ExpectHGet(sessionsKey, "outage-session").SetErr(errors.New("connection refused"))
+		}
+
+		session := models.Session{ID: "outage-session", UserAgent: "curl", LastSeenAt: time.Now()}
+		for i := 0; i < breaker.Threshold; i++ {
+			if err := repo.TouchSession(context.Background(), "outageuser", session); err == nil {
+				t.Errorf("Expected the failed touch to report the outage")
+			}
+		}
+
+		// Once the breaker is open Redis isn't asked at all, and the request carries on.
+		for i := 0; i < 3; i++ {
+			if err := repo.TouchSession(context.Background(), "outageuser", session); err != nil {
+				t.Errorf("Expected the touch to be skipped. Got: %v", err)
+			}
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+}
+
+// TestIsTokenRevoked runs the unit tests for checking revoked tokens while Redis is down.
+func TestIsTokenRevoked(t *testing.T) {
+	t.Run("BEUT-241: Revocation Check Falls Back To This Instance While Redis Is Down", func(t *testing.T) {
+		client, mockCache := redismock.NewClientMock()
+		breaker := cache.NewBreaker(cache.NewRedisCache(client))
+		repo := repository.Repository{Cache: breaker, Redis: client}
+		ctx := context.Background()
+
+		revokedBefore := `{"User":"downuser","Function":"RevokedBefore"}`
+		revokedToken := `{"CacheKey":{"User":"downuser","Function":"RevokedToken"},"TokenID":"session-1"}`
+		outage := errors.New("connection refused")
+
+		mockCache.ExpectSet(revokedToken, 1, config.AccessTokenMaxAge()).SetErr(outage)
+		if err := repo.RevokeSession(ctx, "downuser", "session-1"); err == nil {
+			t.Errorf("Expected revoking to report the outage")
+		}
+
+		otherToken := `{"CacheKey":{"User":"downuser","Function":"RevokedToken"},"TokenID":"session-2"}`
+		for i := 0; i < breaker.Threshold; i++ {
+			if i%2 == 0 {
+				mockCache.ExpectMGet(revokedBefore, revokedToken).SetErr(outage)
+			} else {
+				mockCache.ExpectMGet(revokedBefore, otherToken).SetErr(outage)
+			}
+		}
+
+		for i := 0; i < breaker.Threshold+2; i++ {
+			revoked, err := repo.IsTokenRevoked(ctx, "downuser", []string{"session-1"}, time.Now().Unix())
+			if err != nil || !revoked {
+				t.Errorf("Expected the token revoked through this instance to be refused. Got: %v %v", revoked, err)
+			}
+
+			revoked, err = repo.IsTokenRevoked(ctx, "downuser", []string{"session-2"}, time.Now().Unix())
+			if err != nil || revoked {
+				t.Errorf("Expected other tokens to be accepted. Got: %v %v", revoked, err)
+			}
+		}
+
+		// Once the breaker is open Redis isn't asked at all.
+		if !breaker.Open() {
+			t.Errorf("Expected the breaker to be open")
+		}
+
+		if err := mockCache.ExpectationsWereMet(); err != nil {
+			t.Errorf("Redis expectations were not met: %v", err)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+	"willowsuite-vault/infra/tracing"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// recordSpans sends the spans of the test to memory instead of an exporter, and returns them once they have ended.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return exporter
+}
+
+// findSpan returns the first recorded span called name.
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+
+	return nil
+}
+
+// TestTracing runs the unit tests for the OpenTelemetry instrumentation.
+func TestTracing(t *testing.T) {
+	t.Run("BEUT-220: Requests Continue The Caller's Trace", func(t *testing.T) {
+		exporter := recordSpans(t)
+
+		r := chi.NewRouter()
+		r.Use(tracing.Middleware)
+		r.Get("/beut-220/{category}/{id}", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/beut-220/item/7", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		span := findSpan(exporter.GetSpans(), "GET /beut-220/{category}/{id}")
+		if span == nil {
+			t.Fatalf("Expected a span named after the route pattern. Got: %v", exporter.GetSpans())
+		}
+
+		if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Expected the span to join the caller's trace. Got: %s", span.SpanContext.TraceID())
+		}
+
+		if span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+			t.Errorf("Expected the caller's span to be the parent. Got: %s", span.Parent.SpanID())
+		}
+
+		if span.Status.Code != codes.Error {
+			t.Errorf("Expected a 500 to mark the span failed. Got: %v", span.Status)
+		}
+	})
+
+	t.Run("BEUT-221: Database Queries Are Spans Of The Request", func(t *testing.T) {
+		exporter := recordSpans(t)
+
+		postgres, mockDB := mocks.NewMockDB()
+		if err := tracing.InstrumentDB(postgres); err != nil {
+			t.Fatalf("Expected error to be nil. Got: %v", err)
+		}
+
+		ctx, parent := tracing.Tracer().Start(context.Background(), "request")
+		mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT name FROM entities WHERE id = $1`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Kitchen"))
+		var name string
+		postgres.WithContext(ctx).Raw(`SELECT name FROM entities WHERE id = ?`, 7).Scan(&name)
+		parent.End()
+
+		span := findSpan(exporter.GetSpans(), "gorm.row")
+		if span == nil {
+			t.Fatalf("Expected the query to have a span. Got: %v", exporter.GetSpans())
+		}
+
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected the query span to be a child of the request span")
+		}
+
+		query := ""
+		for _, attr := range span.Attributes {
+			if attr.Key == semconv.DBQueryTextKey {
+				query = attr.Value.AsString()
+			}
+		}
+		if query != `SELECT name FROM entities WHERE id = $1` {
+			t.Errorf("Expected the SQL without its values. Got: %q", query)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-222: Redis Commands Are Spans", func(t *testing.T) {
+		exporter := recordSpans(t)
+
+		// Nothing listens on port 1, so the command fails without a Redis server.
+		client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+		defer client.Close()
+		tracing.InstrumentRedis(client)
+
+		ctx, parent := tracing.Tracer().Start(context.Background(), "request")
+		client.Get(ctx, "key")
+		parent.End()
+
+		span := findSpan(exporter.GetSpans(), "redis.get")
+		if span == nil {
+			t.Fatalf("Expected the command to have a span. Got: %v", exporter.GetSpans())
+		}
+
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected the command span to be a child of the request span")
+		}
+
+		if span.Status.Code != codes.Error {
+			t.Errorf("Expected the failed command to mark the span failed. Got: %v", span.Status)
+		}
+	})
+}
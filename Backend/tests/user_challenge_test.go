@@ -0,0 +1,222 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"willowsuite-vault/config"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+)
+
+type userChallengeResponse struct {
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+type userChallengeTestCase struct {
+	testName        string
+	validData       bool
+	incorrectCode   bool
+	expectedHTTP    int
+	expectedMessage string
+	expectedBody    string
+	UserEmail       string `json:"userEmail"`
+	ChallengeName   string `json:"challengeName"`
+	Session         string `json:"session"`
+	Code            string `json:"code"`
+	NewPassword     string `json:"newPassword"`
+}
+
+var userChallengeEndpoint = "/v1/token/challenge"
+
+func setupUserChallengeTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.MockCognitoClient) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	postgres, _ := mocks.NewMockDB()
+	redis, _ := redismock.NewClientMock()
+	cognito := mocks.NewMockCognitoClient(ctrl)
+	handler := controllers.Handler{
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
+		CognitoClient: cognito,
+		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+	}
+
+	r := chi.NewRouter()
+	r.Post(userChallengeEndpoint, handler.RespondToChallenge)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &http.Client{}, srv, cognito
+}
+
+func setupUserChallengeMockExpectations(cognito *mocks.MockCognitoClient, tc userChallengeTestCase) {
+	challengeResponses := map[string]string{
+		"USERNAME":    tc.UserEmail,
+		"SECRET_HASH": config.CognitoSecretHash(tc.UserEmail),
+	}
+
+	switch types.ChallengeNameType(tc.ChallengeName) {
+	case types.ChallengeNameTypeSoftwareTokenMfa:
+		challengeResponses["SOFTWARE_TOKEN_MFA_CODE"] = tc.Code
+	case types.ChallengeNameTypeNewPasswordRequired:
+		challengeResponses["NEW_PASSWORD"] = tc.NewPassword
+	}
+
+	call := cognito.EXPECT().RespondToAuthChallenge(gomock.All(), &cognitoidentityprovider.RespondToAuthChallengeInput{
+		ChallengeName:      types.ChallengeNameType(tc.ChallengeName),
+		ClientId:           aws.String(config.CognitoClientID()),
+		Session:            aws.String(tc.Session),
+		ChallengeResponses: challengeResponses,
+	})
+
+	if tc.incorrectCode {
+		call.Return(nil, &types.CodeMismatchException{
+			Message: aws.String("Invalid code received for user"),
+		})
+	} else {
+		call.Return(&cognitoidentityprovider.RespondToAuthChallengeOutput{
+			AuthenticationResult: &types.AuthenticationResultType{
+				AccessToken:  aws.String("123"),
+				IdToken:      aws.String("123"),
+				RefreshToken: aws.String("123"),
+				ExpiresIn:    3600,
+			},
+		}, nil)
+	}
+}
+
+func validateUserChallengeResponse(t *testing.T, res *http.Response, expectedHTTP int, expectedMessage string, expectedData string) {
+	if res.StatusCode != expectedHTTP {
+		t.Errorf("Expected status code to be: %d. Got: %d.", expectedHTTP, res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	contents := userChallengeResponse{}
+	err = json.Unmarshal(data, &contents)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	if contents.Message != expectedMessage {
+		t.Errorf("Expected message to be %s. Got: %s", expectedMessage, contents.Message)
+	}
+
+	if expectedData != "" && contents.Data != expectedData && !hasFieldError(contents.Data, expectedData) {
+		t.Errorf("Expected body to be %s. Got: %s", expectedData, contents.Data)
+	}
+}
+
+// TestUserChallenge runs the unit tests for the RespondToChallenge function.
+func TestUserChallenge(t *testing.T) {
+	cases := []userChallengeTestCase{
+		{
+			testName:        "BEUT-122: User Challenge Valid TOTP Code",
+			validData:       true,
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+			UserEmail:       "test@test.gmail.com",
+			ChallengeName:   "SOFTWARE_TOKEN_MFA",
+			Session:         "session",
+			Code:            "123456",
+		},
+		{
+			testName:        "BEUT-123: User Challenge Incorrect TOTP Code",
+			validData:       true,
+			incorrectCode:   true,
+			expectedHTTP:    http.StatusUnauthorized,
+			expectedMessage: "unauthorized",
+			expectedBody:    "Invalid code received for user",
+			UserEmail:       "test@test.gmail.com",
+			ChallengeName:   "SOFTWARE_TOKEN_MFA",
+			Session:         "session",
+			Code:            "654321",
+		},
+		{
+			testName:        "BEUT-124: User Challenge Valid New Password",
+			validData:       true,
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+			UserEmail:       "test@test.gmail.com",
+			ChallengeName:   "NEW_PASSWORD_REQUIRED",
+			Session:         "session",
+			NewPassword:     "newPassword1!",
+		},
+		{
+			testName:        "BEUT-125: User Challenge Missing Session",
+			validData:       false,
+			expectedHTTP:    http.StatusUnprocessableEntity,
+			expectedMessage: "data validation failed",
+			expectedBody:    "Missing session",
+			UserEmail:       "test@test.gmail.com",
+			ChallengeName:   "SOFTWARE_TOKEN_MFA",
+			Code:            "123456",
+		},
+		{
+			testName:        "BEUT-126: User Challenge Missing Code",
+			validData:       false,
+			expectedHTTP:    http.StatusUnprocessableEntity,
+			expectedMessage: "data validation failed",
+			expectedBody:    "Missing code",
+			UserEmail:       "test@test.gmail.com",
+			ChallengeName:   "SOFTWARE_TOKEN_MFA",
+			Session:         "session",
+		},
+		{
+			testName:        "BEUT-127: User Challenge Unsupported Challenge",
+			validData:       false,
+			expectedHTTP:    http.StatusUnprocessableEntity,
+			expectedMessage: "data validation failed",
+			expectedBody:    "Unsupported challenge: CUSTOM_CHALLENGE",
+			UserEmail:       "test@test.gmail.com",
+			ChallengeName:   "CUSTOM_CHALLENGE",
+			Session:         "session",
+		},
+	}
+
+	for _, tc := range cases {
+		client, srv, cognito := setupUserChallengeTest(t)
+		t.Run(tc.testName, func(t *testing.T) {
+
+			if tc.validData {
+				setupUserChallengeMockExpectations(cognito, tc)
+			}
+
+			url := fmt.Sprintf("%s%s", srv.URL, userChallengeEndpoint)
+
+			jsonBody, err := json.Marshal(tc)
+			if err != nil {
+				t.Fatalf("Error marshalling json: %v", err)
+			}
+
+			res, err := client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			validateUserChallengeResponse(t, res, tc.expectedHTTP, tc.expectedMessage, tc.expectedBody)
+
+		})
+	}
+}
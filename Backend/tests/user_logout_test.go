@@ -11,6 +11,7 @@ import (
 	"testing"
 	"willowsuite-vault/config"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-redis/redismock/v9"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 )
 
@@ -32,22 +34,25 @@ type userLogOutTestCase struct {
 	expectedHTTP    int
 	expectedMessage string
 	expectedBody    string
+	userName        string
+	accessToken     string
 	RefreshToken    string `json:"refreshToken"`
 }
 
 var userLogOutEndpoint = "/v1/token"
 
-func setupUserLogOutTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.MockCognitoClient) {
+func setupUserLogOutTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.MockCognitoClient, *mocks.MockTokenHelper, redismock.ClientMock) {
 	ctrl := gomock.NewController(t)
 	t.Cleanup(ctrl.Finish)
 
 	postgres, _ := mocks.NewMockDB()
-	redis, _ := redismock.NewClientMock()
+	redis, mockCache := redismock.NewClientMock()
 	cognito := mocks.NewMockCognitoClient(ctrl)
+	tokenHelper := mocks.NewMockTokenHelper(ctrl)
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: cognito,
-		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+		TokenHelper:   tokenHelper,
 	}
 
 	r := chi.NewRouter()
@@ -56,16 +61,26 @@ func setupUserLogOutTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.M
 	srv := httptest.NewServer(r)
 	t.Cleanup(srv.Close)
 
-	return &http.Client{}, srv, cognito
+	return &http.Client{}, srv, cognito, tokenHelper, mockCache
 }
 
-func setupUserLogOutMockExpectations(cognito *mocks.MockCognitoClient, refreshToken string) {
+func setupUserLogOutMockExpectations(cognito *mocks.MockCognitoClient, tokenHelper *mocks.MockTokenHelper, mockCache redismock.ClientMock, refreshToken string, accessToken string, userName string) {
 
 	cognito.EXPECT().RevokeToken(gomock.All(), &cognitoidentityprovider.RevokeTokenInput{
 		ClientId:     aws.String(config.CognitoClientID()),
 		ClientSecret: aws.String(config.CognitoClientSecret()),
 		Token:        aws.String(refreshToken),
 	})
+
+	if accessToken != "" {
+		sessionID := "origin-jti"
+		tokenHelper.EXPECT().VerifyToken(accessToken, false).Return(&jwt.Token{}, nil)
+		tokenHelper.EXPECT().ExtractClaims(gomock.Any()).Return(jwt.MapClaims{"username": userName, "origin_jti": sessionID}, nil)
+
+		revokedKey := `{"CacheKey":{"User":"` + userName + `","Function":"RevokedToken"},"TokenID":"` + sessionID + `"}`
+		mockCache.ExpectSet(revokedKey, 1, config.AccessTokenMaxAge()).SetVal("OK")
+		mockCache.ExpectHDel(`{"User":"`+userName+`","Function":"Sessions"}`, sessionID).SetVal(1)
+	}
 }
 
 func validateUserLogOutResponse(t *testing.T, res *http.Response, expectedHTTP int, expectedMessage string, expectedData string) {
@@ -88,7 +103,7 @@ func validateUserLogOutResponse(t *testing.T, res *http.Response, expectedHTTP i
 		t.Errorf("Expected message to be %s. Got: %s", expectedMessage, contents.Message)
 	}
 
-	if expectedData != "" && contents.Data != expectedData {
+	if expectedData != "" && contents.Data != expectedData && !hasFieldError(contents.Data, expectedData) {
 		t.Errorf("Expected body to be %s. Got: %s", expectedData, contents.Data)
 	}
 }
@@ -104,10 +119,20 @@ func TestUserLogOut(t *testing.T) {
 			expectedBody:    "",
 			RefreshToken:    "jkadlkfjhadsfakbcvadsuiofha",
 		},
+		{
+			testName:        "BEUT-134: User Log Out Revokes Access Token",
+			validData:       true,
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+			expectedBody:    "",
+			userName:        "testuser",
+			accessToken:     "access-token",
+			RefreshToken:    "jkadlkfjhadsfakbcvadsuiofha",
+		},
 		{
 			testName:        "BEUT-58: User Log Out Invalid Data",
 			validData:       false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing refresh token",
 			RefreshToken:    "",
@@ -115,11 +140,11 @@ func TestUserLogOut(t *testing.T) {
 	}
 
 	for _, tc := range cases {
-		client, srv, cognito := setupUserLogOutTest(t)
+		client, srv, cognito, tokenHelper, mockCache := setupUserLogOutTest(t)
 		t.Run(tc.testName, func(t *testing.T) {
 
 			if tc.validData {
-				setupUserLogOutMockExpectations(cognito, tc.RefreshToken)
+				setupUserLogOutMockExpectations(cognito, tokenHelper, mockCache, tc.RefreshToken, tc.accessToken, tc.userName)
 			}
 
 			url := fmt.Sprintf("%s%s", srv.URL, userLogOutEndpoint)
@@ -134,6 +159,10 @@ func TestUserLogOut(t *testing.T) {
 				t.Fatalf("Failed to build request: %v", err)
 			}
 
+			if tc.accessToken != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.accessToken)
+			}
+
 			res, err := client.Do(req)
 			if err != nil {
 				t.Fatalf("Failed to send request: %v", err)
@@ -142,6 +171,10 @@ func TestUserLogOut(t *testing.T) {
 
 			validateUserLogOutResponse(t, res, tc.expectedHTTP, tc.expectedMessage, tc.expectedBody)
 
+			if err := mockCache.ExpectationsWereMet(); err != nil {
+				t.Errorf("Redis expectations were not met: %v", err)
+			}
+
 		})
 	}
 }
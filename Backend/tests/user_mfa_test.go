@@ -0,0 +1,234 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+)
+
+type userMFAResponse struct {
+	Message string            `json:"message"`
+	Data    map[string]string `json:"data"`
+}
+
+type userMFATestCase struct {
+	testName        string
+	method          string
+	validData       bool
+	accessToken     string
+	expectedHTTP    int
+	expectedMessage string
+	Session         string `json:"session"`
+	Code            string `json:"code"`
+}
+
+var userMFAEndpoint = "/v1/user/mfa"
+
+func setupUserMFATest(t *testing.T) (*http.Client, *httptest.Server, *mocks.MockCognitoClient) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	postgres, _ := mocks.NewMockDB()
+	redis, _ := redismock.NewClientMock()
+	cognito := mocks.NewMockCognitoClient(ctrl)
+	handler := controllers.Handler{
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
+		CognitoClient: cognito,
+		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+	}
+
+	r := chi.NewRouter()
+	r.Post(userMFAEndpoint, handler.AssociateSoftwareToken)
+	r.Put(userMFAEndpoint, handler.VerifySoftwareToken)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &http.Client{}, srv, cognito
+}
+
+func setupUserMFAMockExpectations(cognito *mocks.MockCognitoClient, tc userMFATestCase) {
+	if tc.method == http.MethodPost {
+		input := &cognitoidentityprovider.AssociateSoftwareTokenInput{}
+		if tc.accessToken != "" {
+			input.AccessToken = aws.String(tc.accessToken)
+		} else {
+			input.Session = aws.String(tc.Session)
+		}
+
+		cognito.EXPECT().AssociateSoftwareToken(gomock.All(), input).Return(&cognitoidentityprovider.AssociateSoftwareTokenOutput{
+			SecretCode: aws.String("SECRETCODE"),
+			Session:    aws.String("next-session"),
+		}, nil)
+		return
+	}
+
+	input := &cognitoidentityprovider.VerifySoftwareTokenInput{
+		UserCode:           aws.String(tc.Code),
+		FriendlyDeviceName: aws.String(""),
+	}
+	if tc.accessToken != "" {
+		input.AccessToken = aws.String(tc.accessToken)
+	} else {
+		input.Session = aws.String(tc.Session)
+	}
+
+	cognito.EXPECT().VerifySoftwareToken(gomock.All(), input).Return(&cognitoidentityprovider.VerifySoftwareTokenOutput{
+		Status:  types.VerifySoftwareTokenResponseTypeSuccess,
+		Session: aws.String("next-session"),
+	}, nil)
+
+	if tc.accessToken != "" {
+		cognito.EXPECT().SetUserMFAPreference(gomock.All(), &cognitoidentityprovider.SetUserMFAPreferenceInput{
+			AccessToken: aws.String(tc.accessToken),
+			SoftwareTokenMfaSettings: &types.SoftwareTokenMfaSettingsType{
+				Enabled:      true,
+				PreferredMfa: true,
+			},
+		}).Return(&cognitoidentityprovider.SetUserMFAPreferenceOutput{}, nil)
+	}
+}
+
+func validateUserMFAResponse(t *testing.T, res *http.Response, tc userMFATestCase) {
+	if res.StatusCode != tc.expectedHTTP {
+		t.Errorf("Expected status code to be: %d. Got: %d.", tc.expectedHTTP, res.StatusCode)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	contents := map[string]interface{}{}
+	err = json.Unmarshal(data, &contents)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	if contents["message"] != tc.expectedMessage {
+		t.Errorf("Expected message to be %s. Got: %s", tc.expectedMessage, contents["message"])
+	}
+
+	if !tc.validData {
+		return
+	}
+
+	success := userMFAResponse{}
+	if err = json.Unmarshal(data, &success); err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	if tc.method == http.MethodPost && success.Data["SecretCode"] != "SECRETCODE" {
+		t.Errorf("Expected secret code to be SECRETCODE. Got: %s", success.Data["SecretCode"])
+	}
+
+	if tc.method == http.MethodPut && success.Data["Status"] != string(types.VerifySoftwareTokenResponseTypeSuccess) {
+		t.Errorf("Expected status to be SUCCESS. Got: %s", success.Data["Status"])
+	}
+}
+
+// TestUserMFA runs the unit tests for the AssociateSoftwareToken and VerifySoftwareToken functions.
+func TestUserMFA(t *testing.T) {
+	cases := []userMFATestCase{
+		{
+			testName:        "BEUT-128: User MFA Associate With Access Token",
+			method:          http.MethodPost,
+			validData:       true,
+			accessToken:     "access-token",
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+		},
+		{
+			testName:        "BEUT-129: User MFA Associate With Session",
+			method:          http.MethodPost,
+			validData:       true,
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+			Session:         "session",
+		},
+		{
+			testName:        "BEUT-130: User MFA Associate Missing Credentials",
+			method:          http.MethodPost,
+			validData:       false,
+			expectedHTTP:    http.StatusBadRequest,
+			expectedMessage: "data validation failed",
+		},
+		{
+			testName:        "BEUT-131: User MFA Verify With Access Token",
+			method:          http.MethodPut,
+			validData:       true,
+			accessToken:     "access-token",
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+			Code:            "123456",
+		},
+		{
+			testName:        "BEUT-132: User MFA Verify With Session",
+			method:          http.MethodPut,
+			validData:       true,
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+			Session:         "session",
+			Code:            "123456",
+		},
+		{
+			testName:        "BEUT-133: User MFA Verify Missing Code",
+			method:          http.MethodPut,
+			validData:       false,
+			accessToken:     "access-token",
+			expectedHTTP:    http.StatusUnprocessableEntity,
+			expectedMessage: "data validation failed",
+		},
+	}
+
+	for _, tc := range cases {
+		client, srv, cognito := setupUserMFATest(t)
+		t.Run(tc.testName, func(t *testing.T) {
+
+			if tc.validData {
+				setupUserMFAMockExpectations(cognito, tc)
+			}
+
+			url := fmt.Sprintf("%s%s", srv.URL, userMFAEndpoint)
+
+			jsonBody, err := json.Marshal(tc)
+			if err != nil {
+				t.Fatalf("Error marshalling json: %v", err)
+			}
+
+			req, err := http.NewRequest(tc.method, url, bytes.NewBuffer(jsonBody))
+			if err != nil {
+				t.Fatalf("Failed to build request: %v", err)
+			}
+
+			if tc.accessToken != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.accessToken)
+			}
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			validateUserMFAResponse(t, res, tc)
+
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"willowsuite-vault/config"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -51,7 +52,7 @@ func setupUserRefreshTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.
 	cognito := mocks.NewMockCognitoClient(ctrl)
 	tokenHelper := mocks.NewMockTokenHelper(ctrl)
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: cognito,
 		TokenHelper:   tokenHelper,
 	}
@@ -120,7 +121,7 @@ func validateUserRefreshResponse(t *testing.T, res *http.Response, expectedHTTP
 		t.Errorf("Expected message to be %s. Got: %s : %s", expectedMessage, contents.Message, contents.Data)
 	}
 
-	if expectedData != "" && contents.Data != expectedData {
+	if expectedData != "" && contents.Data != expectedData && !hasFieldError(contents.Data, expectedData) {
 		t.Errorf("Expected body to be %s. Got: %s", expectedData, contents.Data)
 	}
 }
@@ -143,9 +144,9 @@ func TestUserRefresh(t *testing.T) {
 			testName:        "BEUT-60: User Refresh Bad Data",
 			validData:       true,
 			badRequest:      true,
-			expectedHTTP:    http.StatusBadRequest,
-			expectedMessage: "data validation failed",
-			expectedBody:    "Couldn't refresh user",
+			expectedHTTP:    http.StatusUnauthorized,
+			expectedMessage: "unauthorized",
+			expectedBody:    "Incorrect token or userName.",
 			userName:        "testuser1",
 			RefreshToken:    "lkjanbdljkahvf",
 			IDToken:         "eyJraWQiOiJQNW",
@@ -154,7 +155,7 @@ func TestUserRefresh(t *testing.T) {
 			testName:        "BEUT-61: User Refresh Missing Refresh Token",
 			validData:       false,
 			badRequest:      false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing refresh token",
 			userName:        "testuser2",
@@ -165,7 +166,7 @@ func TestUserRefresh(t *testing.T) {
 			testName:        "BEUT-62: User Refresh Missing ID Token",
 			validData:       false,
 			badRequest:      false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing id token",
 			userName:        "testuser3",
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"willowsuite-vault/config"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -31,6 +32,7 @@ type userSignInTestCase struct {
 	testName          string
 	validData         bool
 	incorrectPassword bool
+	mfaChallenge      bool
 	expectedHTTP      int
 	expectedMessage   string
 	expectedBody      string
@@ -48,7 +50,7 @@ func setupUserSignInTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.M
 	redis, _ := redismock.NewClientMock()
 	cognito := mocks.NewMockCognitoClient(ctrl)
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: cognito,
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -62,9 +64,23 @@ func setupUserSignInTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.M
 	return &http.Client{}, srv, cognito
 }
 
-func setupUserSignInMockExpectations(cognito *mocks.MockCognitoClient, userEmail string, password string, incorrectPassword bool) {
+func setupUserSignInMockExpectations(cognito *mocks.MockCognitoClient, userEmail string, password string, incorrectPassword bool, mfaChallenge bool) {
 
-	if incorrectPassword {
+	if mfaChallenge {
+		cognito.EXPECT().InitiateAuth(gomock.All(), &cognitoidentityprovider.InitiateAuthInput{
+			AuthFlow: "USER_PASSWORD_AUTH",
+			ClientId: aws.String(config.CognitoClientID()),
+			AuthParameters: map[string]string{
+				"USERNAME":    userEmail,
+				"PASSWORD":    password,
+				"SECRET_HASH": config.CognitoSecretHash(userEmail),
+			},
+		}).Return(&cognitoidentityprovider.InitiateAuthOutput{
+			ChallengeName:       types.ChallengeNameTypeSoftwareTokenMfa,
+			Session:             aws.String("session"),
+			ChallengeParameters: map[string]string{"USER_ID_FOR_SRP": userEmail},
+		}, nil)
+	} else if incorrectPassword {
 		cognito.EXPECT().InitiateAuth(gomock.All(), &cognitoidentityprovider.InitiateAuthInput{
 			AuthFlow: "USER_PASSWORD_AUTH",
 			ClientId: aws.String(config.CognitoClientID()),
@@ -118,7 +134,7 @@ func validateUserSignInResponse(t *testing.T, res *http.Response, expectedHTTP i
 		t.Errorf("Expected message to be %s. Got: %s", expectedMessage, contents.Message)
 	}
 
-	if expectedData != "" && contents.Data != expectedData {
+	if expectedData != "" && contents.Data != expectedData && !hasFieldError(contents.Data, expectedData) {
 		t.Errorf("Expected body to be %s. Got: %s", expectedData, contents.Data)
 	}
 }
@@ -146,10 +162,20 @@ func TestUserSignIn(t *testing.T) {
 			UserEmail:         "test@test.gmail.com",
 			Password:          "password",
 		},
+		{
+			testName:        "BEUT-121: User Sign In MFA Challenge",
+			validData:       true,
+			mfaChallenge:    true,
+			expectedHTTP:    http.StatusOK,
+			expectedMessage: "success",
+			expectedBody:    "",
+			UserEmail:       "test@test.gmail.com",
+			Password:        "password",
+		},
 		{
 			testName:        "BEUT-55: User Sign In Missing Email",
 			validData:       false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing user email",
 			UserEmail:       "",
@@ -158,7 +184,7 @@ func TestUserSignIn(t *testing.T) {
 		{
 			testName:        "BEUT-56: User Sign In Missing Password",
 			validData:       false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing password",
 			UserEmail:       "test@test.test",
@@ -171,7 +197,7 @@ func TestUserSignIn(t *testing.T) {
 		t.Run(tc.testName, func(t *testing.T) {
 
 			if tc.validData {
-				setupUserSignInMockExpectations(cognito, tc.UserEmail, tc.Password, tc.incorrectPassword)
+				setupUserSignInMockExpectations(cognito, tc.UserEmail, tc.Password, tc.incorrectPassword, tc.mfaChallenge)
 			}
 
 			url := fmt.Sprintf("%s%s", srv.URL, userSignInEndpoint)
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"willowsuite-vault/config"
 	"willowsuite-vault/controllers"
+	"willowsuite-vault/infra/cache"
 	"willowsuite-vault/repository"
 	"willowsuite-vault/tests/mocks"
 
@@ -52,7 +53,7 @@ func setupUserSignUpTest(t *testing.T) (*http.Client, *httptest.Server, *mocks.M
 	redis, _ := redismock.NewClientMock()
 	cognito := mocks.NewMockCognitoClient(ctrl)
 	handler := controllers.Handler{
-		Repository:    &repository.Repository{Database: postgres, Cache: redis},
+		Repository:    &repository.Repository{Database: postgres, Cache: cache.NewRedisCache(redis), Redis: redis},
 		CognitoClient: cognito,
 		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
 	}
@@ -134,7 +135,7 @@ func validateUserSignUpResponse(t *testing.T, res *http.Response, expectedHTTP i
 		t.Errorf("Expected message to be %s. Got: %s", expectedMessage, contents.Message)
 	}
 
-	if expectedData != "" && contents.Data != expectedData {
+	if expectedData != "" && contents.Data != expectedData && !hasFieldError(contents.Data, expectedData) {
 		t.Errorf("Expected body to be %s. Got: %s", expectedData, contents.Data)
 	}
 }
@@ -172,8 +173,8 @@ func TestUserSignUp(t *testing.T) {
 			validData:       true,
 			badPassword:     false,
 			duplicateUser:   true,
-			expectedHTTP:    http.StatusBadRequest,
-			expectedMessage: "data validation failed",
+			expectedHTTP:    http.StatusConflict,
+			expectedMessage: "conflict",
 			expectedBody:    "User already exists",
 			UserEmail:       "test@test.gmail.com",
 			Password:        "password",
@@ -184,7 +185,7 @@ func TestUserSignUp(t *testing.T) {
 		{
 			testName:        "BEUT-44: User Sign Up Missing Email",
 			validData:       false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing user name",
 			UserEmail:       "",
@@ -196,7 +197,7 @@ func TestUserSignUp(t *testing.T) {
 		{
 			testName:        "BEUT-45: User Sign Up Missing Password",
 			validData:       false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing password",
 			UserEmail:       "test@test.gmail.com",
@@ -208,7 +209,7 @@ func TestUserSignUp(t *testing.T) {
 		{
 			testName:        "BEUT-46: User Sign Up Missing First Name",
 			validData:       false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing first name",
 			UserEmail:       "test@test.test",
@@ -220,7 +221,7 @@ func TestUserSignUp(t *testing.T) {
 		{
 			testName:        "BEUT-47: User Sign Up Missing Last Name",
 			validData:       false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing last name",
 			UserEmail:       "test@test.test",
@@ -232,7 +233,7 @@ func TestUserSignUp(t *testing.T) {
 		{
 			testName:        "BEUT-48: User Sign Up Missing Birthday",
 			validData:       false,
-			expectedHTTP:    http.StatusBadRequest,
+			expectedHTTP:    http.StatusUnprocessableEntity,
 			expectedMessage: "data validation failed",
 			expectedBody:    "Missing birthday",
 			UserEmail:       "test@test.test",
@@ -0,0 +1,159 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"willowsuite-vault/models"
+)
+
+type validationResponse struct {
+	Message string                  `json:"message"`
+	Data    models.ValidationErrors `json:"data"`
+}
+
+type validationTestCase struct {
+	testName       string
+	testUser       string
+	body           string
+	expectedHTTP   int
+	expectedErrors models.ValidationErrors
+}
+
+// hasFieldError reports whether a 422 response body lists a field error with the given message.
+func hasFieldError(data interface{}, message string) bool {
+	fieldErrors, ok := data.([]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, fieldError := range fieldErrors {
+		if details, ok := fieldError.(map[string]interface{}); ok && details["message"] == message {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateValidationResponse(t *testing.T, res *http.Response, expectedHTTP int, expectedErrors models.ValidationErrors) {
+	if res.StatusCode != expectedHTTP {
+		t.Errorf("Expected status code to be: %d. Got: %d.", expectedHTTP, res.StatusCode)
+	}
+
+	if expectedHTTP != http.StatusUnprocessableEntity {
+		return
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	contents := validationResponse{}
+	if err = json.Unmarshal(data, &contents); err != nil {
+		t.Errorf("Expected error to be nil. Got: %v", err)
+	}
+
+	if len(contents.Data) != len(expectedErrors) {
+		t.Fatalf("Expected %d field errors. Got: %v", len(expectedErrors), contents.Data)
+	}
+
+	for i, expected := range expectedErrors {
+		if contents.Data[i].Field != expected.Field || contents.Data[i].Code != expected.Code {
+			t.Errorf("Expected field error %s/%s. Got: %s/%s", expected.Field, expected.Code, contents.Data[i].Field, contents.Data[i].Code)
+		}
+	}
+}
+
+// TestRequestValidation runs the unit tests for decoding and validating typed request bodies.
+func TestRequestValidation(t *testing.T) {
+	cases := []validationTestCase{
+		{
+			testName:     "BEUT-139: Validation Lists Every Field Error",
+			testUser:     "testuser",
+			body:         `{"category":"item"}`,
+			expectedHTTP: http.StatusUnprocessableEntity,
+			expectedErrors: models.ValidationErrors{
+				{Field: "name", Code: models.ValidationRequired},
+				{Field: "parentID", Code: models.ValidationRequired},
+				{Field: "parentCategory", Code: models.ValidationRequired},
+			},
+		},
+		{
+			testName:     "BEUT-140: Validation Invalid Category And Parent ID",
+			testUser:     "testuser",
+			body:         `{"name":"Test","category":"garage","parentID":"abc","parentCategory":"room"}`,
+			expectedHTTP: http.StatusUnprocessableEntity,
+			expectedErrors: models.ValidationErrors{
+				{Field: "category", Code: models.ValidationInvalidChoice},
+				{Field: "parentID", Code: models.ValidationInvalidType},
+			},
+		},
+		{
+			testName:     "BEUT-141: Validation Invalid Parent Category",
+			testUser:     "testuser",
+			body:         `{"name":"Test","category":"shelf","parentID":10,"parentCategory":"room"}`,
+			expectedHTTP: http.StatusUnprocessableEntity,
+			expectedErrors: models.ValidationErrors{
+				{Field: "parentCategory", Code: models.ValidationInvalidParent},
+			},
+		},
+		{
+			testName:     "BEUT-142: Validation Wrong JSON Type",
+			testUser:     "testuser",
+			body:         `{"name":12,"category":"building"}`,
+			expectedHTTP: http.StatusUnprocessableEntity,
+			expectedErrors: models.ValidationErrors{
+				{Field: "name", Code: models.ValidationInvalidType},
+			},
+		},
+		{
+			testName:     "BEUT-232: Validation Lists Wrong Types With Other Field Errors",
+			testUser:     "testuser",
+			body:         `{"name":12,"notes":5,"category":"garage","parentID":10,"parentCategory":"room"}`,
+			expectedHTTP: http.StatusUnprocessableEntity,
+			expectedErrors: models.ValidationErrors{
+				{Field: "name", Code: models.ValidationInvalidType},
+				{Field: "notes", Code: models.ValidationInvalidType},
+				{Field: "category", Code: models.ValidationInvalidChoice},
+			},
+		},
+		{
+			testName:     "BEUT-143: Validation Accepts Native JSON Numbers",
+			testUser:     "testuser",
+			body:         `{"name":"Test Item","notes":"Notes","category":"item","parentID":10,"parentCategory":"container"}`,
+			expectedHTTP: http.StatusOK,
+		},
+		{
+			testName:     "BEUT-144: Validation Malformed JSON",
+			testUser:     "testuser",
+			body:         `{"name":`,
+			expectedHTTP: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		client, srv, mockDB, mockCache := setupCreateEntityTest(t, tc.testUser)
+		t.Run(tc.testName, func(t *testing.T) {
+			if tc.expectedHTTP == http.StatusOK {
+				setupCreateEntityMockExpectations(&mockDB, mockCache, "item", "Test Item", "Notes", tc.testUser, "1", "10", "container")
+			}
+
+			res, err := client.Post(srv.URL+endpoint, "application/json", bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			validateValidationResponse(t, res, tc.expectedHTTP, tc.expectedErrors)
+
+			if err := mockDB.ExpectationsWereMet(); err != nil {
+				t.Errorf("PostGres expectations were not met: %v", err)
+			}
+		})
+	}
+}
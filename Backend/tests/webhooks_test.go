@@ -0,0 +1,410 @@
+// Package tests is where all of out unit tests are described.
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"regexp"
+	"testing"
+	"time"
+	"willowsuite-vault/controllers"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/models"
+	"willowsuite-vault/repository"
+	"willowsuite-vault/tests/mocks"
+	"willowsuite-vault/webhooks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"gorm.io/gorm"
+)
+
+type webhookResponse struct {
+	Message string         `json:"message"`
+	Data    models.Webhook `json:"data"`
+}
+
+var queueWebhooksQuery = regexp.QuoteMeta(`INSERT INTO webhook_deliveries (webhook_id, event_type, payload, status, next_attempt_at, created_at) SELECT id,`)
+
+var claimDeliveriesQuery = regexp.QuoteMeta(`UPDATE webhook_deliveries d SET next_attempt_at = $1 FROM webhooks w WHERE w.id = d.webhook_id AND d.id IN`)
+
+var recordAttemptQuery = regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "attempts"=$1,"delivered_at"=$2,"last_error"=$3,"last_status"=$4,"next_attempt_at"=$5,"status"=$6 WHERE id = $7`)
+
+var retryAttemptQuery = regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "attempts"=$1,"last_error"=$2,"last_status"=$3,"next_attempt_at"=$4 WHERE id = $5`)
+
+// expectWebhooksQueued expects a delivery of an event of each of the given types to be queued for a user's
+// webhooks, in order.
+func expectWebhooksQueued(mockDB sqlmock.Sqlmock, testUser string, eventTypes ...string) {
+	for _, eventType := range eventTypes {
+		mockDB.ExpectExec(queueWebhooksQuery).
+			WithArgs(eventType, sqlmock.AnyArg(), models.DeliveryPending, sqlmock.AnyArg(), sqlmock.AnyArg(), testUser, eventType, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func setupWebhooksTest(t *testing.T, userName string) (*http.Client, *httptest.Server, sqlmock.Sqlmock) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	postgres, mockDB := mocks.NewMockDB()
+	handler := controllers.Handler{
+		Repository:    &repository.Repository{Database: postgres},
+		CognitoClient: mocks.NewMockCognitoClient(ctrl),
+		TokenHelper:   mocks.NewMockTokenHelper(ctrl),
+	}
+
+	r := chi.NewRouter()
+	r.Use(mocks.MockJWTMiddleware(userName))
+	r.Post("/v1/webhooks", handler.CreateWebhook)
+	r.Post("/v1/webhooks/{id}/deliveries/{deliveryID}/redeliver", handler.RedeliverWebhook)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &http.Client{}, srv, mockDB
+}
+
+// dueDelivery is the row the dispatcher claims for a delivery of an item update to url.
+func dueDelivery(url string, attempts int) *sqlmock.Rows {
+	payload, _ := models.WebhookPayload{EntityEvent: models.EntityEvent{Type: models.EventUpdate, Category: "item", EntityID: 10, Version: 4}}.Value()
+
+	return sqlmock.NewRows([]string{"id", "webhook_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "last_status", "last_error", "created_at", "delivered_at", "url", "secret"}).
+		AddRow(7, 3, models.EventUpdate, payload, models.DeliveryPending, attempts, time.Now(), 0, "", time.Now(), nil, url, "topsecret")
+}
+
+// localDispatcher returns a dispatcher that may send to this host, where the test receivers run. Its client still
+// refuses redirects.
+func localDispatcher(postgres *gorm.DB) *webhooks.Dispatcher {
+	dispatcher := webhooks.NewDispatcher(&repository.Repository{Database: postgres})
+	dispatcher.Client.Transport = http.DefaultTransport
+	return dispatcher
+}
+
+// TestWebhooks runs the unit tests for webhook subscriptions and deliveries.
+func TestWebhooks(t *testing.T) {
+	t.Run("BEUT-201: Create Webhook Validates Filters", func(t *testing.T) {
+		client, srv, mockDB := setupWebhooksTest(t, "testuser")
+
+		body := `{"url":"ftp://hub.local/hook","events":["create","rename"],"categories":["item","drawer"]}`
+		res, err := client.Post(srv.URL+"/v1/webhooks", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status code to be: %d. Got: %d.", http.StatusUnprocessableEntity, res.StatusCode)
+		}
+
+		contents := validationResponse{}
+		data, _ := io.ReadAll(res.Body)
+		if err := json.Unmarshal(data, &contents); err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		expected := []string{"url", "events[1]", "categories[1]"}
+		if len(contents.Data) != len(expected) {
+			t.Fatalf("Expected %d field errors. Got: %v", len(expected), contents.Data)
+		}
+
+		for i, field := range expected {
+			if contents.Data[i].Field != field {
+				t.Errorf("Expected a field error for %s. Got: %s", field, contents.Data[i].Field)
+			}
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-202: Create Webhook Returns Its Secret", func(t *testing.T) {
+		client, srv, mockDB := setupWebhooksTest(t, "testuser")
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "webhooks" ("user_id","url","secret","events","categories","created_at") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`)).
+			WithArgs("testuser", "https://hub.local/hook", sqlmock.AnyArg(), `["move"]`, `[]`, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mockDB.ExpectCommit()
+
+		body := `{"url":"https://hub.local/hook","events":["move"]}`
+		res, err := client.Post(srv.URL+"/v1/webhooks", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code to be: %d. Got: %d.", http.StatusOK, res.StatusCode)
+		}
+
+		contents := webhookResponse{}
+		data, _ := io.ReadAll(res.Body)
+		if err := json.Unmarshal(data, &contents); err != nil {
+			t.Errorf("Expected error to be nil. Got: %v", err)
+		}
+
+		if contents.Data.ID != 3 || len(contents.Data.Secret) != 64 {
+			t.Errorf("Expected webhook 3 with a 32 byte secret. Got: %+v", contents.Data)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-203: Deliveries Are Signed", func(t *testing.T) {
+		var received *http.Request
+		var receivedBody []byte
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			receivedBody, _ = io.ReadAll(r.Body)
+		}))
+		defer receiver.Close()
+
+		postgres, mockDB := mocks.NewMockDB()
+		mockDB.ExpectQuery(claimDeliveriesQuery).WillReturnRows(dueDelivery(receiver.URL, 0))
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(recordAttemptQuery).
+			WithArgs(1, sqlmock.AnyArg(), "", http.StatusOK, nil, models.DeliverySucceeded, 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		dispatcher := localDispatcher(postgres)
+		if claimed := dispatcher.DeliverDue(context.Background()); claimed != 1 {
+			t.Fatalf("Expected one delivery to be claimed. Got: %d", claimed)
+		}
+
+		if received == nil {
+			t.Fatalf("Expected the delivery to be sent")
+		}
+
+		signature := "sha256=" + webhooks.Sign("topsecret", received.Header.Get("X-Vault-Timestamp"), receivedBody)
+		if received.Header.Get("X-Vault-Signature") != signature {
+			t.Errorf("Expected signature %s. Got: %s", signature, received.Header.Get("X-Vault-Signature"))
+		}
+
+		if received.Header.Get("X-Vault-Event") != models.EventUpdate || received.Header.Get("X-Vault-Delivery") != "7" {
+			t.Errorf("Expected the event and delivery headers. Got: %v", received.Header)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-204: Failed Deliveries Are Retried With Backoff", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer receiver.Close()
+
+		postgres, mockDB := mocks.NewMockDB()
+		mockDB.ExpectQuery(claimDeliveriesQuery).WillReturnRows(dueDelivery(receiver.URL, 2))
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(retryAttemptQuery).
+			WithArgs(3, "receiver answered 503", http.StatusServiceUnavailable, sqlmock.AnyArg(), 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		dispatcher := localDispatcher(postgres)
+		dispatcher.DeliverDue(context.Background())
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+
+		expected := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+		for i, wait := range expected {
+			if got := webhooks.Backoff(i + 1); got != wait {
+				t.Errorf("Expected retry %d to wait %v. Got: %v", i+1, wait, got)
+			}
+		}
+
+		if got := webhooks.Backoff(30); got != 6*time.Hour {
+			t.Errorf("Expected retries to wait at most 6h. Got: %v", got)
+		}
+	})
+
+	t.Run("BEUT-205: Deliveries Fail After The Last Attempt", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		}))
+		defer receiver.Close()
+
+		postgres, mockDB := mocks.NewMockDB()
+		mockDB.ExpectQuery(claimDeliveriesQuery).WillReturnRows(dueDelivery(receiver.URL, webhooks.MaxAttempts-1))
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "attempts"=$1,"last_error"=$2,"last_status"=$3,"next_attempt_at"=$4,"status"=$5 WHERE id = $6`)).
+			WithArgs(webhooks.MaxAttempts, "receiver answered 410", http.StatusGone, nil, models.DeliveryFailed, 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		dispatcher := localDispatcher(postgres)
+		dispatcher.DeliverDue(context.Background())
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-206: Redeliver Queues The Event Again", func(t *testing.T) {
+		client, srv, mockDB := setupWebhooksTest(t, "testuser")
+
+		redeliverQuery := regexp.QuoteMeta(`INSERT INTO webhook_deliveries (webhook_id, event_type, payload, status, next_attempt_at, created_at) SELECT d.webhook_id`)
+		mockDB.ExpectQuery(redeliverQuery).
+			WithArgs(models.DeliveryPending, sqlmock.AnyArg(), sqlmock.AnyArg(), 7, 3, "testuser").
+			WillReturnRows(dueDelivery("", 0))
+		mockDB.ExpectQuery(redeliverQuery).
+			WithArgs(models.DeliveryPending, sqlmock.AnyArg(), sqlmock.AnyArg(), 8, 3, "testuser").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		res, err := client.Post(srv.URL+"/v1/webhooks/3/deliveries/7/redeliver", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusOK, res.StatusCode)
+		}
+
+		res, err = client.Post(srv.URL+"/v1/webhooks/3/deliveries/8/redeliver", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status code to be: %d. Got: %d.", http.StatusNotFound, res.StatusCode)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+	t.Run("BEUT-238: Create Webhook Refuses Private Addresses", func(t *testing.T) {
+		for _, url := range []string{"http://127.0.0.1:6379", "http://localhost/hook", "http://10.0.0.8/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]:5432", "http://0.0.0.0/hook"} {
+			client, srv, mockDB := setupWebhooksTest(t, "testuser")
+
+			body := `{"url":"` + url + `","events":["move"]}`
+			res, err := client.Post(srv.URL+"/v1/webhooks", "application/json", bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+
+			contents := validationResponse{}
+			data, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			json.Unmarshal(data, &contents)
+
+			if res.StatusCode != http.StatusUnprocessableEntity || len(contents.Data) != 1 || contents.Data[0].Field != "url" {
+				t.Errorf("Expected %s to be refused. Got: %d %s", url, res.StatusCode, data)
+			}
+
+			if err := mockDB.ExpectationsWereMet(); err != nil {
+				t.Errorf("PostGres expectations were not met: %v", err)
+			}
+		}
+	})
+
+	t.Run("BEUT-239: Deliveries Are Not Sent To Private Addresses", func(t *testing.T) {
+		called := false
+		receiver := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			called = true
+		}))
+		defer receiver.Close()
+
+		postgres, mockDB := mocks.NewMockDB()
+		mockDB.ExpectQuery(claimDeliveriesQuery).WillReturnRows(dueDelivery(receiver.URL, 0))
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(retryAttemptQuery).
+			WithArgs(1, "receiver could not be reached", 0, sqlmock.AnyArg(), 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		dispatcher := webhooks.NewDispatcher(&repository.Repository{Database: postgres})
+		dispatcher.DeliverDue(context.Background())
+
+		if called {
+			t.Errorf("Expected the delivery not to reach %s", receiver.URL)
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("BEUT-240: Redirects Are Not Followed", func(t *testing.T) {
+		called := false
+		target := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			called = true
+		}))
+		defer target.Close()
+
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+		}))
+		defer receiver.Close()
+
+		postgres, mockDB := mocks.NewMockDB()
+		mockDB.ExpectQuery(claimDeliveriesQuery).WillReturnRows(dueDelivery(receiver.URL, 0))
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(retryAttemptQuery).
+			WithArgs(1, "receiver answered 307", http.StatusTemporaryRedirect, sqlmock.AnyArg(), 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectCommit()
+
+		dispatcher := localDispatcher(postgres)
+		dispatcher.DeliverDue(context.Background())
+
+		if called {
+			t.Errorf("Expected the redirect not to be followed")
+		}
+
+		if err := mockDB.ExpectationsWereMet(); err != nil {
+			t.Errorf("PostGres expectations were not met: %v", err)
+		}
+	})
+	t.Run("BEUT-245: Only Public Addresses Are Public", func(t *testing.T) {
+		cases := map[string]bool{
+			"93.184.216.34":          true,
+			"2606:2800:220:1::":      true,
+			"127.0.0.1":              false,
+			"10.0.0.8":               false,
+			"169.254.169.254":        false,
+			"::ffff:169.254.169.254": false,
+			"0.0.0.0":                false,
+			"0.1.2.3":                false,
+			"100.64.0.1":             false,
+			"100.100.100.200":        false,
+			"198.18.0.1":             false,
+			"198.19.255.255":         false,
+			"224.0.0.1":              false,
+			"239.255.255.250":        false,
+			"255.255.255.255":        false,
+			"ff02::1":                false,
+			"64:ff9b::a00:8":         false,
+			"64:ff9b::a9fe:a9fe":     false,
+			"64:ff9b::5db8:d822":     true,
+			"2002:a00:8::":           false,
+			"2002:7f00:1::":          false,
+			"2002:5db8:d822::":       true,
+			"64:ff9b:1::a00:8":       false,
+			"fd00::1":                false,
+			"fe80::1":                false,
+		}
+
+		for address, public := range cases {
+			if got := helpers.PublicAddress(netip.MustParseAddr(address)); got != public {
+				t.Errorf("Expected %s to be public: %v. Got: %v", address, public, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,228 @@
+// Package webhooks sends the queued webhook deliveries to the URLs users subscribed.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"willowsuite-vault/helpers"
+	"willowsuite-vault/infra/logger"
+	"willowsuite-vault/infra/tracing"
+	"willowsuite-vault/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is marked failed.
+	MaxAttempts = 10
+	// firstRetry is how long the first retry waits, every retry after it waits twice as long as the one before.
+	firstRetry = 30 * time.Second
+	// maxRetry caps how long a retry waits.
+	maxRetry = 6 * time.Hour
+	// lease is how long a claimed delivery is held before another dispatcher may try it.
+	lease = time.Minute
+	// batchSize is the most deliveries claimed at once.
+	batchSize = 20
+)
+
+// Dispatcher works through the delivery queue. Every replica can run one, claimed deliveries are locked so each is
+// only sent once.
+type Dispatcher struct {
+	Repository *repository.Repository
+	Client     *http.Client
+	Interval   time.Duration
+}
+
+// errUnreachable is what is recorded when a delivery gets no answer. The reason it didn't is only logged, so
+// webhooks can't be used to find out which hosts and ports are reachable from here.
+var errUnreachable = errors.New("receiver could not be reached")
+
+// NewDispatcher returns a dispatcher that checks the queue every five seconds and gives receivers ten seconds to
+// answer.
+func NewDispatcher(repo *repository.Repository) *Dispatcher {
+	return &Dispatcher{
+		Repository: repo,
+		Client:     NewClient(10 * time.Second),
+		Interval:   5 * time.Second,
+	}
+}
+
+// NewClient returns a client that only connects to public addresses and doesn't follow redirects, so that webhooks
+// can't reach this host, the private network or the cloud metadata service. The address is checked once the name
+// is resolved, right before connecting, so a name that resolves to a public address when the webhook is created
+// and a private one later is refused too.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+
+			if !helpers.PublicAddress(addrPort.Addr()) {
+				return fmt.Errorf("%s is not a public address", addrPort.Addr())
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// The redirect itself is the answer, a 3xx fails the delivery like any other status that isn't a 2xx.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run sends due deliveries until ctx is done.
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.Interval)
+	defer ticker.Stop()
+
+	for {
+		// A full batch means more may be waiting, so a backlog drains without waiting on the ticker.
+		if dispatcher.DeliverDue(ctx) == batchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue claims the deliveries that are due, sends them and records how each went. It returns how many it
+// claimed.
+func (dispatcher *Dispatcher) DeliverDue(ctx context.Context) int {
+	deliveries, err := dispatcher.Repository.ClaimWebhookDeliveries(ctx, batchSize, lease)
+	if err != nil {
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery repository.DueWebhookDelivery) {
+			defer wg.Done()
+
+			statusCode, err := dispatcher.send(ctx, delivery)
+
+			var retryAt *time.Time
+			if err != nil && delivery.Attempts+1 < MaxAttempts {
+				next := time.Now().Add(Backoff(delivery.Attempts + 1))
+				retryAt = &next
+			}
+
+			if err != nil {
+				logger.Ctx(ctx).Warnf("webhook delivery %d to %s failed: %v", delivery.ID, delivery.URL, err)
+			}
+
+			if err != nil && statusCode == 0 {
+				err = errUnreachable
+			}
+
+			dispatcher.Repository.RecordWebhookAttempt(ctx, delivery.WebhookDelivery, statusCode, err, retryAt)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries)
+}
+
+// send posts the event of a delivery to its webhook and returns the status the receiver answered with. Anything
+// but a 2xx is an error.
+func (dispatcher *Dispatcher) send(ctx context.Context, delivery repository.DueWebhookDelivery) (statusCode int, err error) {
+	// The receiver gets this span's trace context in traceparent, so its own traces can join ours.
+	ctx, span := tracing.Tracer().Start(ctx, "webhooks.send", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("webhook.event", delivery.EventType),
+		attribute.Int64("webhook.delivery", int64(delivery.ID)),
+	))
+	defer func() {
+		if statusCode != 0 {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+		}
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "WillowSuite-Vault-Webhooks")
+	request.Header.Set("X-Vault-Event", delivery.EventType)
+	request.Header.Set("X-Vault-Delivery", strconv.FormatUint(delivery.ID, 10))
+	request.Header.Set("X-Vault-Timestamp", timestamp)
+	request.Header.Set("X-Vault-Signature", "sha256="+Sign(delivery.Secret, timestamp, body))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	response, err := dispatcher.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("receiver answered %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of the timestamp and body of a delivery, joined by a dot. Receivers compute the
+// same with their secret and compare it to X-Vault-Signature.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns how long to wait before trying a delivery again after it failed attempts times.
+func Backoff(attempts int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < attempts && wait < maxRetry; i++ {
+		wait *= 2
+	}
+
+	return min(wait, maxRetry)
+}
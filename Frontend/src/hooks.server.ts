@@ -2,6 +2,7 @@ import { sequence } from '@sveltejs/kit/hooks';
 import { API_URL } from '$env/static/private';
 import { cookieStore } from '$lib/stores/cookieStore';
 import { redirect } from '@sveltejs/kit';
+import { proxyHeaders } from '$lib/proxyHeaders';
 
 const public_paths = [
     '/login',
@@ -43,6 +44,7 @@ export async function refreshTokenHandle({ event, resolve }) {
         try {
             const refreshResponse = await fetch(`${API_URL}v1/token`, {
                 method: 'PUT',
+                headers: proxyHeaders(event.getClientAddress),
                 body: JSON.stringify({
                     refreshToken: refreshToken,
                     idToken: idToken,
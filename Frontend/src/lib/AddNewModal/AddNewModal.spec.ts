@@ -321,6 +321,7 @@ describe("Unit Tests for Entity Functions", () => {
         it("FEUT-8: Edit Entity Request", async () => {
             const formData = {
                 id: 1,
+                version: 3,
                 category: 'item',
                 name: 'Updated Test item',
                 address: '',
@@ -355,6 +356,7 @@ describe("Unit Tests for Entity Functions", () => {
                     method: 'PUT',
                     headers:{
                         'Content-Type': 'application/json',
+                        'If-Match': '"3"',
                     },
                     body: JSON.stringify({
                         id: formData.id,
@@ -374,6 +376,7 @@ describe("Unit Tests for Entity Functions", () => {
         it("FEUT-9: Edit Entity Bad Request", async () => {
             const formData = {
                 id: 1,
+                version: 3,
                 category: '',
                 name: '',
                 address: '',
@@ -502,7 +505,7 @@ describe("Unit Tests for Entity Functions", () => {
 
             (fetch as ReturnType<typeof vi.fn>).mockResolvedValue(createFetchResponse(deleteEntityResponse));
 
-            const [message, error] = await deleteEntity(id, category);
+            const [message, error] = await deleteEntity(id, category, 3);
 
             expect(global.fetch).toHaveBeenCalledWith(
                 `/api/v1/entity/${category}/${id}`,
@@ -510,6 +513,7 @@ describe("Unit Tests for Entity Functions", () => {
                     method: 'DELETE',
                     headers:{
                         'Content-Type': 'application/json',
+                        'If-Match': '"3"',
                     },
                 }
             );
@@ -528,7 +532,7 @@ describe("Unit Tests for Entity Functions", () => {
 
             (fetch as ReturnType<typeof vi.fn>).mockResolvedValue(createFetchResponse(deleteEntityResponse));
 
-            const [message, error] = await deleteEntity(id, category);
+            const [message, error] = await deleteEntity(id, category, 3);
 
             expect(global.fetch).toHaveBeenCalledWith(
                 `/api/v1/entity/${category}/${id}`,
@@ -536,6 +540,7 @@ describe("Unit Tests for Entity Functions", () => {
                     method: 'DELETE',
                     headers:{
                         'Content-Type': 'application/json',
+                        'If-Match': '"3"',
                     },
                 }
             );
@@ -8,6 +8,7 @@ export interface getEntityEntity {
     ID: number
     Name: string
     Notes?: string
+    Version?: number
 }
 
 export interface getEntityParent {
@@ -53,7 +54,7 @@ export const createEntity = async (formData: { category: string; address: string
     return [message, id];
 }
 
-export const editEntity = async (formData: { id: number, category: string; address: string; name: string; notes: string; parent: string; }): Promise<[string, getEntityData]> => {
+export const editEntity = async (formData: { id: number, version: number, category: string; address: string; name: string; notes: string; parent: string; }): Promise<[string, getEntityData]> => {
     let message: string = ""
     let entity: getEntityData = {
         Entity: {
@@ -73,6 +74,7 @@ export const editEntity = async (formData: { id: number, category: string; addre
         method: 'PUT',
         headers: {
             'Content-Type': 'application/json',
+            'If-Match': `"${formData.version}"`,
         },
         body: JSON.stringify({
             id: formData.id,
@@ -140,7 +142,7 @@ export const getParents = async (category: string): Promise<[string, parentData[
     return [message, parents]
 }
 
-export const deleteEntity = async (id: number, category: string): Promise<[string, string]> => {
+export const deleteEntity = async (id: number, category: string, version: number): Promise<[string, string]> => {
     let message: string = ""
     let error: string = ""
 
@@ -149,6 +151,7 @@ export const deleteEntity = async (id: number, category: string): Promise<[strin
             method: "DELETE",
             headers: {
                 'Content-Type': 'application/json',
+                'If-Match': `"${version}"`,
             },
         }
     );
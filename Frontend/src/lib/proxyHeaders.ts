@@ -0,0 +1,16 @@
+// proxyHeaders returns the headers for a call to the API made on behalf of a browser. The API limits sign up and
+// sign in per client address, so the browser's is passed on rather than every user sharing this server's.
+export function proxyHeaders(getClientAddress?: () => string): Headers {
+  const headers = new Headers({ 'content-type': 'application/json' });
+
+  try {
+    const address = getClientAddress?.();
+    if (address) {
+      headers.set('x-forwarded-for', address);
+    }
+  } catch (error) {
+    // Prerendering and some adapters can't tell who the client is.
+  }
+
+  return headers;
+}
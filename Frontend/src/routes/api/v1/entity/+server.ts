@@ -65,6 +65,7 @@ export async function PUT({ request, cookies }) {
                 headers: {
                     'Authorization': 'Bearer ' + cookieStore.get(cookies, 'accessToken'),
                     'Content-Type': 'application/json',
+                    'If-Match': request.headers.get('If-Match') ?? '',
                 },
                 body: JSON.stringify({
                     id: id + "",
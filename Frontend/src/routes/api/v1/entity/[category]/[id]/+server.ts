@@ -35,7 +35,7 @@ export async function GET({ params, cookies }) {
 }
 
 //@ts-ignore
-export async function DELETE({ params, cookies }) {
+export async function DELETE({ params, request, cookies }) {
     let response = new Response()
     if( (params.category == 'item' ||
         params.category == 'container' ||
@@ -50,7 +50,8 @@ export async function DELETE({ params, cookies }) {
                 {
                     method: "DELETE",
                     headers: {
-                        Authorization: "Bearer " + cookieStore.get(cookies, "accessToken")
+                        Authorization: "Bearer " + cookieStore.get(cookies, "accessToken"),
+                        'If-Match': request.headers.get('If-Match') ?? '',
                     }
                 }
             );
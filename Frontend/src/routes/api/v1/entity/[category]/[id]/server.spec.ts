@@ -86,6 +86,8 @@ describe('GET function', () => {
 });
 
 describe('DELETE function', () => {
+  const mockRequest = { headers: new Headers({ 'If-Match': '"3"' }) };
+
   beforeEach(() => {
     vi.clearAllMocks();
     global.fetch = vi.fn();
@@ -106,7 +108,7 @@ describe('DELETE function', () => {
         json: vi.fn().mockResolvedValue(mockResponseData)
       });
 
-      const response = await DELETE({ params: { category, id: mockId }, cookies: mockCookies });
+      const response = await DELETE({ params: { category, id: mockId }, request: mockRequest, cookies: mockCookies });
 
       expect(global.fetch).toHaveBeenCalledWith(
         `http://mock-api.com/v1/entity/${category}/${mockId}`,
@@ -114,6 +116,7 @@ describe('DELETE function', () => {
           method: 'DELETE',
           headers: {
             'Authorization': 'Bearer mockAccessToken',
+            'If-Match': '"3"',
           }
         })
       );
@@ -130,7 +133,7 @@ describe('DELETE function', () => {
     const invalidCategory = 'invalid';
     const mockId = '123';
 
-    const response = await DELETE({ params: { category: invalidCategory, id: mockId }, cookies: mockCookies });
+    const response = await DELETE({ params: { category: invalidCategory, id: mockId }, request: mockRequest, cookies: mockCookies });
 
     expect(global.fetch).not.toHaveBeenCalled();
     expect(response).toBeInstanceOf(Response);
@@ -148,7 +151,7 @@ describe('DELETE function', () => {
     global.fetch = vi.fn().mockRejectedValue(new Error('Network error'));
     console.error = vi.fn();
 
-    const response = await DELETE({ params: { category, id: mockId }, cookies: mockCookies });
+    const response = await DELETE({ params: { category, id: mockId }, request: mockRequest, cookies: mockCookies });
 
     expect(console.error).toHaveBeenCalledWith(new Error('Network error'));
     expect(response.status).toBe(400);
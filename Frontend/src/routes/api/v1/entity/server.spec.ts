@@ -104,7 +104,8 @@ describe('PUT function', () => {
     };
 
     const mockRequest = {
-      json: vi.fn().mockResolvedValue(mockRequestBody)
+      json: vi.fn().mockResolvedValue(mockRequestBody),
+      headers: new Headers({ 'If-Match': '"3"' })
     };
 
     vi.mocked(cookieStore.get).mockReturnValue('mock-token');
@@ -119,6 +120,7 @@ describe('PUT function', () => {
         headers: {
           'Authorization': 'Bearer mock-token',
           'Content-Type': 'application/json',
+          'If-Match': '"3"',
         },
         body: JSON.stringify({...mockRequestBody, id: '456'})
       }
@@ -131,7 +133,8 @@ describe('PUT function', () => {
   it('FEUT-56: Update Entity Server Request Unsuccess', async () => {
     const mockCookies = {};
     const mockRequest = {
-      json: vi.fn().mockResolvedValue({id: '456'})
+      json: vi.fn().mockResolvedValue({id: '456'}),
+      headers: new Headers()
     };
 
     global.fetch = vi.fn().mockRejectedValue(new Error('Network error'));
@@ -1,8 +1,9 @@
 import { API_URL } from '$env/static/private';
 import { cookieStore } from '$lib/stores/cookieStore';
+import { proxyHeaders } from '$lib/proxyHeaders';
 
 //@ts-ignore
-export async function POST({ request, cookies }) {
+export async function POST({ request, cookies, getClientAddress }) {
     const {
         userEmail,
         password,
@@ -12,7 +13,7 @@ export async function POST({ request, cookies }) {
     try {
         const proxyResponse = await fetch(`${API_URL}v1/token`, {
             method: 'POST',
-            headers: new Headers({ 'content-type': 'application/json' }),
+            headers: proxyHeaders(getClientAddress),
             body: JSON.stringify({
                 userEmail: userEmail,
                 password: password,
@@ -61,13 +62,13 @@ export async function POST({ request, cookies }) {
 }
 
 //@ts-ignore
-export async function DELETE({ cookies }) {
+export async function DELETE({ cookies, getClientAddress }) {
     let response = new Response()
 
     try {
         const response = await fetch(`${API_URL}v1/token`, {
             method: 'DELETE',
-            headers: new Headers({ 'content-type': 'application/json' }),
+            headers: proxyHeaders(getClientAddress),
             body: JSON.stringify({
                 refreshToken: cookieStore.get(cookies, "refreshToken"),
             })
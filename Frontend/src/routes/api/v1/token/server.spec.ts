@@ -77,6 +77,28 @@ describe('POST function', () => {
   });
 });
 
+describe('Client address', () => {
+  beforeEach(() => {
+    vi.clearAllMocks();
+  });
+
+  it('FEUT-77: Login Forwards The Browser Address', async () => {
+    const mockRequest = {
+      json: vi.fn().mockResolvedValue({ userEmail: 'test@example.com', password: 'password123' })
+    };
+
+    global.fetch = vi.fn().mockResolvedValue({
+      status: 401,
+      json: vi.fn().mockResolvedValue({ message: 'error' })
+    });
+
+    await POST({ request: mockRequest, cookies: {}, getClientAddress: () => '203.0.113.7' });
+
+    const init = vi.mocked(global.fetch).mock.calls[0][1];
+    expect(new Headers(init?.headers).get('x-forwarded-for')).toBe('203.0.113.7');
+  });
+});
+
 describe('DELETE function', () => {
   beforeEach(() => {
     vi.clearAllMocks();
@@ -1,8 +1,9 @@
 import { API_URL } from '$env/static/private';
 import { cookieStore } from '$lib/stores/cookieStore.js';
+import { proxyHeaders } from '$lib/proxyHeaders';
 
 //@ts-ignore
-export async function POST({ request, cookies }) {
+export async function POST({ request, cookies, getClientAddress }) {
     const {
         userEmail,
         password,
@@ -15,7 +16,7 @@ export async function POST({ request, cookies }) {
     try {
         const proxyResponse = await fetch(`${API_URL}v1/user`, {
             method: 'POST',
-            headers: new Headers({ 'content-type': 'application/json' }),
+            headers: proxyHeaders(getClientAddress),
             body: JSON.stringify({
                 userEmail: userEmail,
                 password: password,
@@ -56,7 +57,7 @@ export async function POST({ request, cookies }) {
 }
 
 //@ts-ignore
-export async function PUT({ request, cookies }) {
+export async function PUT({ request, cookies, getClientAddress }) {
     const {
         confirmationCode,
     } = await request.json();
@@ -65,7 +66,7 @@ export async function PUT({ request, cookies }) {
     try {
         const proxyResponse = await fetch(`${API_URL}v1/user`, {
             method: 'PUT',
-            headers: new Headers({ 'content-type': 'application/json' }),
+            headers: proxyHeaders(getClientAddress),
             body: JSON.stringify({
                 userEmail: cookieStore.get(cookies, "userEmail"),
                 confirmationCode: confirmationCode,